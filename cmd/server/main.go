@@ -9,8 +9,10 @@ import (
 	"syscall"
 	"time"
 
+	"frame_control_system/internal/blobstore"
 	"frame_control_system/internal/config"
 	"frame_control_system/internal/httpserver"
+	"frame_control_system/internal/jobs"
 	"frame_control_system/internal/storage"
 )
 
@@ -27,7 +29,12 @@ func main() {
 		log.Fatalf("migrations: %v", err)
 	}
 
-	router := httpserver.NewRouter(cfg, db)
+	blobs, err := blobstore.NewLocal(cfg.BlobRoot)
+	if err != nil {
+		log.Fatalf("blob store: %v", err)
+	}
+
+	router := httpserver.NewRouter(cfg, db, blobs)
 
 	server := &http.Server{
 		Addr:              cfg.Address(),
@@ -35,6 +42,13 @@ func main() {
 		ReadHeaderTimeout: 10 * time.Second,
 	}
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go jobs.RunOverdueChecker(jobsCtx, db, cfg.OverdueCheckInterval)
+	go jobs.RunThumbnailWorker(jobsCtx, db, blobs, cfg.ThumbnailSizes, cfg.ThumbnailPollInterval)
+	go jobs.RunArchiver(jobsCtx, db, cfg.ArchiveCheckInterval, cfg.ArchiveAfter)
+	go jobs.RunBlobSweeper(jobsCtx, db, blobs, cfg.BlobSweepInterval, cfg.BlobSweepGrace)
+
 	go func() {
 		log.Printf("server listening on %s", cfg.Address())
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -46,6 +60,7 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
+	stopJobs()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	_ = server.Shutdown(ctx)
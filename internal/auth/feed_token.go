@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// NewFeedToken returns a random token for feeds that clients fetch without a
+// login (calendar subscriptions) together with its hash; only the hash is stored.
+func NewFeedToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashFeedToken(token), nil
+}
+
+// HashFeedToken returns the hex SHA-256 of the token, the form it is looked up by.
+func HashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
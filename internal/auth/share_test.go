@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareToken(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	token := SignShareToken("order", "o-1", now.Add(time.Hour), "key")
+	kind, id, exp, err := ParseShareToken(token, "key", now)
+	if err != nil || kind != "order" || id != "o-1" || !exp.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected result: %q %q %v %v", kind, id, exp, err)
+	}
+	if _, _, _, err := ParseShareToken(token, "other-key", now); !errors.Is(err, ErrShareTokenInvalid) {
+		t.Fatalf("expected invalid token for another key, got %v", err)
+	}
+	if _, _, _, err := ParseShareToken(token, "key", now.Add(time.Hour)); !errors.Is(err, ErrShareTokenExpired) {
+		t.Fatalf("expected expired token, got %v", err)
+	}
+	tampered := strings.Replace(token, "o-1", "o-2", 1)
+	if _, _, _, err := ParseShareToken(tampered, "key", now); !errors.Is(err, ErrShareTokenInvalid) {
+		t.Fatalf("expected invalid token for a changed id, got %v", err)
+	}
+	if _, _, _, err := ParseShareToken("garbage", "key", now); !errors.Is(err, ErrShareTokenInvalid) {
+		t.Fatalf("expected invalid token, got %v", err)
+	}
+}
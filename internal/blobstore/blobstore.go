@@ -0,0 +1,169 @@
+// Package blobstore keeps binary content (attachments, photos) outside the
+// database. Blobs are addressed by the hex SHA-256 of their content, so the
+// same file uploaded twice is stored once.
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+type Store interface {
+	// Put stores the content of r and returns its key and size.
+	Put(ctx context.Context, r io.Reader) (key string, size int64, err error)
+	// Open returns the content stored under key or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob; deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// Sweep deletes blobs last stored before the given time for which inUse
+	// reports false, and returns how many it deleted. Put refreshes that time
+	// for content it already holds, so a blob just handed out by Put survives
+	// until its reference is saved.
+	Sweep(ctx context.Context, before time.Time, inUse func(ctx context.Context, key string) (bool, error)) (int, error)
+}
+
+// Local stores blobs as files under root, fanned out by the first two byte
+// pairs of the key: root/ab/cd/abcd....
+type Local struct {
+	root string
+	// mu orders the last step of Put against Sweep's check-and-delete.
+	mu sync.Mutex
+}
+
+func NewLocal(root string) (*Local, error) {
+	if err := os.MkdirAll(filepath.Join(root, "tmp"), 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{root: root}, nil
+}
+
+func (s *Local) Put(ctx context.Context, r io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.root, "tmp"), "upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", 0, err
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	path := s.path(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := os.Stat(path); err == nil {
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil {
+			return "", 0, err
+		}
+		return key, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+	return key, size, nil
+}
+
+func (s *Local) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	if !ValidKey(key) {
+		return nil, ErrInvalidKey
+	}
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *Local) Delete(_ context.Context, key string) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *Local) Sweep(ctx context.Context, before time.Time, inUse func(ctx context.Context, key string) (bool, error)) (int, error) {
+	keys, err := filepath.Glob(filepath.Join(s.root, "??", "??", "*"))
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, path := range keys {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		key := filepath.Base(path)
+		if !ValidKey(key) {
+			continue
+		}
+		removed, err := s.sweepOne(ctx, key, before, inUse)
+		if err != nil {
+			return n, err
+		}
+		if removed {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Local) sweepOne(ctx context.Context, key string, before time.Time, inUse func(ctx context.Context, key string) (bool, error)) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fi, err := os.Stat(s.path(key))
+	if err != nil || !fi.ModTime().Before(before) {
+		return false, nil
+	}
+	used, err := inUse(ctx, key)
+	if err != nil || used {
+		return false, err
+	}
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Local) path(key string) string {
+	return filepath.Join(s.root, key[:2], key[2:4], key)
+}
+
+// ValidKey reports whether key looks like a key returned by Put. Checking it
+// before touching the filesystem keeps keys from escaping the root.
+func ValidKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range key {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalPutOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, size, err := s.Put(ctx, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" || size != 5 {
+		t.Fatalf("unexpected key/size: %s %d", key, size)
+	}
+	again, _, err := s.Put(ctx, strings.NewReader("hello"))
+	if err != nil || again != key {
+		t.Fatalf("same content should give the same key: %s, %v", again, err)
+	}
+	rc, err := s.Open(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != "hello" {
+		t.Fatalf("got %q", b)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.Open(ctx, "../../etc/passwd"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected ErrInvalidKey, got %v", err)
+	}
+}
+
+func TestLocalSweep(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	used, _, _ := s.Put(ctx, strings.NewReader("used"))
+	orphan, _, _ := s.Put(ctx, strings.NewReader("orphan"))
+	reused, _, _ := s.Put(ctx, strings.NewReader("reused"))
+	old := time.Now().Add(-2 * time.Hour)
+	for _, key := range []string{used, orphan, reused} {
+		if err := os.Chtimes(s.path(key), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An identical upload hands out the old blob again; it must not be swept
+	// before the new reference is saved.
+	if _, _, err := s.Put(ctx, strings.NewReader("reused")); err != nil {
+		t.Fatal(err)
+	}
+	inUse := func(_ context.Context, key string) (bool, error) { return key == used, nil }
+	n, err := s.Sweep(ctx, time.Now().Add(-time.Hour), inUse)
+	if err != nil || n != 1 {
+		t.Fatalf("want 1 blob swept, got %d, %v", n, err)
+	}
+	for key, want := range map[string]bool{used: true, orphan: false, reused: true} {
+		rc, err := s.Open(ctx, key)
+		if (err == nil) != want {
+			t.Fatalf("%s: kept=%v, want %v", key, err == nil, want)
+		}
+		if rc != nil {
+			rc.Close()
+		}
+	}
+}
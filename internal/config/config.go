@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -16,18 +17,60 @@ type Config struct {
 	LogLevel       string
 	RateLimitRPS   float64
 	RateLimitBurst int
+	IdempotencyTTL time.Duration
+	// OverdueCheckInterval is how often the overdue checker scans orders; 0 disables it.
+	OverdueCheckInterval time.Duration
+	// CommentEditWindow is how long authors may edit their comments; 0 means no limit.
+	CommentEditWindow time.Duration
+	// BlobRoot is the directory of the local blob store (attachments).
+	BlobRoot               string
+	AttachmentMaxBytes     int64
+	AttachmentAllowedTypes []string
+	// AttachmentStripEXIF removes EXIF (GPS, camera data) from downloaded JPEG originals.
+	AttachmentStripEXIF bool
+	// ThumbnailSizes are the longer sides, in pixels, of generated thumbnails.
+	ThumbnailSizes        []int
+	ThumbnailPollInterval time.Duration
+	// BlobSweepInterval is how often blobs no record references are deleted; 0
+	// disables it. Blobs stored within BlobSweepGrace are kept, as an upload may
+	// not have saved its record yet.
+	BlobSweepInterval time.Duration
+	BlobSweepGrace    time.Duration
+	// VATDefaultRate and VATRates (by item category) are VAT percentages added on top of net prices.
+	VATDefaultRate float64
+	VATRates       map[string]float64
+	// ArchiveAfter is how long done and cancelled orders stay in default lists; 0 disables archiving.
+	ArchiveAfter         time.Duration
+	ArchiveCheckInterval time.Duration
 }
 
+const defaultAttachmentTypes = "image/jpeg,image/png,image/gif,image/webp,application/pdf"
+
 func Load() Config {
 	return Config{
-		Env:            getEnv("APP_ENV", "dev"),
-		Port:           getEnvInt("APP_PORT", 8080),
-		DBPath:         getEnv("DB_PATH", "app.db"),
-		JWTSecret:      getEnv("JWT_SECRET", "dev-secret-change-me"),
-		CORSOrigins:    splitAndTrim(getEnv("CORS_ORIGINS", "*")),
-		LogLevel:       getEnv("LOG_LEVEL", "info"),
-		RateLimitRPS:   getEnvFloat("RATE_LIMIT_RPS", 10),
-		RateLimitBurst: getEnvInt("RATE_LIMIT_BURST", 20),
+		Env:                    getEnv("APP_ENV", "dev"),
+		Port:                   getEnvInt("APP_PORT", 8080),
+		DBPath:                 getEnv("DB_PATH", "app.db"),
+		JWTSecret:              getEnv("JWT_SECRET", "dev-secret-change-me"),
+		CORSOrigins:            splitAndTrim(getEnv("CORS_ORIGINS", "*")),
+		LogLevel:               getEnv("LOG_LEVEL", "info"),
+		RateLimitRPS:           getEnvFloat("RATE_LIMIT_RPS", 10),
+		RateLimitBurst:         getEnvInt("RATE_LIMIT_BURST", 20),
+		IdempotencyTTL:         getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		OverdueCheckInterval:   getEnvDuration("OVERDUE_CHECK_INTERVAL", time.Minute),
+		CommentEditWindow:      getEnvDuration("COMMENT_EDIT_WINDOW", 15*time.Minute),
+		BlobRoot:               getEnv("BLOB_ROOT", "data/blobs"),
+		AttachmentMaxBytes:     int64(getEnvInt("ATTACHMENT_MAX_BYTES", 10<<20)),
+		AttachmentAllowedTypes: splitAndTrim(getEnv("ATTACHMENT_ALLOWED_TYPES", defaultAttachmentTypes)),
+		AttachmentStripEXIF:    getEnvBool("ATTACHMENT_STRIP_EXIF", false),
+		ThumbnailSizes:         getEnvInts("THUMBNAIL_SIZES", []int{160, 640}),
+		ThumbnailPollInterval:  getEnvDuration("THUMBNAIL_POLL_INTERVAL", 5*time.Second),
+		BlobSweepInterval:      getEnvDuration("BLOB_SWEEP_INTERVAL", time.Hour),
+		BlobSweepGrace:         getEnvDuration("BLOB_SWEEP_GRACE", time.Hour),
+		VATDefaultRate:         getEnvFloat("VAT_DEFAULT_RATE", 0),
+		VATRates:               getEnvRates("VAT_RATES"),
+		ArchiveAfter:           getEnvDuration("ARCHIVE_AFTER", 30*24*time.Hour),
+		ArchiveCheckInterval:   getEnvDuration("ARCHIVE_CHECK_INTERVAL", time.Hour),
 	}
 }
 
@@ -60,6 +103,60 @@ func getEnvFloat(key string, def float64) float64 {
 	return def
 }
 
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	if v, ok := os.LookupEnv(key); ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+func getEnvBool(key string, def bool) bool {
+	if v, ok := os.LookupEnv(key); ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// getEnvInts reads a comma-separated list of positive integers; an invalid
+// list falls back to def.
+func getEnvInts(key string, def []int) []int {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	var out []int
+	for _, p := range splitAndTrim(v) {
+		n, err := strconv.Atoi(p)
+		if err != nil || n <= 0 {
+			return def
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// getEnvRates reads "category:rate" pairs separated by commas, e.g.
+// "food:10,books:0". Categories are lower-cased; invalid pairs are skipped.
+func getEnvRates(key string) map[string]float64 {
+	out := map[string]float64{}
+	for _, p := range splitAndTrim(os.Getenv(key)) {
+		cat, rate, ok := strings.Cut(p, ":")
+		if !ok {
+			continue
+		}
+		r, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+		if err != nil || r < 0 {
+			continue
+		}
+		out[strings.ToLower(strings.TrimSpace(cat))] = r
+	}
+	return out
+}
+
 func splitAndTrim(s string) []string {
 	parts := strings.Split(s, ",")
 	out := make([]string, 0, len(parts))
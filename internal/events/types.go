@@ -1,8 +1,58 @@
 package events
 
 const (
-	OrderCreated      = "order.created"
-	OrderStatusUpdate = "order.status_updated"
+	OrderCreated           = "order.created"
+	OrderStatusUpdate      = "order.status_updated"
+	OrderItemsUpdated      = "order.items_updated"
+	OrderOverdue           = "order.overdue"
+	OrderScheduleUpdated   = "order.schedule_updated"
+	OrderAssigned          = "order.assigned"
+	OrderUnassigned        = "order.unassigned"
+	OrderCommentAdded      = "order.comment_added"
+	OrderCommentUpdated    = "order.comment_updated"
+	OrderCommentDeleted    = "order.comment_deleted"
+	OrderMention           = "order.mention"
+	OrderAttachmentAdded   = "order.attachment_added"
+	OrderAttachmentDeleted = "order.attachment_deleted"
+	OrderPricingUpdated    = "order.pricing_updated"
+	OrderArchived          = "order.archived"
+	OrderDeleted           = "order.deleted"
+	OrderRestored          = "order.restored"
+	OrderAttributesUpdated = "order.attributes_updated"
+	OrderProjectChanged    = "order.project_changed"
+	OrderViewerAdded       = "order.viewer_added"
+	OrderViewerRemoved     = "order.viewer_removed"
+	OrderShareLinkCreated  = "order.share_link_created"
+	OrderDependencyAdded   = "order.dependency_added"
+	OrderDependencyRemoved = "order.dependency_removed"
+	OrderChecklistAdded    = "order.checklist_added"
+	OrderChecklistRemoved  = "order.checklist_removed"
+	OrderChecklistChecked  = "order.checklist_item_checked"
+	OrderChecklistCleared  = "order.checklist_item_unchecked"
+	OrderTimerStarted      = "order.timer_started"
+	OrderTimeLogged        = "order.time_logged"
+	OrderTimeEntryDeleted  = "order.time_entry_deleted"
+	OrderApprovalRequested = "order.approval_requested"
+	OrderApprovalGranted   = "order.approval_granted"
+	OrderApprovalRejected  = "order.approval_rejected"
+	OrderApproved          = "order.approved"
+
+	ProjectCreated          = "project.created"
+	ProjectUpdated          = "project.updated"
+	ProjectDeleted          = "project.deleted"
+	ProjectMemberAdded      = "project.member_added"
+	ProjectMemberRemoved    = "project.member_removed"
+	ProjectShareLinkCreated = "project.share_link_created"
+
+	DefectCreated       = "defect.created"
+	DefectUpdated       = "defect.updated"
+	DefectStatusChanged = "defect.status_changed"
+	DefectDeleted       = "defect.deleted"
+	DefectPhotoAdded    = "defect.photo_added"
+	DefectPhotoDeleted  = "defect.photo_deleted"
+
+	CalendarFeedCreated = "calendar.feed_created"
+	CalendarFeedRevoked = "calendar.feed_revoked"
 )
 
 
@@ -0,0 +1,647 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	maxApprovalLevel      = 5
+	maxApprovalCategories = 50
+	maxApprovalComment    = 2000
+	maxApprovalQueue      = 100
+)
+
+var approverRoleRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+type approvalRuleRequest struct {
+	Name         *string           `json:"name"`
+	MinTotal     optional[float64] `json:"min_total"`
+	Categories   *[]string         `json:"categories"`
+	Level        *int              `json:"level"`
+	ApproverRole *string           `json:"approver_role"`
+}
+
+func (req approvalRuleRequest) apply(ar *models.ApprovalRule) {
+	if req.Name != nil {
+		ar.Name = *req.Name
+	}
+	if req.MinTotal.Set {
+		ar.MinTotal = req.MinTotal.Value
+	}
+	if req.Categories != nil {
+		ar.Categories = *req.Categories
+	}
+	if req.Level != nil {
+		ar.Level = *req.Level
+	}
+	if req.ApproverRole != nil {
+		ar.ApproverRole = *req.ApproverRole
+	}
+}
+
+type approvalDecisionRequest struct {
+	Comment string `json:"comment"`
+}
+
+// orderApprovalState is the answer to a decision: the order and the steps of
+// its current approval round.
+type orderApprovalState struct {
+	Order     *models.Order          `json:"order"`
+	Approvals []models.OrderApproval `json:"approvals"`
+}
+
+// validateApprovalRule normalizes the rule in place and checks it. Categories
+// are lower-cased like item categories.
+func validateApprovalRule(ar *models.ApprovalRule) error {
+	ar.Name = strings.TrimSpace(ar.Name)
+	if ar.Name == "" || utf8.RuneCountInString(ar.Name) > 200 {
+		return errors.New("name must be 1-200 characters")
+	}
+	if ar.MinTotal != nil && (*ar.MinTotal < 0 || math.IsNaN(*ar.MinTotal) || math.IsInf(*ar.MinTotal, 0)) {
+		return errors.New("min_total must be a non-negative number")
+	}
+	categories := make([]string, 0, len(ar.Categories))
+	seen := map[string]bool{}
+	for _, c := range ar.Categories {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" {
+			return errors.New("categories must not be empty")
+		}
+		if !seen[c] {
+			seen[c] = true
+			categories = append(categories, c)
+		}
+	}
+	if len(categories) > maxApprovalCategories {
+		return fmt.Errorf("at most %d categories", maxApprovalCategories)
+	}
+	ar.Categories = categories
+	if ar.MinTotal == nil && len(ar.Categories) == 0 {
+		return errors.New("a rule needs min_total or categories")
+	}
+	if ar.Level < 1 || ar.Level > maxApprovalLevel {
+		return fmt.Errorf("level must be 1-%d", maxApprovalLevel)
+	}
+	ar.ApproverRole = strings.ToLower(strings.TrimSpace(ar.ApproverRole))
+	if !approverRoleRe.MatchString(ar.ApproverRole) {
+		return errors.New("approver_role must be a role name: lower-case letters, digits and _")
+	}
+	if ar.ApproverRole == "viewer" {
+		return errors.New("viewers cannot approve orders")
+	}
+	return nil
+}
+
+// approvalSteps returns the approvals the order needs under the rules: one
+// step per level and approver role, listing the rules that asked for it,
+// ordered by level. None means the order needs no approval.
+func approvalSteps(rules []models.ApprovalRule, o models.Order) []models.OrderApproval {
+	steps := []models.OrderApproval{}
+	index := map[string]int{}
+	for _, ar := range rules {
+		if !ar.Matches(o) {
+			continue
+		}
+		key := fmt.Sprintf("%d/%s", ar.Level, ar.ApproverRole)
+		i, ok := index[key]
+		if !ok {
+			i = len(steps)
+			index[key] = i
+			steps = append(steps, models.OrderApproval{Level: ar.Level, ApproverRole: ar.ApproverRole, Rules: []string{}})
+		}
+		steps[i].Rules = append(steps[i].Rules, ar.Name)
+	}
+	sort.SliceStable(steps, func(i, j int) bool {
+		if steps[i].Level != steps[j].Level {
+			return steps[i].Level < steps[j].Level
+		}
+		return steps[i].ApproverRole < steps[j].ApproverRole
+	})
+	return steps
+}
+
+// pendingLevel is the lowest level with a pending step; 0 when the round is
+// complete.
+func pendingLevel(steps []models.OrderApproval) int {
+	level := 0
+	for _, a := range steps {
+		if a.Status == models.ApprovalPending && (level == 0 || a.Level < level) {
+			level = a.Level
+		}
+	}
+	return level
+}
+
+// decidableSteps returns the indexes of the steps the caller may decide now:
+// pending steps at the current level for a role the caller has (admins
+// stand in for any role). Owners do not decide on their own orders, and
+// nobody approves two levels of the same round.
+func decidableSteps(steps []models.OrderApproval, o *models.Order, ac *AuthContext) ([]int, *apiError) {
+	if o.UserID == ac.UserID {
+		return nil, &apiError{Code: "forbidden", Message: "owners cannot decide on their own orders"}
+	}
+	level := pendingLevel(steps)
+	idx := []int{}
+	for i, a := range steps {
+		if a.DecidedBy == ac.UserID {
+			return nil, &apiError{Code: "forbidden", Message: fmt.Sprintf("already decided at level %d", a.Level)}
+		}
+		if a.Status == models.ApprovalPending && a.Level == level && (hasRole(ac.Roles, a.ApproverRole) || hasRole(ac.Roles, "admin")) {
+			idx = append(idx, i)
+		}
+	}
+	if len(idx) == 0 {
+		return nil, &apiError{Code: "forbidden", Message: fmt.Sprintf("no approval at level %d is waiting for your roles", level)}
+	}
+	return idx, nil
+}
+
+// orderApprovalSteps evaluates the approval rules against the order.
+func orderApprovalSteps(ctx context.Context, q storage.DBTX, o models.Order) ([]models.OrderApproval, error) {
+	rules, err := storage.NewApprovalRepository(q).ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return approvalSteps(rules, o), nil
+}
+
+// requestApproval starts a new approval round for the order, which the caller
+// has put into pending_approval, and records order.approval_requested.
+func requestApproval(ctx context.Context, q storage.DBTX, o *models.Order, steps []models.OrderApproval, requestedBy string) error {
+	if err := storage.NewApprovalRepository(q).StartRound(ctx, o.ID, steps); err != nil {
+		return err
+	}
+	summary := make([]map[string]any, 0, len(steps))
+	for _, a := range steps {
+		summary = append(summary, map[string]any{"level": a.Level, "approver_role": a.ApproverRole, "rules": a.Rules})
+	}
+	return storage.AddOutboxEvent(ctx, q, events.OrderApprovalRequested, map[string]any{
+		"id":           o.ID,
+		"user_id":      o.UserID,
+		"requested_by": requestedBy,
+		"round":        steps[0].Round,
+		"total":        o.TotalAmount,
+		"steps":        summary,
+	})
+}
+
+// reevaluateApproval applies the rules to an order whose content or price
+// changed: matching orders go back to pending_approval with a new round,
+// orders that no longer match leave it.
+func reevaluateApproval(ctx context.Context, q storage.DBTX, o *models.Order, by string) error {
+	steps, err := orderApprovalSteps(ctx, q, *o)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		if o.Status != models.OrderStatusPendingApproval {
+			return nil
+		}
+		if err := storage.NewApprovalRepository(q).CancelPending(ctx, o.ID); err != nil {
+			return err
+		}
+		return setApprovalStatus(ctx, q, o, models.OrderStatusCreated, by)
+	}
+	if o.Status != models.OrderStatusPendingApproval {
+		if err := setApprovalStatus(ctx, q, o, models.OrderStatusPendingApproval, by); err != nil {
+			return err
+		}
+	}
+	return requestApproval(ctx, q, o, steps, by)
+}
+
+// setApprovalStatus moves the order to the status an approval decision
+// leads to, with history and the usual status event.
+func setApprovalStatus(ctx context.Context, q storage.DBTX, o *models.Order, to models.OrderStatus, by string) error {
+	repo := storage.NewOrderRepository(q)
+	if err := repo.UpdateStatus(ctx, o.ID, o.Status, to); err != nil {
+		return err
+	}
+	if err := repo.AddStatusHistory(ctx, o.ID, o.Status, to, by); err != nil {
+		return err
+	}
+	o.Status = to
+	return storage.AddOutboxEvent(ctx, q, events.OrderStatusUpdate, map[string]any{
+		"id":     o.ID,
+		"status": to,
+	})
+}
+
+// loadOrderForApproval is loadViewableOrder that also lets in users holding
+// a role asked for by the order's current approval round.
+func loadOrderForApproval(ctx context.Context, q storage.DBTX, ac *AuthContext, id string) (*models.Order, int, *apiError) {
+	o, status, apiErr := loadViewableOrder(ctx, q, ac, id)
+	if apiErr == nil || status != http.StatusForbidden {
+		return o, status, apiErr
+	}
+	steps, err := storage.NewApprovalRepository(q).CurrentRound(ctx, id)
+	if err != nil {
+		return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	for _, a := range steps {
+		if hasRole(ac.Roles, a.ApproverRole) {
+			o, err := storage.NewOrderRepository(q).GetByID(ctx, id)
+			if err != nil {
+				return nil, http.StatusNotFound, &apiError{Code: "not_found", Message: "order not found"}
+			}
+			return o, 0, nil
+		}
+	}
+	return nil, status, apiErr
+}
+
+func decodeApprovalDecision(r *http.Request) (approvalDecisionRequest, error) {
+	var req approvalDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return req, errors.New("invalid json")
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if utf8.RuneCountInString(req.Comment) > maxApprovalComment {
+		return req, fmt.Errorf("comment must be at most %d characters", maxApprovalComment)
+	}
+	return req, nil
+}
+
+// ListApprovalRulesHandler returns all rules; every signed-in user may see
+// why an order waits for approval.
+func ListApprovalRulesHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewApprovalRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		list, err := repo.ListRules(ctx)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: list})
+	}
+}
+
+func GetApprovalRuleHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewApprovalRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		ar, err := repo.GetRule(ctx, chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "approval rule not found"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: ar})
+	}
+}
+
+// CreateApprovalRuleHandler adds a rule. It applies to orders created or
+// edited from now on.
+func CreateApprovalRuleHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewApprovalRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req approvalRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		ar := &models.ApprovalRule{CreatedBy: ac.UserID, Level: 1}
+		req.apply(ar)
+		if err := validateApprovalRule(ar); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		if err := repo.CreateRule(ctx, ar); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: ar})
+	}
+}
+
+// UpdateApprovalRuleHandler changes a rule; min_total null removes the
+// threshold. Orders already waiting keep the steps they were given.
+func UpdateApprovalRuleHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewApprovalRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req approvalRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		ar, err := repo.GetRule(ctx, chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "approval rule not found"}})
+			return
+		}
+		req.apply(ar)
+		if err := validateApprovalRule(ar); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		if err := repo.UpdateRule(ctx, ar); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: ar})
+	}
+}
+
+func DeleteApprovalRuleHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewApprovalRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		id := chi.URLParam(r, "id")
+		if err := repo.DeleteRule(ctx, id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "approval rule not found"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"id": id, "status": "deleted"}})
+	}
+}
+
+// ListOrderApprovalsHandler returns the approval steps of every round of the
+// order and the level currently waiting for a decision.
+func ListOrderApprovalsHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewApprovalRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadOrderForApproval(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		list, err := repo.ListByOrder(ctx, o.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		current, err := repo.CurrentRound(ctx, o.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]any{
+			"status":        o.Status,
+			"pending_level": pendingLevel(current),
+			"items":         list,
+		}})
+	}
+}
+
+// ApprovalQueueHandler lists the orders waiting for the caller's decision,
+// oldest request first.
+func ApprovalQueueHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewApprovalRepository(db)
+	orders := storage.NewOrderRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		steps, err := repo.Queue(ctx, ac.UserID, ac.Roles, hasRole(ac.Roles, "admin"), maxApprovalQueue)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		items := []orderApprovalState{}
+		index := map[string]int{}
+		for _, a := range steps {
+			i, ok := index[a.OrderID]
+			if !ok {
+				o, err := orders.GetByID(ctx, a.OrderID)
+				if err != nil {
+					writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+					return
+				}
+				i = len(items)
+				index[a.OrderID] = i
+				items = append(items, orderApprovalState{Order: o, Approvals: []models.OrderApproval{}})
+			}
+			items[i].Approvals = append(items[i].Approvals, a)
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: items})
+	}
+}
+
+// ApproveOrderHandler approves the caller's steps at the current level. Once
+// the last level is approved the order moves on to created.
+func ApproveOrderHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		req, err := decodeApprovalDecision(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		o, status, apiErr := loadOrderForApproval(ctx, tx, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if o.Status != models.OrderStatusPendingApproval {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_pending", Message: fmt.Sprintf("order in status %s is not waiting for approval", o.Status)}})
+			return
+		}
+		repo := storage.NewApprovalRepository(tx)
+		steps, err := repo.CurrentRound(ctx, o.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		idx, apiErr := decidableSteps(steps, o, ac)
+		if apiErr != nil {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: apiErr})
+			return
+		}
+		now := time.Now()
+		for _, i := range idx {
+			a := &steps[i]
+			if err := repo.Decide(ctx, a, models.ApprovalApproved, ac.UserID, req.Comment, now); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_pending", Message: "approval changed, retry"}})
+					return
+				}
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			if err := storage.AddOutboxEvent(ctx, tx, events.OrderApprovalGranted, map[string]any{
+				"id":            o.ID,
+				"user_id":       o.UserID,
+				"round":         a.Round,
+				"level":         a.Level,
+				"approver_role": a.ApproverRole,
+				"by":            ac.UserID,
+				"comment":       req.Comment,
+			}); err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+		}
+		if pendingLevel(steps) == 0 {
+			if err := setApprovalStatus(ctx, tx, o, models.OrderStatusCreated, ac.UserID); err != nil {
+				if errors.Is(err, storage.ErrOrderNotEditable) {
+					writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_editable", Message: "order status changed, retry"}})
+					return
+				}
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			if err := storage.AddOutboxEvent(ctx, tx, events.OrderApproved, map[string]any{
+				"id":      o.ID,
+				"user_id": o.UserID,
+				"round":   steps[0].Round,
+				"total":   o.TotalAmount,
+			}); err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: orderApprovalState{Order: o, Approvals: steps}})
+	}
+}
+
+// RejectOrderHandler rejects the order at the current level; a comment is
+// required. The remaining steps are closed and the order is cancelled.
+func RejectOrderHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		req, err := decodeApprovalDecision(r)
+		if err == nil && req.Comment == "" {
+			err = errors.New("comment required")
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		o, status, apiErr := loadOrderForApproval(ctx, tx, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if o.Status != models.OrderStatusPendingApproval {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_pending", Message: fmt.Sprintf("order in status %s is not waiting for approval", o.Status)}})
+			return
+		}
+		repo := storage.NewApprovalRepository(tx)
+		steps, err := repo.CurrentRound(ctx, o.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		idx, apiErr := decidableSteps(steps, o, ac)
+		if apiErr != nil {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: apiErr})
+			return
+		}
+		a := &steps[idx[0]]
+		if err := repo.Decide(ctx, a, models.ApprovalRejected, ac.UserID, req.Comment, time.Now()); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_pending", Message: "approval changed, retry"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := repo.CancelPending(ctx, o.ID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		for i := range steps {
+			if steps[i].Status == models.ApprovalPending {
+				steps[i].Status = models.ApprovalCancelled
+			}
+		}
+		if err := setApprovalStatus(ctx, tx, o, models.OrderStatusCancelled, ac.UserID); err != nil {
+			if errors.Is(err, storage.ErrOrderNotEditable) {
+				writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_editable", Message: "order status changed, retry"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderApprovalRejected, map[string]any{
+			"id":            o.ID,
+			"user_id":       o.UserID,
+			"round":         a.Round,
+			"level":         a.Level,
+			"approver_role": a.ApproverRole,
+			"by":            ac.UserID,
+			"comment":       req.Comment,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: orderApprovalState{Order: o, Approvals: steps}})
+	}
+}
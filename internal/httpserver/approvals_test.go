@@ -0,0 +1,102 @@
+package httpserver
+
+import (
+	"testing"
+
+	"frame_control_system/internal/models"
+)
+
+func TestApprovalSteps(t *testing.T) {
+	amount := func(v float64) *float64 { return &v }
+	rules := []models.ApprovalRule{
+		{Name: "large", MinTotal: amount(1000), Level: 1, ApproverRole: "manager"},
+		{Name: "chemicals", Categories: []string{"chemicals"}, Level: 1, ApproverRole: "manager"},
+		{Name: "huge", MinTotal: amount(10000), Level: 2, ApproverRole: "director"},
+		{Name: "safety", Categories: []string{"explosives"}, Level: 1, ApproverRole: "safety"},
+	}
+
+	small := models.Order{TotalAmount: 500, Items: []models.OrderItem{{Name: "rebar", Category: "metal"}}}
+	if steps := approvalSteps(rules, small); len(steps) != 0 {
+		t.Fatalf("small order: expected no steps, got %+v", steps)
+	}
+
+	big := models.Order{TotalAmount: 20000, Items: []models.OrderItem{{Name: "acid", Category: "chemicals"}}}
+	steps := approvalSteps(rules, big)
+	if len(steps) != 2 {
+		t.Fatalf("big order: expected 2 steps, got %+v", steps)
+	}
+	if steps[0].Level != 1 || steps[0].ApproverRole != "manager" || len(steps[0].Rules) != 2 || steps[0].Rules[0] != "large" || steps[0].Rules[1] != "chemicals" {
+		t.Fatalf("level 1: got %+v", steps[0])
+	}
+	if steps[1].Level != 2 || steps[1].ApproverRole != "director" {
+		t.Fatalf("level 2: got %+v", steps[1])
+	}
+
+	tnt := models.Order{TotalAmount: 10, Items: []models.OrderItem{{Name: "tnt", Category: "explosives"}, {Name: "acid", Category: "chemicals"}}}
+	steps = approvalSteps(rules, tnt)
+	if len(steps) != 2 || steps[0].ApproverRole != "manager" || steps[1].ApproverRole != "safety" {
+		t.Fatalf("same level: expected manager and safety, got %+v", steps)
+	}
+}
+
+func TestValidateApprovalRule(t *testing.T) {
+	amount := func(v float64) *float64 { return &v }
+	ar := models.ApprovalRule{Name: " Chemicals ", Categories: []string{" Chemicals", "chemicals", "ACIDS"}, Level: 2, ApproverRole: " Safety_Officer "}
+	if err := validateApprovalRule(&ar); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ar.Name != "Chemicals" || len(ar.Categories) != 2 || ar.Categories[0] != "chemicals" || ar.Categories[1] != "acids" || ar.ApproverRole != "safety_officer" {
+		t.Fatalf("not normalized: %+v", ar)
+	}
+
+	bad := map[string]models.ApprovalRule{
+		"no name":         {MinTotal: amount(100), Level: 1, ApproverRole: "manager"},
+		"no condition":    {Name: "x", Level: 1, ApproverRole: "manager"},
+		"negative total":  {Name: "x", MinTotal: amount(-1), Level: 1, ApproverRole: "manager"},
+		"empty category":  {Name: "x", Categories: []string{" "}, Level: 1, ApproverRole: "manager"},
+		"level too low":   {Name: "x", MinTotal: amount(100), Level: 0, ApproverRole: "manager"},
+		"level too high":  {Name: "x", MinTotal: amount(100), Level: maxApprovalLevel + 1, ApproverRole: "manager"},
+		"bad role":        {Name: "x", MinTotal: amount(100), Level: 1, ApproverRole: "head of sales"},
+		"viewer approver": {Name: "x", MinTotal: amount(100), Level: 1, ApproverRole: "viewer"},
+	}
+	for name, ar := range bad {
+		if err := validateApprovalRule(&ar); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDecidableSteps(t *testing.T) {
+	order := &models.Order{UserID: "owner"}
+	steps := []models.OrderApproval{
+		{Level: 1, ApproverRole: "manager", Status: models.ApprovalApproved, DecidedBy: "m1"},
+		{Level: 1, ApproverRole: "safety", Status: models.ApprovalPending},
+		{Level: 2, ApproverRole: "director", Status: models.ApprovalPending},
+	}
+
+	if _, apiErr := decidableSteps(steps, order, &AuthContext{UserID: "owner", Roles: []string{"admin"}}); apiErr == nil {
+		t.Fatal("owner must not decide on own order")
+	}
+	if _, apiErr := decidableSteps(steps, order, &AuthContext{UserID: "d1", Roles: []string{"director"}}); apiErr == nil {
+		t.Fatal("level 2 must wait for level 1")
+	}
+	if _, apiErr := decidableSteps(steps, order, &AuthContext{UserID: "m1", Roles: []string{"manager", "safety"}}); apiErr == nil {
+		t.Fatal("a user must not decide twice in a round")
+	}
+	idx, apiErr := decidableSteps(steps, order, &AuthContext{UserID: "s1", Roles: []string{"safety"}})
+	if apiErr != nil || len(idx) != 1 || idx[0] != 1 {
+		t.Fatalf("safety: got %v %v", idx, apiErr)
+	}
+	idx, apiErr = decidableSteps(steps, order, &AuthContext{UserID: "a1", Roles: []string{"admin"}})
+	if apiErr != nil || len(idx) != 1 || idx[0] != 1 {
+		t.Fatalf("admin: got %v %v", idx, apiErr)
+	}
+
+	if level := pendingLevel(steps); level != 1 {
+		t.Fatalf("pending level: got %d", level)
+	}
+	steps[1].Status, steps[2].Status = models.ApprovalApproved, models.ApprovalApproved
+	if level := pendingLevel(steps); level != 0 {
+		t.Fatalf("complete round: got level %d", level)
+	}
+}
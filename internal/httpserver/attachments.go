@@ -0,0 +1,409 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/blobstore"
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/imaging"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const maxFilenameLength = 255
+
+var errFileTooLarge = errors.New("file too large")
+
+// limitedReader fails with errFileTooLarge instead of silently truncating.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, errFileTooLarge
+	}
+	return n, err
+}
+
+// detectContentType sniffs the MIME type from the first bytes of the content;
+// the client-supplied Content-Type is not trusted.
+func detectContentType(head []byte) string {
+	ct := http.DetectContentType(head)
+	if mt, _, err := mime.ParseMediaType(ct); err == nil {
+		return mt
+	}
+	return ct
+}
+
+func allowedContentType(ct string, allowed []string) bool {
+	for _, a := range allowed {
+		if strings.EqualFold(a, ct) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeFilename keeps the base name without control characters, as it is
+// echoed back in Content-Disposition.
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	if r := []rune(name); len(r) > maxFilenameLength {
+		name = string(r[len(r)-maxFilenameLength:])
+	}
+	return name
+}
+
+func ListAttachmentsHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewAttachmentRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		list, err := repo.ListByOrder(ctx, o.ID)
+		if err == nil {
+			err = attachThumbnails(ctx, repo, list)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: list})
+	}
+}
+
+// UploadAttachmentHandler accepts a multipart/form-data body with a "file"
+// part. The content type is sniffed and must be in allowed.
+func UploadAttachmentHandler(db *sql.DB, blobs blobstore.Store, maxBytes int64, allowed []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadWritableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+
+		// Leave room for multipart headers around the file itself.
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes+64<<10)
+		mr, err := r.MultipartReader()
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "multipart/form-data body with a file field required"}})
+			return
+		}
+		var part io.Reader
+		var filename string
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			if p.FormName() == "file" {
+				part, filename = p, sanitizeFilename(p.FileName())
+				break
+			}
+		}
+		if part == nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "file field required"}})
+			return
+		}
+		head := make([]byte, 512)
+		n, err := io.ReadFull(part, head)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "empty file"}})
+			return
+		}
+		head = head[:n]
+		contentType := detectContentType(head)
+		if !allowedContentType(contentType, allowed) {
+			writeJSON(w, http.StatusUnsupportedMediaType, envelope{Success: false, Error: &apiError{Code: "unsupported_media_type", Message: "file type " + contentType + " is not allowed"}})
+			return
+		}
+
+		key, size, err := blobs.Put(r.Context(), &limitedReader{r: io.MultiReader(bytes.NewReader(head), part), n: maxBytes})
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.Is(err, errFileTooLarge) || errors.As(err, &mbe) {
+				writeJSON(w, http.StatusRequestEntityTooLarge, envelope{Success: false, Error: &apiError{Code: "file_too_large", Message: "file exceeds " + strconv.FormatInt(maxBytes, 10) + " bytes"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "storage error"}})
+			return
+		}
+
+		a := &models.Attachment{
+			OrderID:     o.ID,
+			BlobKey:     key,
+			Filename:    filename,
+			ContentType: contentType,
+			Size:        size,
+			UploadedBy:  ac.UserID,
+			MediaStatus: mediaStatusFor(contentType),
+		}
+		if err := saveAttachment(ctx, db, a); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: a})
+	}
+}
+
+// attachThumbnails fills in the thumbnails and their download URLs.
+func attachThumbnails(ctx context.Context, repo *storage.AttachmentRepository, list []models.Attachment) error {
+	ids := make([]string, len(list))
+	for i, a := range list {
+		ids[i] = a.ID
+	}
+	thumbs, err := repo.Thumbnails(ctx, ids...)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		a := &list[i]
+		a.Thumbnails = thumbs[a.ID]
+		for j := range a.Thumbnails {
+			a.Thumbnails[j].URL = fmt.Sprintf("/api/v1/orders/%s/attachments/%s/thumbnails/%d", a.OrderID, a.ID, a.Thumbnails[j].Size)
+		}
+	}
+	return nil
+}
+
+// mediaStatusFor tells the thumbnail worker which uploads to pick up.
+func mediaStatusFor(contentType string) string {
+	switch {
+	case imaging.Supported(contentType):
+		return models.MediaPending
+	case strings.HasPrefix(contentType, "image/"):
+		return models.MediaUnsupported
+	}
+	return models.MediaNone
+}
+
+func saveAttachment(ctx context.Context, db *sql.DB, a *models.Attachment) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := storage.NewAttachmentRepository(tx).Create(ctx, a); err != nil {
+		return err
+	}
+	if err := storage.AddOutboxEvent(ctx, tx, events.OrderAttachmentAdded, map[string]any{
+		"order_id":      a.OrderID,
+		"attachment_id": a.ID,
+		"filename":      a.Filename,
+		"content_type":  a.ContentType,
+		"size":          a.Size,
+		"uploaded_by":   a.UploadedBy,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DownloadAttachmentHandler streams the file. inline=true asks browsers to
+// display it instead of saving. With stripEXIF, JPEG originals are served
+// without their EXIF/XMP metadata.
+func DownloadAttachmentHandler(db *sql.DB, blobs blobstore.Store, stripEXIF bool) http.HandlerFunc {
+	repo := storage.NewAttachmentRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		a, err := repo.GetByID(ctx, o.ID, chi.URLParam(r, "attachmentID"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "attachment not found"}})
+			return
+		}
+		rc, err := blobs.Open(r.Context(), a.BlobKey)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "attachment content missing"}})
+			return
+		}
+		defer rc.Close()
+		var body io.Reader = rc
+		size := a.Size
+		if stripEXIF && a.ContentType == "image/jpeg" {
+			data, err := io.ReadAll(rc)
+			var buf bytes.Buffer
+			if err == nil {
+				err = imaging.StripJPEGMetadata(&buf, data)
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "storage error"}})
+				return
+			}
+			body, size = &buf, int64(buf.Len())
+		}
+		disposition := "attachment"
+		if r.URL.Query().Get("inline") == "true" {
+			disposition = "inline"
+		}
+		w.Header().Set("Content-Type", a.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.Copy(w, body)
+	}
+}
+
+// ThumbnailHandler serves a generated JPEG thumbnail of an image attachment.
+func ThumbnailHandler(db *sql.DB, blobs blobstore.Store) http.HandlerFunc {
+	repo := storage.NewAttachmentRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		size, err := strconv.Atoi(chi.URLParam(r, "size"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "size must be a number"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		a, err := repo.GetByID(ctx, o.ID, chi.URLParam(r, "attachmentID"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "attachment not found"}})
+			return
+		}
+		thumbs, err := repo.Thumbnails(ctx, a.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		var key string
+		for _, t := range thumbs[a.ID] {
+			if t.Size == size {
+				key = t.BlobKey
+			}
+		}
+		if key == "" {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "thumbnail not available"}})
+			return
+		}
+		rc, err := blobs.Open(r.Context(), key)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "thumbnail content missing"}})
+			return
+		}
+		defer rc.Close()
+		w.Header().Set("Content-Type", "image/jpeg")
+		// Thumbnails never change for a given attachment and size.
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.Copy(w, rc)
+	}
+}
+
+// DeleteAttachmentHandler removes an attachment; allowed to the uploader, the
+// order owner and admins.
+func DeleteAttachmentHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewAttachmentRepository(tx)
+		a, err := repo.GetByID(ctx, o.ID, chi.URLParam(r, "attachmentID"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "attachment not found"}})
+			return
+		}
+		if a.UploadedBy != ac.UserID && !canModifyOrder(o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		// Thumbnails go with it (ON DELETE CASCADE); blobs nobody references
+		// any more are removed later by the blob sweeper.
+		err = repo.Delete(ctx, a.ID)
+		if err == nil {
+			err = storage.AddOutboxEvent(ctx, tx, events.OrderAttachmentDeleted, map[string]any{
+				"order_id":      o.ID,
+				"attachment_id": a.ID,
+				"deleted_by":    ac.UserID,
+			})
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"id": a.ID, "status": "deleted"}})
+	}
+}
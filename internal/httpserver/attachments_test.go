@@ -0,0 +1,46 @@
+package httpserver
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := map[string]string{
+		"photo.jpg":              "photo.jpg",
+		"../../etc/passwd":       "passwd",
+		`C:\Users\ivan\act.pdf`:  "act.pdf",
+		"bad\r\nname.png":        "badname.png",
+		"":                       "file",
+		"  ":                     "file",
+		strings.Repeat("a", 300): strings.Repeat("a", maxFilenameLength),
+	}
+	for in, want := range tests {
+		if got := sanitizeFilename(in); got != want {
+			t.Fatalf("sanitizeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	if ct := detectContentType([]byte("%PDF-1.7\n")); ct != "application/pdf" {
+		t.Fatalf("got %q", ct)
+	}
+	if ct := detectContentType([]byte("\x89PNG\r\n\x1a\n")); ct != "image/png" {
+		t.Fatalf("got %q", ct)
+	}
+	if ct := detectContentType([]byte("plain text")); ct != "text/plain" {
+		t.Fatalf("got %q", ct)
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	if _, err := io.ReadAll(&limitedReader{r: strings.NewReader("12345"), n: 5}); err != nil {
+		t.Fatalf("exact size should pass: %v", err)
+	}
+	if _, err := io.ReadAll(&limitedReader{r: strings.NewReader("123456"), n: 5}); !errors.Is(err, errFileTooLarge) {
+		t.Fatalf("expected errFileTooLarge, got %v", err)
+	}
+}
@@ -51,6 +51,43 @@ func RequireRole(role string) func(next http.Handler) http.Handler {
 	}
 }
 
+// RequireAnyRole lets the request through if the user has at least one of roles.
+func RequireAnyRole(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ac := GetAuth(r)
+			if ac == nil || !hasAnyRole(ac.Roles, roles...) {
+				writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "insufficient permissions"}})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ReadOnlyViewers rejects changes made by viewer accounts; they may only read
+// what was shared with them and edit their own profile.
+func ReadOnlyViewers() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ac := GetAuth(r)
+			if ac != nil && hasRole(ac.Roles, "viewer") && !viewerAllowed(r) {
+				writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "viewer accounts are read-only"}})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func viewerAllowed(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return r.Method == http.MethodPatch && strings.HasSuffix(r.URL.Path, "/users/me")
+}
+
 func GetAuth(r *http.Request) *AuthContext {
 	if v := r.Context().Value(authCtxKey{}); v != nil {
 		if ac, ok := v.(*AuthContext); ok {
@@ -69,4 +106,11 @@ func hasRole(roles []string, role string) bool {
 	return false
 }
 
-
+func hasAnyRole(roles []string, want ...string) bool {
+	for _, w := range want {
+		if hasRole(roles, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,316 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/auth"
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/ical"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	calendarProdID = "-//Frame Control System//Deadlines//EN"
+	calendarDomain = "frame-control-system"
+	// maxCalendarItems caps the orders and the defects of one feed.
+	maxCalendarItems = 1000
+)
+
+type calendarFeedLink struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// calendarEntry is one deadline of the feed, rendered as a VEVENT or a VTODO.
+type calendarEntry struct {
+	UID         string
+	Summary     string
+	Description string
+	Category    string
+	Due         time.Time
+	Updated     time.Time
+	// Priority uses the RFC 5545 scale: 1 highest, 9 lowest.
+	Priority int
+	// Status is the VTODO status; VEVENTs only distinguish cancelled ones.
+	Status string
+}
+
+func orderCalendarEntry(o models.Order) calendarEntry {
+	names := make([]string, 0, len(o.Items))
+	for _, it := range o.Items {
+		names = append(names, it.Name)
+	}
+	e := calendarEntry{
+		UID:         "order-" + o.ID + "@" + calendarDomain,
+		Summary:     "Order " + shortID(o.ID),
+		Description: o.Notes,
+		Category:    "ORDER",
+		Due:         *o.DueAt,
+		Updated:     o.UpdatedAt,
+		Status:      "NEEDS-ACTION",
+	}
+	if len(names) > 0 {
+		e.Summary += ": " + strings.Join(names, ", ")
+	}
+	switch o.Priority {
+	case models.OrderPriorityUrgent:
+		e.Priority = 1
+	case models.OrderPriorityHigh:
+		e.Priority = 3
+	case models.OrderPriorityLow:
+		e.Priority = 9
+	default:
+		e.Priority = 5
+	}
+	switch o.Status {
+	case models.OrderStatusInProgress:
+		e.Status = "IN-PROCESS"
+	case models.OrderStatusDone:
+		e.Status = "COMPLETED"
+	case models.OrderStatusCancelled:
+		e.Status = "CANCELLED"
+	}
+	return e
+}
+
+func defectCalendarEntry(d models.Defect) calendarEntry {
+	e := calendarEntry{
+		UID:         "defect-" + d.ID + "@" + calendarDomain,
+		Summary:     "Defect: " + d.Title,
+		Description: d.Description,
+		Category:    "DEFECT",
+		Due:         *d.DueAt,
+		Updated:     d.UpdatedAt,
+		Status:      "NEEDS-ACTION",
+	}
+	if d.Location != "" {
+		if e.Description != "" {
+			e.Description += "\n"
+		}
+		e.Description += "Location: " + d.Location
+	}
+	switch d.Severity {
+	case models.DefectSeverityCritical:
+		e.Priority = 1
+	case models.DefectSeverityHigh:
+		e.Priority = 3
+	case models.DefectSeverityLow:
+		e.Priority = 9
+	default:
+		e.Priority = 5
+	}
+	switch d.Status {
+	case models.DefectStatusInWork, models.DefectStatusOnReview:
+		e.Status = "IN-PROCESS"
+	case models.DefectStatusClosed:
+		e.Status = "COMPLETED"
+	case models.DefectStatusRejected:
+		e.Status = "CANCELLED"
+	}
+	return e
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// writeCalendar renders the entries as VTODOs or as zero-length VEVENTs at
+// the deadline. DTSTAMP and LAST-MODIFIED follow the item's updated_at, so
+// clients pick up status changes on the next refresh.
+func writeCalendar(out io.Writer, name string, entries []calendarEntry, todo bool) error {
+	w := ical.NewWriter(out)
+	w.Begin("VCALENDAR")
+	w.Prop("VERSION", "2.0")
+	w.Prop("PRODID", calendarProdID)
+	w.Prop("CALSCALE", "GREGORIAN")
+	w.Text("X-WR-CALNAME", name)
+	component := "VEVENT"
+	if todo {
+		component = "VTODO"
+	}
+	for _, e := range entries {
+		w.Begin(component)
+		w.Text("UID", e.UID)
+		w.Time("DTSTAMP", e.Updated)
+		w.Time("LAST-MODIFIED", e.Updated)
+		if todo {
+			w.Time("DUE", e.Due)
+		} else {
+			w.Time("DTSTART", e.Due)
+		}
+		w.Text("SUMMARY", e.Summary)
+		if e.Description != "" {
+			w.Text("DESCRIPTION", e.Description)
+		}
+		w.Text("CATEGORIES", e.Category)
+		w.Prop("PRIORITY", strconv.Itoa(e.Priority))
+		switch {
+		case todo:
+			w.Prop("STATUS", e.Status)
+			if e.Status == "COMPLETED" {
+				w.Time("COMPLETED", e.Updated)
+			}
+		case e.Status == "CANCELLED":
+			w.Prop("STATUS", "CANCELLED")
+		default:
+			w.Prop("STATUS", "CONFIRMED")
+		}
+		w.End(component)
+	}
+	w.End("VCALENDAR")
+	return w.Flush()
+}
+
+// CreateCalendarFeedHandler issues the caller's calendar feed token. Issuing a
+// new one revokes the previous token; the token is shown only once.
+func CreateCalendarFeedHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		token, hash, err := auth.NewFeedToken()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "token error"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		if err := storage.NewCalendarFeedRepository(tx).SetToken(ctx, ac.UserID, hash); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.CalendarFeedCreated, map[string]any{
+			"user_id": ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: calendarFeedLink{
+			Token: token,
+			URL:   "/api/v1/calendar/" + token + ".ics",
+		}})
+	}
+}
+
+// RevokeCalendarFeedHandler disables the caller's calendar feed.
+func RevokeCalendarFeedHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		if err := storage.NewCalendarFeedRepository(tx).Delete(ctx, ac.UserID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "calendar feed not enabled"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.CalendarFeedRevoked, map[string]any{
+			"user_id": ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"status": "revoked"}})
+	}
+}
+
+// CalendarFeedHandler serves the iCalendar feed behind a feed token: orders
+// assigned to the user and defects the user is responsible for, as long as
+// they have a due date. ?kind=todo switches from VEVENTs to VTODOs.
+func CalendarFeedHandler(db *sql.DB) http.HandlerFunc {
+	feeds := storage.NewCalendarFeedRepository(db)
+	users := storage.NewUserRepository(db)
+	orders := storage.NewOrderRepository(db)
+	defects := storage.NewDefectRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("X-Robots-Tag", "noindex")
+		kind := r.URL.Query().Get("kind")
+		if kind != "" && kind != "event" && kind != "todo" {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "kind must be event or todo"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		userID, err := feeds.UserIDByTokenHash(ctx, auth.HashFeedToken(chi.URLParam(r, "token")))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "calendar feed not found"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		u, err := users.GetByID(ctx, userID)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "calendar feed not found"}})
+			return
+		}
+		assigned, err := orders.List(ctx, storage.ListOrdersParams{AdminView: true, AssigneeID: u.ID, Limit: maxCalendarItems})
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		responsible, err := defects.List(ctx, storage.ListDefectsParams{AdminView: true, ResponsibleID: u.ID, Limit: maxCalendarItems})
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		entries := []calendarEntry{}
+		for _, o := range assigned {
+			if o.DueAt != nil {
+				entries = append(entries, orderCalendarEntry(o))
+			}
+		}
+		for _, d := range responsible {
+			if d.DueAt != nil {
+				entries = append(entries, defectCalendarEntry(d))
+			}
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", `inline; filename="deadlines.ics"`)
+		_ = writeCalendar(w, "Deadlines: "+u.Name, entries, kind == "todo")
+	}
+}
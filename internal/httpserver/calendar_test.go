@@ -0,0 +1,85 @@
+package httpserver
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"frame_control_system/internal/models"
+)
+
+func TestOrderCalendarEntry(t *testing.T) {
+	due := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	o := models.Order{
+		ID:       "0123456789abcdef",
+		Items:    []models.OrderItem{{Name: "Frame"}, {Name: "Glass"}},
+		Status:   models.OrderStatusDone,
+		Priority: models.OrderPriorityUrgent,
+		DueAt:    &due,
+	}
+	e := orderCalendarEntry(o)
+	if e.Summary != "Order 01234567: Frame, Glass" || e.Priority != 1 || e.Status != "COMPLETED" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e.UID != "order-0123456789abcdef@"+calendarDomain {
+		t.Fatalf("unexpected uid %q", e.UID)
+	}
+}
+
+func TestDefectCalendarEntry(t *testing.T) {
+	due := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	e := defectCalendarEntry(models.Defect{
+		ID:       "d1",
+		Title:    "Crack",
+		Location: "Floor 2",
+		Severity: models.DefectSeverityLow,
+		Status:   models.DefectStatusOnReview,
+		DueAt:    &due,
+	})
+	if e.Summary != "Defect: Crack" || e.Description != "Location: Floor 2" || e.Priority != 9 || e.Status != "IN-PROCESS" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+}
+
+func TestWriteCalendar(t *testing.T) {
+	due := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	updated := time.Date(2025, 5, 20, 12, 30, 0, 0, time.UTC)
+	entries := []calendarEntry{
+		{UID: "order-1@x", Summary: "Order 1", Category: "ORDER", Due: due, Updated: updated, Priority: 5, Status: "COMPLETED"},
+		{UID: "defect-2@x", Summary: "Defect: a, b", Category: "DEFECT", Due: due, Updated: updated, Priority: 1, Status: "CANCELLED"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeCalendar(&buf, "Deadlines", entries, false); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:",
+		"BEGIN:VEVENT\r\nUID:order-1@x\r\nDTSTAMP:20250520T123000Z\r\n",
+		"DTSTART:20250601T090000Z\r\n",
+		"SUMMARY:Defect: a\\, b\r\n",
+		"STATUS:CONFIRMED\r\n",
+		"STATUS:CANCELLED\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "VTODO") || strings.Contains(out, "COMPLETED") {
+		t.Fatalf("events must not carry todo properties:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := writeCalendar(&buf, "Deadlines", entries, true); err != nil {
+		t.Fatal(err)
+	}
+	out = buf.String()
+	for _, want := range []string{"BEGIN:VTODO\r\n", "DUE:20250601T090000Z\r\n", "STATUS:COMPLETED\r\nCOMPLETED:20250520T123000Z\r\n"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+}
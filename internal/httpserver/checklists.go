@@ -0,0 +1,461 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	maxChecklistItems       = 100
+	maxChecklistItemLength  = 500
+	maxChecklistDescription = 2000
+)
+
+type checklistTemplateRequest struct {
+	Name        *string                         `json:"name"`
+	Description *string                         `json:"description"`
+	Items       *[]models.ChecklistTemplateItem `json:"items"`
+}
+
+type createChecklistRequest struct {
+	TemplateID string `json:"template_id"`
+}
+
+type checkItemRequest struct {
+	Checked *bool `json:"checked"`
+}
+
+// validateChecklistTemplate trims the template in place and checks its limits.
+func validateChecklistTemplate(t *models.ChecklistTemplate) error {
+	t.Name = strings.TrimSpace(t.Name)
+	t.Description = strings.TrimSpace(t.Description)
+	if t.Name == "" || utf8.RuneCountInString(t.Name) > 200 {
+		return errors.New("name must be 1-200 characters")
+	}
+	if utf8.RuneCountInString(t.Description) > maxChecklistDescription {
+		return fmt.Errorf("description must be at most %d characters", maxChecklistDescription)
+	}
+	if len(t.Items) == 0 || len(t.Items) > maxChecklistItems {
+		return fmt.Errorf("a template needs 1-%d items", maxChecklistItems)
+	}
+	for i := range t.Items {
+		t.Items[i].Text = strings.TrimSpace(t.Items[i].Text)
+		if t.Items[i].Text == "" || utf8.RuneCountInString(t.Items[i].Text) > maxChecklistItemLength {
+			return fmt.Errorf("item %d: text must be 1-%d characters", i+1, maxChecklistItemLength)
+		}
+	}
+	return nil
+}
+
+// canCheckOffItems: whoever plans the order, its assignee, and the managers
+// and engineers of its project, who carry out the acceptance.
+func canCheckOffItems(ctx context.Context, q storage.DBTX, o *models.Order, ac *AuthContext) bool {
+	if canPlanOrder(o, ac) || o.AssigneeID == ac.UserID {
+		return true
+	}
+	if o.ProjectID == "" {
+		return false
+	}
+	m, err := storage.NewProjectRepository(q).GetMember(ctx, o.ProjectID, ac.UserID)
+	return err == nil && (m.Role == models.ProjectRoleManager || m.Role == models.ProjectRoleEngineer)
+}
+
+// ListChecklistTemplatesHandler returns all templates; every signed-in user
+// may pick one for an order.
+func ListChecklistTemplatesHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewChecklistRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		list, err := repo.ListTemplates(ctx)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: list})
+	}
+}
+
+func GetChecklistTemplateHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewChecklistRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		t, err := repo.GetTemplate(ctx, chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "checklist template not found"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: t})
+	}
+}
+
+func CreateChecklistTemplateHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewChecklistRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req checklistTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		t := &models.ChecklistTemplate{CreatedBy: ac.UserID}
+		if req.Name != nil {
+			t.Name = *req.Name
+		}
+		if req.Description != nil {
+			t.Description = *req.Description
+		}
+		if req.Items != nil {
+			t.Items = *req.Items
+		}
+		if err := validateChecklistTemplate(t); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		if err := repo.CreateTemplate(ctx, t); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: t})
+	}
+}
+
+// UpdateChecklistTemplateHandler changes a template; items are replaced as a
+// whole. Checklists already attached to orders keep their copy.
+func UpdateChecklistTemplateHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewChecklistRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req checklistTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		t, err := repo.GetTemplate(ctx, chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "checklist template not found"}})
+			return
+		}
+		if req.Name != nil {
+			t.Name = *req.Name
+		}
+		if req.Description != nil {
+			t.Description = *req.Description
+		}
+		if req.Items != nil {
+			t.Items = *req.Items
+		}
+		if err := validateChecklistTemplate(t); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		if err := repo.UpdateTemplate(ctx, t); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: t})
+	}
+}
+
+func DeleteChecklistTemplateHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewChecklistRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		id := chi.URLParam(r, "id")
+		if err := repo.DeleteTemplate(ctx, id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "checklist template not found"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"id": id, "status": "deleted"}})
+	}
+}
+
+func ListOrderChecklistsHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewChecklistRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		list, err := repo.ListByOrder(ctx, o.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: list})
+	}
+}
+
+// CreateOrderChecklistHandler attaches a copy of a template to an open order.
+func CreateOrderChecklistHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req createChecklistRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.TemplateID) == "" {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "template_id required"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canPlanOrder(o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		if !o.Open() {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_editable", Message: fmt.Sprintf("order in status %s cannot be edited", o.Status)}})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewChecklistRepository(tx)
+		t, err := repo.GetTemplate(ctx, strings.TrimSpace(req.TemplateID))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "checklist template not found"}})
+			return
+		}
+		c, err := repo.Instantiate(ctx, t, o.ID, ac.UserID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderChecklistAdded, map[string]any{
+			"order_id":     o.ID,
+			"checklist_id": c.ID,
+			"template_id":  t.ID,
+			"added_by":     ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: c})
+	}
+}
+
+// checkChecklistRemovable keeps open mandatory items from being removed to get
+// around the checklist gate on done; only admins may drop such a checklist.
+func checkChecklistRemovable(c *models.Checklist, ac *AuthContext) *apiError {
+	if c.MandatoryOpen > 0 && !hasRole(ac.Roles, "admin") {
+		return &apiError{Code: "checklist_incomplete", Message: fmt.Sprintf("%d mandatory checklist items are not completed", c.MandatoryOpen)}
+	}
+	return nil
+}
+
+// DeleteOrderChecklistHandler removes a checklist from an open order. A
+// checklist with open mandatory items can only be removed by an admin.
+func DeleteOrderChecklistHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canPlanOrder(o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		if !o.Open() {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_editable", Message: fmt.Sprintf("order in status %s cannot be edited", o.Status)}})
+			return
+		}
+		checklistID := chi.URLParam(r, "checklistID")
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		checklists := storage.NewChecklistRepository(tx)
+		c, err := checklists.Get(ctx, o.ID, checklistID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "checklist not found"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if apiErr := checkChecklistRemovable(c, ac); apiErr != nil {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if err := checklists.Delete(ctx, o.ID, checklistID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "checklist not found"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderChecklistRemoved, map[string]any{
+			"order_id":     o.ID,
+			"checklist_id": checklistID,
+			"removed_by":   ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"id": checklistID, "status": "deleted"}})
+	}
+}
+
+// CheckChecklistItemHandler checks an item off, recording who did it and
+// when, or clears the check. The order must still be open.
+func CheckChecklistItemHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req checkItemRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Checked == nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "checked required"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canCheckOffItems(ctx, db, o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		if !o.Open() {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_editable", Message: fmt.Sprintf("order in status %s cannot be edited", o.Status)}})
+			return
+		}
+		checklistID, itemID := chi.URLParam(r, "checklistID"), chi.URLParam(r, "itemID")
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewChecklistRepository(tx)
+		c, err := repo.Get(ctx, o.ID, checklistID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "checklist not found"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		var item *models.ChecklistItem
+		for i := range c.Items {
+			if c.Items[i].ID == itemID {
+				item = &c.Items[i]
+			}
+		}
+		if item == nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "checklist item not found"}})
+			return
+		}
+		if item.Checked == *req.Checked {
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: c})
+			return
+		}
+		var at *time.Time
+		eventType := events.OrderChecklistCleared
+		if *req.Checked {
+			now := time.Now().UTC().Truncate(time.Second)
+			at = &now
+			eventType = events.OrderChecklistChecked
+		}
+		if err := repo.SetItemChecked(ctx, c.ID, item.ID, ac.UserID, at); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, eventType, map[string]any{
+			"order_id":     o.ID,
+			"checklist_id": c.ID,
+			"item_id":      item.ID,
+			"mandatory":    item.Mandatory,
+			"by":           ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		item.Checked, item.CheckedAt, item.CheckedBy = at != nil, at, ""
+		if at != nil {
+			item.CheckedBy = ac.UserID
+		}
+		if item.Mandatory {
+			if at != nil {
+				c.MandatoryOpen--
+			} else {
+				c.MandatoryOpen++
+			}
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: c})
+	}
+}
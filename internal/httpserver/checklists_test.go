@@ -0,0 +1,50 @@
+package httpserver
+
+import (
+	"strings"
+	"testing"
+
+	"frame_control_system/internal/models"
+)
+
+func TestValidateChecklistTemplate(t *testing.T) {
+	tpl := &models.ChecklistTemplate{
+		Name:  "  Reinforcement  ",
+		Items: []models.ChecklistTemplateItem{{Text: " spacing checked ", Mandatory: true}, {Text: "photos taken"}},
+	}
+	if err := validateChecklistTemplate(tpl); err != nil {
+		t.Fatal(err)
+	}
+	if tpl.Name != "Reinforcement" || tpl.Items[0].Text != "spacing checked" {
+		t.Fatalf("values must be trimmed: %+v", tpl)
+	}
+
+	cases := []models.ChecklistTemplate{
+		{Name: "", Items: []models.ChecklistTemplateItem{{Text: "a"}}},
+		{Name: "No items"},
+		{Name: "Blank item", Items: []models.ChecklistTemplateItem{{Text: "  "}}},
+		{Name: "Long item", Items: []models.ChecklistTemplateItem{{Text: strings.Repeat("x", maxChecklistItemLength+1)}}},
+		{Name: "Too many", Items: make([]models.ChecklistTemplateItem, maxChecklistItems+1)},
+	}
+	for _, c := range cases {
+		c := c
+		if err := validateChecklistTemplate(&c); err == nil {
+			t.Fatalf("expected error for %q", c.Name)
+		}
+	}
+}
+
+func TestCheckChecklistRemovable(t *testing.T) {
+	owner := &AuthContext{UserID: "u1", Roles: []string{"user"}}
+	admin := &AuthContext{UserID: "a1", Roles: []string{"admin"}}
+	open := &models.Checklist{MandatoryOpen: 2}
+	if apiErr := checkChecklistRemovable(open, owner); apiErr == nil || apiErr.Code != "checklist_incomplete" {
+		t.Fatalf("open mandatory items: got %v", apiErr)
+	}
+	if apiErr := checkChecklistRemovable(open, admin); apiErr != nil {
+		t.Fatalf("admin: got %v", apiErr)
+	}
+	if apiErr := checkChecklistRemovable(&models.Checklist{}, owner); apiErr != nil {
+		t.Fatalf("completed checklist: got %v", apiErr)
+	}
+}
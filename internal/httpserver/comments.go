@@ -0,0 +1,336 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	maxCommentLength = 5000
+	maxMentions      = 20
+)
+
+type commentRequest struct {
+	Body string `json:"body"`
+}
+
+// mentionRe matches @user@example.com; the leading @ must not follow a word
+// character so that plain e-mail addresses in the text are not mentions.
+var mentionRe = regexp.MustCompile(`(?:^|[^\w.@])@([\w.%+-]+@[\w-]+(?:\.[\w-]+)*\.[A-Za-z]{2,})`)
+
+// extractMentions returns the distinct e-mails mentioned in body, in order of
+// first appearance and compared case-insensitively.
+func extractMentions(body string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, m := range mentionRe.FindAllStringSubmatch(body, -1) {
+		key := strings.ToLower(m[1])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, m[1])
+		if len(out) == maxMentions {
+			break
+		}
+	}
+	return out
+}
+
+// resolveMentions maps mentioned e-mails to user ids, skipping unknown users
+// and those who cannot see o, so a mention never notifies about an order the
+// user has no access to.
+func resolveMentions(ctx context.Context, db *sql.DB, o *models.Order, body string) ([]string, error) {
+	users := storage.NewUserRepository(db)
+	ids := []string{}
+	for _, email := range extractMentions(body) {
+		u, err := users.GetByEmail(ctx, email)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !orderVisibleTo(ctx, db, o, &AuthContext{UserID: u.ID, Roles: u.Roles}) {
+			continue
+		}
+		ids = append(ids, u.ID)
+	}
+	return ids, nil
+}
+
+func validCommentBody(body string) (string, *apiError) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", &apiError{Code: "invalid_input", Message: "body required"}
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return "", &apiError{Code: "invalid_input", Message: "body is too long"}
+	}
+	return body, nil
+}
+
+// addMentionEvents notifies users newly mentioned in a comment, except its author.
+func addMentionEvents(ctx context.Context, q storage.DBTX, c *models.Comment, previous []string) error {
+	already := map[string]bool{c.AuthorID: true}
+	for _, id := range previous {
+		already[id] = true
+	}
+	for _, id := range c.Mentions {
+		if already[id] {
+			continue
+		}
+		if err := storage.AddOutboxEvent(ctx, q, events.OrderMention, map[string]any{
+			"order_id":   c.OrderID,
+			"comment_id": c.ID,
+			"author_id":  c.AuthorID,
+			"user_id":    id,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ListCommentsHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewCommentRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		pg, err := parsePageRequest(r.URL.Query())
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid cursor"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		list, err := repo.ListByOrder(ctx, o.ID, pg.Limit+1, pg.After)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		var next string
+		if len(list) > pg.Limit {
+			list = list[:pg.Limit]
+			last := list[len(list)-1]
+			next = storage.EncodeCursor(storage.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		}
+		// Comments are paged by cursor only, oldest first.
+		data := map[string]any{"limit": pg.Limit, "has_more": next != "", "items": list}
+		if next != "" {
+			data["next_cursor"] = next
+		}
+		setNextLink(w, r, next)
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: data})
+	}
+}
+
+func CreateCommentHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req commentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		body, apiErr := validCommentBody(req.Body)
+		if apiErr != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: apiErr})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadWritableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		mentions, err := resolveMentions(ctx, db, o, body)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		c := &models.Comment{OrderID: o.ID, AuthorID: ac.UserID, Body: body, Mentions: mentions}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		if err := storage.NewCommentRepository(tx).Create(ctx, c); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		err = storage.AddOutboxEvent(ctx, tx, events.OrderCommentAdded, map[string]any{
+			"order_id":   o.ID,
+			"comment_id": c.ID,
+			"author_id":  c.AuthorID,
+			"mentions":   c.Mentions,
+		})
+		if err == nil {
+			err = addMentionEvents(ctx, tx, c, nil)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: c})
+	}
+}
+
+// UpdateCommentHandler lets the author edit a comment within editWindow of
+// posting it (editWindow <= 0 means no limit).
+func UpdateCommentHandler(db *sql.DB, editWindow time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req commentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		body, apiErr := validCommentBody(req.Body)
+		if apiErr != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: apiErr})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadWritableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		c, err := storage.NewCommentRepository(db).GetByID(ctx, o.ID, chi.URLParam(r, "commentID"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "comment not found"}})
+			return
+		}
+		if c.AuthorID != ac.UserID {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "only the author can edit a comment"}})
+			return
+		}
+		if editWindow > 0 && time.Since(c.CreatedAt) > editWindow {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "edit_window_expired", Message: "comment can no longer be edited"}})
+			return
+		}
+		if body == c.Body {
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: c})
+			return
+		}
+		mentions, err := resolveMentions(ctx, db, o, body)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		previous := c.Mentions
+		c.Body, c.Mentions = body, mentions
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		err = storage.NewCommentRepository(tx).Update(ctx, c)
+		if err == nil {
+			err = storage.AddOutboxEvent(ctx, tx, events.OrderCommentUpdated, map[string]any{
+				"order_id":   o.ID,
+				"comment_id": c.ID,
+				"author_id":  c.AuthorID,
+				"mentions":   c.Mentions,
+			})
+		}
+		if err == nil {
+			err = addMentionEvents(ctx, tx, c, previous)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: c})
+	}
+}
+
+func DeleteCommentHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewCommentRepository(tx)
+		c, err := repo.GetByID(ctx, o.ID, chi.URLParam(r, "commentID"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "comment not found"}})
+			return
+		}
+		if c.AuthorID != ac.UserID && !hasRole(ac.Roles, "admin") {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "only the author or an admin can delete a comment"}})
+			return
+		}
+		err = repo.Delete(ctx, c.ID)
+		if err == nil {
+			err = storage.AddOutboxEvent(ctx, tx, events.OrderCommentDeleted, map[string]any{
+				"order_id":   o.ID,
+				"comment_id": c.ID,
+				"author_id":  c.AuthorID,
+				"deleted_by": ac.UserID,
+			})
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"id": c.ID, "status": "deleted"}})
+	}
+}
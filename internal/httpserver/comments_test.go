@@ -0,0 +1,24 @@
+package httpserver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"no mentions here", nil},
+		{"@ivan@example.com please check", []string{"ivan@example.com"}},
+		{"cc @a@x.io, @b.b@x.io and @A@X.IO.", []string{"a@x.io", "b.b@x.io"}},
+		{"write to a@x.io, not a mention", nil},
+		{"(@petr@example.ru)", []string{"petr@example.ru"}},
+	}
+	for _, tt := range tests {
+		if got := extractMentions(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("extractMentions(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
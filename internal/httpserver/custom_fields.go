@@ -0,0 +1,288 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	maxTags             = 20
+	maxTagLength        = 50
+	maxCustomTextLength = 500
+)
+
+var customFieldKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+type createCustomFieldRequest struct {
+	Key     string                 `json:"key"`
+	Label   string                 `json:"label"`
+	Type    models.CustomFieldType `json:"type"`
+	Options []string               `json:"options"`
+}
+
+type updateCustomFieldRequest struct {
+	Label   *string   `json:"label"`
+	Options *[]string `json:"options"`
+}
+
+// normalizeTags trims and lower-cases tags and drops empty and repeated ones.
+func normalizeTags(tags []string) ([]string, error) {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		if utf8.RuneCountInString(t) > maxTagLength || strings.Contains(t, ",") {
+			return nil, fmt.Errorf("tag %q is invalid: at most %d characters, no commas", t, maxTagLength)
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	if len(out) > maxTags {
+		return nil, fmt.Errorf("at most %d tags", maxTags)
+	}
+	return out, nil
+}
+
+// normalizeFieldValue checks a custom field value against its definition and
+// returns it in stored form.
+func normalizeFieldValue(d models.CustomFieldDefinition, v any) (any, error) {
+	switch d.Type {
+	case models.CustomFieldNumber:
+		f, ok := v.(float64)
+		if !ok || math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("custom field %q must be a number", d.Key)
+		}
+		return f, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("custom field %q must be a string", d.Key)
+	}
+	s = strings.TrimSpace(s)
+	switch d.Type {
+	case models.CustomFieldText:
+		if s == "" || utf8.RuneCountInString(s) > maxCustomTextLength {
+			return nil, fmt.Errorf("custom field %q must be 1-%d characters", d.Key, maxCustomTextLength)
+		}
+	case models.CustomFieldDate:
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return nil, fmt.Errorf("custom field %q must be a date YYYY-MM-DD", d.Key)
+		}
+	case models.CustomFieldEnum:
+		found := false
+		for _, o := range d.Options {
+			if o == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("custom field %q must be one of %s", d.Key, strings.Join(d.Options, ", "))
+		}
+	}
+	return s, nil
+}
+
+// mergeCustomFields applies patch to current and returns the result. A null
+// value removes the field; values already stored are not re-validated, so
+// narrowing an enum does not break existing orders.
+func mergeCustomFields(defs map[string]models.CustomFieldDefinition, current, patch map[string]any) (map[string]any, error) {
+	out := make(map[string]any, len(current)+len(patch))
+	for k, v := range current {
+		out[k] = v
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		d, ok := defs[k]
+		if !ok {
+			return nil, fmt.Errorf("unknown custom field %q", k)
+		}
+		nv, err := normalizeFieldValue(d, v)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = nv
+	}
+	return out, nil
+}
+
+func loadFieldDefs(ctx context.Context, q storage.DBTX) (map[string]models.CustomFieldDefinition, error) {
+	list, err := storage.NewCustomFieldRepository(q).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defs := make(map[string]models.CustomFieldDefinition, len(list))
+	for _, d := range list {
+		defs[d.Key] = d
+	}
+	return defs, nil
+}
+
+func validateFieldOptions(t models.CustomFieldType, options []string) ([]string, error) {
+	if t != models.CustomFieldEnum {
+		if len(options) > 0 {
+			return nil, errors.New("options are allowed only for enum fields")
+		}
+		return nil, nil
+	}
+	out := []string{}
+	seen := map[string]bool{}
+	for _, o := range options {
+		o = strings.TrimSpace(o)
+		if o == "" || seen[o] {
+			continue
+		}
+		seen[o] = true
+		out = append(out, o)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("enum fields need at least one option")
+	}
+	return out, nil
+}
+
+// ListCustomFieldsHandler returns the field definitions; every signed-in user
+// needs them to fill in orders.
+func ListCustomFieldsHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewCustomFieldRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		defs, err := repo.List(ctx)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: defs})
+	}
+}
+
+func CreateCustomFieldHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewCustomFieldRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req createCustomFieldRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		d := &models.CustomFieldDefinition{
+			Key:   strings.TrimSpace(req.Key),
+			Label: strings.TrimSpace(req.Label),
+			Type:  req.Type,
+		}
+		if !customFieldKeyRe.MatchString(d.Key) {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "key must start with a letter and contain only a-z, 0-9 and _ (max 40)"}})
+			return
+		}
+		if d.Label == "" {
+			d.Label = d.Key
+		}
+		if !d.Type.Valid() {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "type must be one of text, number, date, enum"}})
+			return
+		}
+		options, err := validateFieldOptions(d.Type, req.Options)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		d.Options = options
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		if _, err := repo.Get(ctx, d.Key); err == nil {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "custom_field_exists", Message: "custom field already exists"}})
+			return
+		}
+		if err := repo.Create(ctx, d); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: d})
+	}
+}
+
+func UpdateCustomFieldHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewCustomFieldRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req updateCustomFieldRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		d, err := repo.Get(ctx, chi.URLParam(r, "key"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "custom field not found"}})
+			return
+		}
+		if req.Label != nil {
+			if d.Label = strings.TrimSpace(*req.Label); d.Label == "" {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "label must not be empty"}})
+				return
+			}
+		}
+		if req.Options != nil {
+			options, err := validateFieldOptions(d.Type, *req.Options)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+				return
+			}
+			d.Options = options
+		}
+		if err := repo.Update(ctx, d); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: d})
+	}
+}
+
+// DeleteCustomFieldHandler removes a definition that no order uses; values
+// have to be cleared from orders first.
+func DeleteCustomFieldHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewCustomFieldRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		key := chi.URLParam(r, "key")
+		if _, err := repo.Get(ctx, key); err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "custom field not found"}})
+			return
+		}
+		inUse, err := repo.InUse(ctx, key)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if inUse {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "custom_field_in_use", Message: "custom field has values in orders"}})
+			return
+		}
+		if err := repo.Delete(ctx, key); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"key": key, "status": "deleted"}})
+	}
+}
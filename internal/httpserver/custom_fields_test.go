@@ -0,0 +1,65 @@
+package httpserver
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"frame_control_system/internal/models"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := normalizeTags([]string{" Floor-2 ", "floor-2", "", "HVAC"})
+	if err != nil || !reflect.DeepEqual(got, []string{"floor-2", "hvac"}) {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	if _, err := normalizeTags([]string{"a,b"}); err == nil {
+		t.Fatal("expected error for a tag with a comma")
+	}
+}
+
+func TestMergeCustomFields(t *testing.T) {
+	defs := map[string]models.CustomFieldDefinition{
+		"floor":    {Key: "floor", Type: models.CustomFieldNumber},
+		"section":  {Key: "section", Type: models.CustomFieldEnum, Options: []string{"A", "B"}},
+		"handover": {Key: "handover", Type: models.CustomFieldDate},
+	}
+	current := map[string]any{"floor": 3.0, "section": "A"}
+	got, err := mergeCustomFields(defs, current, map[string]any{"section": nil, "handover": "2025-03-01"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{"floor": 3.0, "handover": "2025-03-01"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if current["section"] != "A" {
+		t.Fatal("current fields must not be modified")
+	}
+	bad := []map[string]any{
+		{"floor": "3"},
+		{"section": "C"},
+		{"handover": "01.03.2025"},
+		{"unknown": "x"},
+	}
+	for _, patch := range bad {
+		if _, err := mergeCustomFields(defs, nil, patch); err == nil {
+			t.Fatalf("expected error for %v", patch)
+		}
+	}
+}
+
+func TestParseOrderFiltersTagsAndFields(t *testing.T) {
+	ac := &AuthContext{UserID: "u1", Roles: []string{"user"}}
+	q := url.Values{"tag": {"North, HVAC"}, "cf.floor": {"2"}}
+	p, err := parseOrderFilters(q, ac)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(p.Tags, []string{"north", "hvac"}) || p.CustomFields["floor"] != "2" {
+		t.Fatalf("unexpected params: %+v", p)
+	}
+	if _, err := parseOrderFilters(url.Values{"cf.Bad-Key": {"1"}}, ac); err == nil {
+		t.Fatal("expected error for an invalid custom field key")
+	}
+}
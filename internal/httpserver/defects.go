@@ -0,0 +1,808 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/blobstore"
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	maxDefectTitleLength       = 200
+	maxDefectDescriptionLength = 5000
+	maxDefectLocationLength    = 500
+)
+
+type createDefectRequest struct {
+	ProjectID     string                `json:"project_id"`
+	Title         string                `json:"title"`
+	Description   string                `json:"description"`
+	Severity      models.DefectSeverity `json:"severity"`
+	Location      string                `json:"location"`
+	ResponsibleID string                `json:"responsible_id"`
+	DueAt         *time.Time            `json:"due_at"`
+}
+
+// updateDefectRequest changes an open defect; omitted fields are kept, an
+// empty responsible_id or a null due_at clears the value.
+type updateDefectRequest struct {
+	Title         *string                `json:"title"`
+	Description   *string                `json:"description"`
+	Severity      *models.DefectSeverity `json:"severity"`
+	Location      *string                `json:"location"`
+	ResponsibleID *string                `json:"responsible_id"`
+	DueAt         optional[time.Time]    `json:"due_at"`
+}
+
+type defectStatusRequest struct {
+	Status  models.DefectStatus `json:"status"`
+	Comment string              `json:"comment"`
+}
+
+func validateDefect(d *models.Defect) error {
+	d.Title = strings.TrimSpace(d.Title)
+	d.Description = strings.TrimSpace(d.Description)
+	d.Location = strings.TrimSpace(d.Location)
+	d.ResponsibleID = strings.TrimSpace(d.ResponsibleID)
+	if d.Title == "" || utf8.RuneCountInString(d.Title) > maxDefectTitleLength {
+		return fmt.Errorf("title must be 1-%d characters", maxDefectTitleLength)
+	}
+	if utf8.RuneCountInString(d.Description) > maxDefectDescriptionLength {
+		return fmt.Errorf("description must be at most %d characters", maxDefectDescriptionLength)
+	}
+	if utf8.RuneCountInString(d.Location) > maxDefectLocationLength {
+		return fmt.Errorf("location must be at most %d characters", maxDefectLocationLength)
+	}
+	if !d.Severity.Valid() {
+		return errors.New("severity must be one of low, medium, high, critical")
+	}
+	return nil
+}
+
+// validateDefectTransition follows the order lifecycle: work moves forward
+// through review, a reviewer may send it back, closed and rejected are final.
+func validateDefectTransition(from, to models.DefectStatus) error {
+	switch from {
+	case models.DefectStatusNew:
+		if to == models.DefectStatusInWork || to == models.DefectStatusRejected {
+			return nil
+		}
+	case models.DefectStatusInWork:
+		if to == models.DefectStatusOnReview || to == models.DefectStatusRejected {
+			return nil
+		}
+	case models.DefectStatusOnReview:
+		if to == models.DefectStatusClosed || to == models.DefectStatusInWork {
+			return nil
+		}
+	case models.DefectStatusClosed, models.DefectStatusRejected:
+		// terminal
+	}
+	return fmt.Errorf("cannot transition from %s to %s", from, to)
+}
+
+// canRegisterDefects: admins, managers and the project's managers and engineers.
+func canRegisterDefects(ac *AuthContext, m *models.ProjectMember) bool {
+	return canManageProject(ac, m) || (m != nil && m.Role == models.ProjectRoleEngineer)
+}
+
+// canEditDefect: the reporter and whoever manages the project.
+func canEditDefect(ac *AuthContext, m *models.ProjectMember, d *models.Defect) bool {
+	return d.ReportedBy == ac.UserID || canManageProject(ac, m)
+}
+
+// canChangeDefectStatus lets the responsible person take the defect into work
+// and hand it over for review; accepting, returning and rejecting are left to
+// the reporter and project managers.
+func canChangeDefectStatus(ac *AuthContext, m *models.ProjectMember, d *models.Defect, to models.DefectStatus) bool {
+	if canEditDefect(ac, m, d) {
+		return true
+	}
+	if d.ResponsibleID != ac.UserID {
+		return false
+	}
+	return (d.Status == models.DefectStatusNew && to == models.DefectStatusInWork) || to == models.DefectStatusOnReview
+}
+
+// loadViewableDefect fetches a defect of a project the caller may see together
+// with the caller's project membership (nil for non-members).
+func loadViewableDefect(ctx context.Context, q storage.DBTX, ac *AuthContext, id string) (*models.Defect, *models.ProjectMember, int, *apiError) {
+	d, err := storage.NewDefectRepository(q).GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, http.StatusNotFound, &apiError{Code: "not_found", Message: "defect not found"}
+	}
+	_, m, status, apiErr := loadViewableProject(ctx, q, ac, d.ProjectID)
+	if apiErr != nil {
+		if status == http.StatusForbidden {
+			// Do not reveal defects of foreign projects.
+			return nil, nil, http.StatusNotFound, &apiError{Code: "not_found", Message: "defect not found"}
+		}
+		return nil, nil, status, apiErr
+	}
+	return d, m, 0, nil
+}
+
+// checkDefectResponsible requires the responsible person to be a project member.
+func checkDefectResponsible(ctx context.Context, q storage.DBTX, projectID, userID string) *apiError {
+	if userID == "" {
+		return nil
+	}
+	if !isProjectMember(ctx, q, projectID, userID) {
+		return &apiError{Code: "invalid_input", Message: "responsible must be a member of the project"}
+	}
+	return nil
+}
+
+// attachDefectPhotos fills in the photos and their download URLs.
+func attachDefectPhotos(ctx context.Context, repo *storage.DefectRepository, list []models.Defect) error {
+	ids := make([]string, len(list))
+	for i, d := range list {
+		ids[i] = d.ID
+	}
+	photos, err := repo.Photos(ctx, ids...)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		d := &list[i]
+		d.Photos = photos[d.ID]
+		if d.Photos == nil {
+			d.Photos = []models.DefectPhoto{}
+		}
+		for j := range d.Photos {
+			d.Photos[j].URL = fmt.Sprintf("/api/v1/defects/%s/photos/%s", d.ID, d.Photos[j].ID)
+		}
+	}
+	return nil
+}
+
+// parseDefectFilters reads and validates the defect list filters from the
+// query string. Pagination fields are left for the caller to fill in.
+func parseDefectFilters(q url.Values, ac *AuthContext) (storage.ListDefectsParams, error) {
+	p := storage.ListDefectsParams{
+		UserID:    ac.UserID,
+		AdminView: hasAnyRole(ac.Roles, "admin", "manager"),
+		ProjectID: strings.TrimSpace(q.Get("project_id")),
+	}
+	for _, raw := range q["status"] {
+		for _, st := range strings.Split(raw, ",") {
+			st = strings.TrimSpace(st)
+			if st == "" {
+				continue
+			}
+			if !models.DefectStatus(st).Valid() {
+				return p, fmt.Errorf("unknown status %q", st)
+			}
+			p.Statuses = append(p.Statuses, st)
+		}
+	}
+	for _, raw := range q["severity"] {
+		for _, s := range strings.Split(raw, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			if !models.DefectSeverity(s).Valid() {
+				return p, fmt.Errorf("unknown severity %q", s)
+			}
+			p.Severities = append(p.Severities, s)
+		}
+	}
+	p.ResponsibleID = strings.TrimSpace(q.Get("responsible"))
+	if p.ResponsibleID == "me" {
+		p.ResponsibleID = ac.UserID
+	}
+	p.ReportedBy = strings.TrimSpace(q.Get("reported_by"))
+	if p.ReportedBy == "me" {
+		p.ReportedBy = ac.UserID
+	}
+	var err error
+	if p.Overdue, err = parseBoolParam(q, "overdue"); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+func ListDefectsHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewDefectRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		q := r.URL.Query()
+		pg, err := parsePageRequest(q)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid cursor"}})
+			return
+		}
+		params, err := parseDefectFilters(q, ac)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		params.Limit = pg.Limit + 1 // one extra row tells whether there is a next page
+		params.Offset = pg.Offset
+		params.After = pg.After
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		list, err := repo.List(ctx, params)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		var next string
+		if len(list) > pg.Limit {
+			list = list[:pg.Limit]
+			last := list[len(list)-1]
+			next = storage.EncodeCursor(storage.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		}
+		if err := attachDefectPhotos(ctx, repo, list); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		var total *int
+		if pg.IncludeTotal {
+			n, err := repo.Count(ctx, params)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			total = &n
+		}
+		data := pg.meta(next, next != "", total)
+		data["items"] = list
+		setNextLink(w, r, next)
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: data})
+	}
+}
+
+// CreateDefectHandler registers a defect in a project; allowed to project
+// engineers and managers.
+func CreateDefectHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req createDefectRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		if strings.TrimSpace(req.ProjectID) == "" {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "project_id required"}})
+			return
+		}
+		d := &models.Defect{
+			ProjectID:     strings.TrimSpace(req.ProjectID),
+			Title:         req.Title,
+			Description:   req.Description,
+			Severity:      req.Severity,
+			Location:      req.Location,
+			ResponsibleID: req.ResponsibleID,
+			Status:        models.DefectStatusNew,
+			ReportedBy:    ac.UserID,
+		}
+		if d.Severity == "" {
+			d.Severity = models.DefectSeverityMedium
+		}
+		if err := validateDefect(d); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		if req.DueAt != nil {
+			if !req.DueAt.After(time.Now()) {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "due_at must be in the future"}})
+				return
+			}
+			due := req.DueAt.UTC().Truncate(time.Second)
+			d.DueAt = &due
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		p, m, status, apiErr := loadViewableProject(ctx, db, ac, d.ProjectID)
+		if apiErr != nil {
+			if status == http.StatusNotFound {
+				status, apiErr = http.StatusBadRequest, &apiError{Code: "invalid_input", Message: "project not found"}
+			}
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canRegisterDefects(ac, m) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "only project engineers and managers can register defects"}})
+			return
+		}
+		if p.Status == models.ProjectStatusCompleted || p.Status == models.ProjectStatusCancelled {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "project_closed", Message: fmt.Sprintf("project in status %s does not accept defects", p.Status)}})
+			return
+		}
+		if apiErr := checkDefectResponsible(ctx, db, p.ID, d.ResponsibleID); apiErr != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: apiErr})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		if err := storage.NewDefectRepository(tx).Create(ctx, d); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.DefectCreated, map[string]any{
+			"id":             d.ID,
+			"project_id":     d.ProjectID,
+			"title":          d.Title,
+			"severity":       d.Severity,
+			"responsible_id": d.ResponsibleID,
+			"due_at":         d.DueAt,
+			"reported_by":    ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		d.Photos = []models.DefectPhoto{}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: d})
+	}
+}
+
+func GetDefectHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewDefectRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		d, _, status, apiErr := loadViewableDefect(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		list := []models.Defect{*d}
+		if err := attachDefectPhotos(ctx, repo, list); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: list[0]})
+	}
+}
+
+// UpdateDefectHandler edits an open defect; allowed to the reporter and
+// project managers.
+func UpdateDefectHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req updateDefectRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		d, m, status, apiErr := loadViewableDefect(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canEditDefect(ac, m, d) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		if !d.Open() {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "defect_not_editable", Message: fmt.Sprintf("defect in status %s cannot be edited", d.Status)}})
+			return
+		}
+		before := *d
+		if req.Title != nil {
+			d.Title = *req.Title
+		}
+		if req.Description != nil {
+			d.Description = *req.Description
+		}
+		if req.Severity != nil {
+			d.Severity = *req.Severity
+		}
+		if req.Location != nil {
+			d.Location = *req.Location
+		}
+		if req.ResponsibleID != nil {
+			d.ResponsibleID = *req.ResponsibleID
+		}
+		if req.DueAt.Set {
+			d.DueAt = nil
+			if req.DueAt.Value != nil {
+				if !req.DueAt.Value.After(d.CreatedAt) {
+					writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "due_at must be after the defect creation time"}})
+					return
+				}
+				due := req.DueAt.Value.UTC().Truncate(time.Second)
+				d.DueAt = &due
+			}
+		}
+		if err := validateDefect(d); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		if d.ResponsibleID != before.ResponsibleID {
+			if apiErr := checkDefectResponsible(ctx, db, d.ProjectID, d.ResponsibleID); apiErr != nil {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: apiErr})
+				return
+			}
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewDefectRepository(tx)
+		if err := repo.Update(ctx, d); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.DefectUpdated, map[string]any{
+			"id":         d.ID,
+			"project_id": d.ProjectID,
+			"changed_by": ac.UserID,
+			"before":     before,
+			"after":      d,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		list := []models.Defect{*d}
+		_ = attachDefectPhotos(ctx, storage.NewDefectRepository(db), list)
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: list[0]})
+	}
+}
+
+// UpdateDefectStatusHandler moves a defect through its lifecycle. An optional
+// comment travels with the defect.status_changed event.
+func UpdateDefectStatusHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req defectStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		to := models.DefectStatus(strings.TrimSpace(string(req.Status)))
+		if !to.Valid() || to == models.DefectStatusNew {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "unsupported status"}})
+			return
+		}
+		comment := strings.TrimSpace(req.Comment)
+		if utf8.RuneCountInString(comment) > maxDefectDescriptionLength {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: fmt.Sprintf("comment must be at most %d characters", maxDefectDescriptionLength)}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		d, m, status, apiErr := loadViewableDefect(ctx, tx, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canChangeDefectStatus(ac, m, d, to) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		if err := validateDefectTransition(d.Status, to); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_transition", Message: err.Error()}})
+			return
+		}
+		from := d.Status
+		d.Status = to
+		if err := storage.NewDefectRepository(tx).Update(ctx, d); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.DefectStatusChanged, map[string]any{
+			"id":         d.ID,
+			"project_id": d.ProjectID,
+			"from":       from,
+			"to":         to,
+			"comment":    comment,
+			"changed_by": ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		list := []models.Defect{*d}
+		_ = attachDefectPhotos(ctx, storage.NewDefectRepository(db), list)
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: list[0]})
+	}
+}
+
+// DeleteDefectHandler removes a defect registered by mistake: the reporter
+// while it is new, project managers at any time.
+func DeleteDefectHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		d, m, status, apiErr := loadViewableDefect(ctx, tx, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canManageProject(ac, m) && (d.ReportedBy != ac.UserID || d.Status != models.DefectStatusNew) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		repo := storage.NewDefectRepository(tx)
+		err = repo.Delete(ctx, d.ID) // photos go with it (ON DELETE CASCADE)
+		if err == nil {
+			err = storage.AddOutboxEvent(ctx, tx, events.DefectDeleted, map[string]any{
+				"id":         d.ID,
+				"project_id": d.ProjectID,
+				"deleted_by": ac.UserID,
+			})
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"id": d.ID, "status": "deleted"}})
+	}
+}
+
+// UploadDefectPhotoHandler accepts a multipart/form-data body with a "file"
+// part. Only image types from allowed are accepted.
+func UploadDefectPhotoHandler(db *sql.DB, blobs blobstore.Store, maxBytes int64, allowed []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		d, _, status, apiErr := loadViewableDefect(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+
+		// Leave room for multipart headers around the file itself.
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes+64<<10)
+		mr, err := r.MultipartReader()
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "multipart/form-data body with a file field required"}})
+			return
+		}
+		var part io.Reader
+		var filename string
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			if p.FormName() == "file" {
+				part, filename = p, sanitizeFilename(p.FileName())
+				break
+			}
+		}
+		if part == nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "file field required"}})
+			return
+		}
+		head := make([]byte, 512)
+		n, err := io.ReadFull(part, head)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "empty file"}})
+			return
+		}
+		head = head[:n]
+		contentType := detectContentType(head)
+		if !strings.HasPrefix(contentType, "image/") || !allowedContentType(contentType, allowed) {
+			writeJSON(w, http.StatusUnsupportedMediaType, envelope{Success: false, Error: &apiError{Code: "unsupported_media_type", Message: "file type " + contentType + " is not an allowed image type"}})
+			return
+		}
+
+		key, size, err := blobs.Put(r.Context(), &limitedReader{r: io.MultiReader(bytes.NewReader(head), part), n: maxBytes})
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.Is(err, errFileTooLarge) || errors.As(err, &mbe) {
+				writeJSON(w, http.StatusRequestEntityTooLarge, envelope{Success: false, Error: &apiError{Code: "file_too_large", Message: "file exceeds " + strconv.FormatInt(maxBytes, 10) + " bytes"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "storage error"}})
+			return
+		}
+
+		ph := &models.DefectPhoto{
+			DefectID:    d.ID,
+			BlobKey:     key,
+			Filename:    filename,
+			ContentType: contentType,
+			Size:        size,
+			UploadedBy:  ac.UserID,
+		}
+		if err := saveDefectPhoto(ctx, db, d, ph); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		ph.URL = fmt.Sprintf("/api/v1/defects/%s/photos/%s", d.ID, ph.ID)
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: ph})
+	}
+}
+
+func saveDefectPhoto(ctx context.Context, db *sql.DB, d *models.Defect, ph *models.DefectPhoto) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := storage.NewDefectRepository(tx).AddPhoto(ctx, ph); err != nil {
+		return err
+	}
+	if err := storage.AddOutboxEvent(ctx, tx, events.DefectPhotoAdded, map[string]any{
+		"defect_id":    d.ID,
+		"project_id":   d.ProjectID,
+		"photo_id":     ph.ID,
+		"filename":     ph.Filename,
+		"content_type": ph.ContentType,
+		"size":         ph.Size,
+		"uploaded_by":  ph.UploadedBy,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DownloadDefectPhotoHandler streams the photo; inline=true asks browsers to
+// display it instead of saving.
+func DownloadDefectPhotoHandler(db *sql.DB, blobs blobstore.Store) http.HandlerFunc {
+	repo := storage.NewDefectRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		d, _, status, apiErr := loadViewableDefect(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		ph, err := repo.GetPhoto(ctx, d.ID, chi.URLParam(r, "photoID"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "photo not found"}})
+			return
+		}
+		rc, err := blobs.Open(r.Context(), ph.BlobKey)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "photo content missing"}})
+			return
+		}
+		defer rc.Close()
+		disposition := "attachment"
+		if r.URL.Query().Get("inline") == "true" {
+			disposition = "inline"
+		}
+		w.Header().Set("Content-Type", ph.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(ph.Size, 10))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": ph.Filename}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.Copy(w, rc)
+	}
+}
+
+// DeleteDefectPhotoHandler removes a photo; allowed to the uploader, the
+// reporter and project managers.
+func DeleteDefectPhotoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		d, m, status, apiErr := loadViewableDefect(ctx, tx, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		repo := storage.NewDefectRepository(tx)
+		ph, err := repo.GetPhoto(ctx, d.ID, chi.URLParam(r, "photoID"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "photo not found"}})
+			return
+		}
+		if ph.UploadedBy != ac.UserID && !canEditDefect(ac, m, d) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		err = repo.DeletePhoto(ctx, ph.ID)
+		if err == nil {
+			err = storage.AddOutboxEvent(ctx, tx, events.DefectPhotoDeleted, map[string]any{
+				"defect_id":  d.ID,
+				"project_id": d.ProjectID,
+				"photo_id":   ph.ID,
+				"deleted_by": ac.UserID,
+			})
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"id": ph.ID, "status": "deleted"}})
+	}
+}
@@ -0,0 +1,93 @@
+package httpserver
+
+import (
+	"net/url"
+	"testing"
+
+	"frame_control_system/internal/models"
+)
+
+func TestValidateDefectTransition(t *testing.T) {
+	ok := [][2]models.DefectStatus{
+		{models.DefectStatusNew, models.DefectStatusInWork},
+		{models.DefectStatusNew, models.DefectStatusRejected},
+		{models.DefectStatusInWork, models.DefectStatusOnReview},
+		{models.DefectStatusOnReview, models.DefectStatusClosed},
+		{models.DefectStatusOnReview, models.DefectStatusInWork},
+	}
+	for _, c := range ok {
+		if err := validateDefectTransition(c[0], c[1]); err != nil {
+			t.Fatalf("%s -> %s: unexpected error %v", c[0], c[1], err)
+		}
+	}
+	bad := [][2]models.DefectStatus{
+		{models.DefectStatusNew, models.DefectStatusClosed},
+		{models.DefectStatusInWork, models.DefectStatusClosed},
+		{models.DefectStatusClosed, models.DefectStatusInWork},
+		{models.DefectStatusRejected, models.DefectStatusNew},
+	}
+	for _, c := range bad {
+		if err := validateDefectTransition(c[0], c[1]); err == nil {
+			t.Fatalf("%s -> %s: expected error", c[0], c[1])
+		}
+	}
+}
+
+func TestCanChangeDefectStatus(t *testing.T) {
+	d := &models.Defect{ReportedBy: "eng", ResponsibleID: "exec", Status: models.DefectStatusNew}
+	exec := &AuthContext{UserID: "exec", Roles: []string{"user"}}
+	if !canChangeDefectStatus(exec, nil, d, models.DefectStatusInWork) {
+		t.Fatal("the responsible person takes the defect into work")
+	}
+	if canChangeDefectStatus(exec, nil, d, models.DefectStatusRejected) {
+		t.Fatal("the responsible person cannot reject the defect")
+	}
+	d.Status = models.DefectStatusOnReview
+	if canChangeDefectStatus(exec, nil, d, models.DefectStatusClosed) {
+		t.Fatal("the responsible person cannot accept their own work")
+	}
+	if !canChangeDefectStatus(&AuthContext{UserID: "eng"}, nil, d, models.DefectStatusClosed) {
+		t.Fatal("the reporter accepts the work")
+	}
+	if canChangeDefectStatus(&AuthContext{UserID: "other"}, &models.ProjectMember{Role: models.ProjectRoleEngineer}, d, models.DefectStatusClosed) {
+		t.Fatal("other engineers cannot change the status")
+	}
+}
+
+func TestValidateDefect(t *testing.T) {
+	d := &models.Defect{Title: " Crack in slab ", Severity: models.DefectSeverityHigh, Location: " Floor 3, axis B-4 "}
+	if err := validateDefect(d); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Title != "Crack in slab" || d.Location != "Floor 3, axis B-4" {
+		t.Fatalf("fields should be trimmed: %+v", d)
+	}
+	if err := validateDefect(&models.Defect{Title: " ", Severity: models.DefectSeverityLow}); err == nil {
+		t.Fatal("expected error for an empty title")
+	}
+	if err := validateDefect(&models.Defect{Title: "x", Severity: "minor"}); err == nil {
+		t.Fatal("expected error for an unknown severity")
+	}
+}
+
+func TestParseDefectFilters(t *testing.T) {
+	ac := &AuthContext{UserID: "u1"}
+	p, err := parseDefectFilters(url.Values{
+		"status":      {"new,in_work"},
+		"severity":    {"critical"},
+		"responsible": {"me"},
+		"overdue":     {"true"},
+	}, ac)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Statuses) != 2 || len(p.Severities) != 1 || p.ResponsibleID != "u1" || !p.Overdue || p.AdminView {
+		t.Fatalf("unexpected params: %+v", p)
+	}
+	if _, err := parseDefectFilters(url.Values{"status": {"open"}}, ac); err == nil {
+		t.Fatal("expected error for an unknown status")
+	}
+	if _, err := parseDefectFilters(url.Values{"severity": {"minor"}}, ac); err == nil {
+		t.Fatal("expected error for an unknown severity")
+	}
+}
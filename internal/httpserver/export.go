@@ -0,0 +1,262 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	// exportFlushEvery is how many lines are written between flushes to the client.
+	exportFlushEvery = 200
+	// exportTimeout bounds a streaming export; the export route is exempt
+	// from the global request timeout.
+	exportTimeout = 10 * time.Minute
+)
+
+// exportLine is one order item flattened together with its order.
+type exportLine struct {
+	OrderID     string             `json:"order_id"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Status      models.OrderStatus `json:"status"`
+	UserID      string             `json:"user_id"`
+	Notes       string             `json:"notes"`
+	TotalAmount float64            `json:"total_amount"`
+	// Subtotal, Discount and Tax come from the order breakdown.
+	Subtotal float64 `json:"subtotal"`
+	Discount float64 `json:"discount"`
+	Tax      float64 `json:"tax"`
+	ItemName string  `json:"item_name"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price"`
+	// LineTotal is net of the line discount, before order discounts and VAT.
+	LineTotal   float64  `json:"line_total"`
+	LoggedHours float64  `json:"logged_hours"`
+	Tags        []string `json:"tags"`
+	// CustomFields become one CSV column per field definition.
+	CustomFields map[string]any `json:"custom_fields"`
+}
+
+var exportHeaders = map[string][]string{
+	"en": {"Order ID", "Created at", "Updated at", "Status", "User ID", "Notes", "Order total", "Subtotal", "Discount", "VAT", "Item", "Quantity", "Price", "Line total (net)", "Hours logged"},
+	"ru": {"ID заказа", "Создан", "Изменён", "Статус", "ID пользователя", "Примечание", "Сумма заказа", "Сумма без скидок", "Скидка", "НДС", "Позиция", "Количество", "Цена", "Сумма позиции без НДС", "Часы работы"},
+}
+
+var exportTagsHeader = map[string]string{"en": "Tags", "ru": "Теги"}
+
+// csvHeader is the fixed header followed by tags and the custom field labels.
+func csvHeader(lang string, fields []models.CustomFieldDefinition) []string {
+	h := append([]string{}, exportHeaders[lang]...)
+	h = append(h, exportTagsHeader[lang])
+	for _, f := range fields {
+		h = append(h, f.Label)
+	}
+	return h
+}
+
+func flattenOrder(o models.Order) []exportLine {
+	base := exportLine{
+		OrderID:      o.ID,
+		CreatedAt:    o.CreatedAt,
+		UpdatedAt:    o.UpdatedAt,
+		Status:       o.Status,
+		UserID:       o.UserID,
+		Notes:        o.Notes,
+		TotalAmount:  o.TotalAmount,
+		Subtotal:     o.Breakdown.Subtotal,
+		Discount:     o.Breakdown.Discount,
+		Tax:          o.Breakdown.Tax,
+		LoggedHours:  o.LoggedHours,
+		Tags:         o.Tags,
+		CustomFields: o.CustomFields,
+	}
+	if len(o.Items) == 0 {
+		return []exportLine{base}
+	}
+	lines := make([]exportLine, 0, len(o.Items))
+	for _, it := range o.Items {
+		l := base
+		l.ItemName = it.Name
+		l.Quantity = it.Quantity
+		l.Price = it.Price
+		l.LineTotal = storage.LineNet(it)
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// exportLang picks the header language from ?lang= or Accept-Language.
+func exportLang(r *http.Request) string {
+	lang := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("lang")))
+	if lang == "" {
+		lang = strings.ToLower(r.Header.Get("Accept-Language"))
+	}
+	if strings.HasPrefix(lang, "en") {
+		return "en"
+	}
+	return "ru"
+}
+
+// csvRecord renders a line for the given language. Russian spreadsheets expect
+// a decimal comma, so numbers are localized as well.
+func (l exportLine) csvRecord(lang string, fields []models.CustomFieldDefinition) []string {
+	num := func(f float64) string {
+		s := strconv.FormatFloat(f, 'f', -1, 64)
+		if lang == "ru" {
+			s = strings.Replace(s, ".", ",", 1)
+		}
+		return s
+	}
+	qty := ""
+	if l.ItemName != "" {
+		qty = strconv.Itoa(l.Quantity)
+	}
+	rec := []string{
+		l.OrderID,
+		l.CreatedAt.UTC().Format(time.RFC3339),
+		l.UpdatedAt.UTC().Format(time.RFC3339),
+		string(l.Status),
+		l.UserID,
+		l.Notes,
+		num(l.TotalAmount),
+		num(l.Subtotal),
+		num(l.Discount),
+		num(l.Tax),
+		l.ItemName,
+		qty,
+		num(l.Price),
+		num(l.LineTotal),
+		num(l.LoggedHours),
+		strings.Join(l.Tags, ", "),
+	}
+	for _, f := range fields {
+		switch v := l.CustomFields[f.Key].(type) {
+		case float64:
+			rec = append(rec, num(v))
+		case string:
+			rec = append(rec, v)
+		default:
+			rec = append(rec, "")
+		}
+	}
+	return rec
+}
+
+func ExportOrdersHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewOrderRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		q := r.URL.Query()
+		format := strings.TrimSpace(q.Get("format"))
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "ndjson" {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "format must be csv or ndjson"}})
+			return
+		}
+		params, err := parseOrderFilters(q, ac)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		if apiErr := checkListScope(params, ac); apiErr != nil {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: apiErr})
+			return
+		}
+		lang := exportLang(r)
+		fields, err := storage.NewCustomFieldRepository(db).List(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		// The export stops when the client goes away or after exportTimeout; a
+		// cut-off export is logged as failed.
+		ctx, cancel := context.WithTimeout(r.Context(), exportTimeout)
+		defer cancel()
+
+		filename := "orders-" + time.Now().UTC().Format("20060102-150405")
+		flusher, _ := w.(http.Flusher)
+		lines := 0
+		// lineWritten periodically pushes buffered output to the client.
+		lineWritten := func(flushBuffer func()) {
+			lines++
+			if lines%exportFlushEvery != 0 {
+				return
+			}
+			if flushBuffer != nil {
+				flushBuffer()
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
+		var each func(models.Order) error
+		var finish func() error
+		switch format {
+		case "csv":
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+			w.WriteHeader(http.StatusOK)
+			// BOM so that spreadsheet apps detect UTF-8.
+			_, _ = w.Write(utf8BOM)
+			cw := csv.NewWriter(w)
+			if lang == "ru" {
+				cw.Comma = ';'
+			}
+			_ = cw.Write(csvHeader(lang, fields))
+			each = func(o models.Order) error {
+				for _, l := range flattenOrder(o) {
+					if err := cw.Write(l.csvRecord(lang, fields)); err != nil {
+						return err
+					}
+					lineWritten(cw.Flush)
+				}
+				return cw.Error()
+			}
+			finish = func() error {
+				cw.Flush()
+				return cw.Error()
+			}
+		case "ndjson":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.ndjson"`)
+			w.WriteHeader(http.StatusOK)
+			enc := json.NewEncoder(w)
+			each = func(o models.Order) error {
+				for _, l := range flattenOrder(o) {
+					if err := enc.Encode(l); err != nil {
+						return err
+					}
+					lineWritten(nil)
+				}
+				return nil
+			}
+			finish = func() error { return nil }
+		}
+
+		// Headers are already sent, so failures can only be logged.
+		if err := repo.Each(ctx, params, each); err != nil {
+			slog.Error("orders_export_failed", "error", err, "format", format)
+			return
+		}
+		if err := finish(); err != nil {
+			slog.Error("orders_export_failed", "error", err, "format", format)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package httpserver
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+func TestFlattenOrder(t *testing.T) {
+	o := models.Order{
+		ID: "o1",
+		Items: []models.OrderItem{
+			{Name: "rebar", Quantity: 3, Price: 2.5},
+			{Name: "cement", Quantity: 1, Price: 10},
+		},
+		TotalAmount: 17.5,
+		Breakdown:   models.PriceBreakdown{Subtotal: 17.5, Total: 17.5},
+		LoggedHours: 2.25,
+	}
+	lines := flattenOrder(o)
+	if len(lines) != 2 || lines[0].LineTotal != 7.5 || lines[1].ItemName != "cement" {
+		t.Fatalf("unexpected lines: %+v", lines)
+	}
+	rec := lines[0].csvRecord("ru", nil)
+	if rec[6] != "17,5" || rec[7] != "17,5" || rec[12] != "2,5" || rec[13] != "7,5" || rec[14] != "2,25" {
+		t.Fatalf("expected decimal comma for ru, got %v", rec)
+	}
+	if rec := lines[0].csvRecord("en", nil); rec[6] != "17.5" {
+		t.Fatalf("expected decimal point for en, got %v", rec)
+	}
+	if len(csvHeader("ru", nil)) != len(rec) || len(csvHeader("en", nil)) != len(rec) {
+		t.Fatalf("headers do not match record width")
+	}
+	if got := flattenOrder(models.Order{ID: "empty"}); len(got) != 1 {
+		t.Fatalf("order without items must produce one line, got %d", len(got))
+	}
+}
+
+func TestCSVRecordTagsAndCustomFields(t *testing.T) {
+	fields := []models.CustomFieldDefinition{
+		{Key: "floor", Label: "Floor", Type: models.CustomFieldNumber},
+		{Key: "section", Label: "Section", Type: models.CustomFieldEnum},
+		{Key: "cost_center", Label: "Cost center", Type: models.CustomFieldText},
+	}
+	o := models.Order{
+		ID:           "o1",
+		Items:        []models.OrderItem{{Name: "rebar", Quantity: 1, Price: 1}},
+		Tags:         []string{"urgent", "north"},
+		CustomFields: map[string]any{"floor": 2.5, "section": "B"},
+	}
+	rec := flattenOrder(o)[0].csvRecord("ru", fields)
+	want := []string{"urgent, north", "2,5", "B", ""}
+	if got := rec[len(rec)-4:]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if h := csvHeader("en", fields); len(h) != len(rec) || h[len(h)-1] != "Cost center" {
+		t.Fatalf("unexpected header %v", h)
+	}
+}
+
+func TestFlattenOrderDiscountsAndTax(t *testing.T) {
+	items := []models.OrderItem{
+		{Name: "rebar", Quantity: 4, Price: 25, DiscountPercent: 10},
+		{Name: "cement", Quantity: 2, Price: 30},
+	}
+	rates := storage.TaxRates{Default: 20}
+	b, err := storage.PriceOrder(items, 5, 0, nil, rates)
+	if err != nil {
+		t.Fatalf("price: %v", err)
+	}
+	o := models.Order{ID: "o1", Items: items, TotalAmount: b.Total, Breakdown: b}
+	lines := flattenOrder(o)
+	if lines[0].LineTotal != 90 || lines[1].LineTotal != 60 {
+		t.Fatalf("line totals must be net of line discounts: %+v", lines)
+	}
+	// Lines, then order discounts and VAT add up to the order total.
+	sum := lines[0].LineTotal + lines[1].LineTotal
+	if got := sum - (b.Discount - b.LineDiscount) + b.Tax; math.Abs(got-b.Total) > 0.005 {
+		t.Fatalf("lines add up to %v, order total %v", got, b.Total)
+	}
+	rec := lines[0].csvRecord("en", nil)
+	if rec[7] != "160" || rec[8] != "17.5" || rec[9] != "28.5" {
+		t.Fatalf("breakdown columns: %v", rec[6:10])
+	}
+}
@@ -0,0 +1,234 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"frame_control_system/internal/storage"
+)
+
+const (
+	idempotencyHeader   = "Idempotency-Key"
+	idempotencyReplayed = "Idempotent-Replayed"
+	maxIdempotencyKey   = 255
+	// Bodies up to maxIdempotentBody are kept in memory, larger ones (imports,
+	// uploads) are spooled to a temporary file while they are hashed.
+	maxIdempotentBody = 1 << 20
+)
+
+var errIdempotentBodyTooLarge = errors.New("request body too large")
+
+// recordingWriter keeps a copy of the response so it can be replayed later.
+// base holds the headers set before the handler ran (by outer middleware),
+// header the ones in place when the response was written.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	base   http.Header
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+		w.header = w.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+		w.header = w.Header().Clone()
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Idempotency makes mutating requests carrying an Idempotency-Key header safe
+// to retry: the first response is stored for ttl and replayed for retries with
+// the same key and body. Bodies over maxBody are refused with 413. Must be
+// mounted after AuthMiddleware.
+func Idempotency(db *sql.DB, ttl time.Duration, maxBody int64) func(next http.Handler) http.Handler {
+	repo := storage.NewIdempotencyRepository(db)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimSpace(r.Header.Get(idempotencyHeader))
+			if key == "" || !isMutatingMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ac := GetAuth(r)
+			if ac == nil {
+				writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+				return
+			}
+			if len(key) > maxIdempotencyKey {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "idempotency key too long"}})
+				return
+			}
+			h := fingerprintHash(r.Method, r.URL.Path)
+			body, cleanup, err := spoolBody(r.Body, h, maxBody)
+			if err != nil {
+				if errors.Is(err, errIdempotentBodyTooLarge) {
+					writeJSON(w, http.StatusRequestEntityTooLarge, envelope{Success: false, Error: &apiError{Code: "body_too_large", Message: "request body too large"}})
+					return
+				}
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "cannot read request body"}})
+				return
+			}
+			defer cleanup()
+			r.Body = body
+
+			rec := storage.IdempotencyRecord{
+				UserID:      ac.UserID,
+				Key:         key,
+				Method:      r.Method,
+				Path:        r.URL.Path,
+				Fingerprint: hex.EncodeToString(h.Sum(nil)),
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+			existing, err := repo.Reserve(ctx, rec, ttl)
+			cancel()
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			if existing != nil {
+				switch {
+				case existing.Fingerprint != rec.Fingerprint:
+					writeJSON(w, http.StatusUnprocessableEntity, envelope{Success: false, Error: &apiError{Code: "idempotency_key_reused", Message: "idempotency key was used with a different request"}})
+				case existing.StatusCode == 0:
+					writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "idempotency_in_progress", Message: "request with this idempotency key is still in progress"}})
+				default:
+					if existing.ResponseHeaders == nil {
+						w.Header().Set("Content-Type", "application/json")
+					}
+					for k, v := range existing.ResponseHeaders {
+						w.Header()[k] = v
+					}
+					w.Header().Set(idempotencyReplayed, "true")
+					w.WriteHeader(existing.StatusCode)
+					_, _ = w.Write(existing.ResponseBody)
+				}
+				return
+			}
+
+			rw := &recordingWriter{ResponseWriter: w, base: w.Header().Clone()}
+			defer func() {
+				// A panicking handler must not leave the key in progress for the whole TTL.
+				if p := recover(); p != nil {
+					ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+					_ = repo.Release(ctx, ac.UserID, key)
+					cancel()
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+
+			// The request context may already be cancelled by the client.
+			ctx, cancel = context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+			defer cancel()
+			if rw.status == 0 || rw.status >= http.StatusInternalServerError {
+				// Server errors are not cached so the client can retry.
+				_ = repo.Release(ctx, ac.UserID, key)
+				return
+			}
+			_ = repo.Complete(ctx, ac.UserID, key, rw.status, handlerHeaders(rw.base, rw.header), rw.body.Bytes())
+		})
+	}
+}
+
+// handlerHeaders returns the headers the handler added or changed on top of
+// base. Headers set by outer middleware (request id, CORS) are left out so a
+// replay gets fresh ones for the retry instead of the first request's.
+func handlerHeaders(base, final http.Header) http.Header {
+	out := http.Header{}
+	for k, v := range final {
+		if !slices.Equal(base[k], v) {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func requestFingerprint(method, path string, body []byte) string {
+	h := fingerprintHash(method, path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprintHash starts the request fingerprint; the body is written to it
+// as it is read.
+func fingerprintHash(method, path string) hash.Hash {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(path))
+	h.Write([]byte{'\n'})
+	return h
+}
+
+// spoolBody reads the whole body into h and returns a copy for the handler:
+// in memory up to maxIdempotentBody, in a temporary file beyond that. The
+// cleanup function removes the file.
+func spoolBody(body io.Reader, h hash.Hash, maxBody int64) (io.ReadCloser, func(), error) {
+	noop := func() {}
+	head, err := io.ReadAll(io.LimitReader(body, maxIdempotentBody+1))
+	if err != nil {
+		return nil, noop, err
+	}
+	h.Write(head)
+	if int64(len(head)) > maxBody {
+		return nil, noop, errIdempotentBodyTooLarge
+	}
+	if len(head) <= maxIdempotentBody {
+		return io.NopCloser(bytes.NewReader(head)), noop, nil
+	}
+	f, err := os.CreateTemp("", "idempotent-body-*")
+	if err != nil {
+		return nil, noop, err
+	}
+	cleanup := func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+	if _, err := f.Write(head); err != nil {
+		cleanup()
+		return nil, noop, err
+	}
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(body, maxBody-int64(len(head))+1))
+	if err != nil {
+		cleanup()
+		return nil, noop, err
+	}
+	if int64(len(head))+n > maxBody {
+		cleanup()
+		return nil, noop, errIdempotentBodyTooLarge
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, noop, err
+	}
+	return io.NopCloser(f), cleanup, nil
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestRequestFingerprint(t *testing.T) {
+	a := requestFingerprint("POST", "/api/v1/orders", []byte(`{"items":[]}`))
+	b := requestFingerprint("POST", "/api/v1/orders", []byte(`{"items":[]}`))
+	if a != b {
+		t.Fatalf("expected equal fingerprints for identical requests")
+	}
+	if a == requestFingerprint("POST", "/api/v1/orders", []byte(`{"items":[{}]}`)) {
+		t.Fatalf("expected different fingerprint for different body")
+	}
+	if a == requestFingerprint("PATCH", "/api/v1/orders", []byte(`{"items":[]}`)) {
+		t.Fatalf("expected different fingerprint for different method")
+	}
+}
+
+func TestSpoolBody(t *testing.T) {
+	large := bytes.Repeat([]byte("x"), maxIdempotentBody+10)
+	for name, body := range map[string][]byte{"small": []byte(`{"items":[]}`), "large": large} {
+		h := fingerprintHash("POST", "/api/v1/orders/import")
+		rc, cleanup, err := spoolBody(bytes.NewReader(body), h, 4<<20)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		got, _ := io.ReadAll(rc)
+		cleanup()
+		if !bytes.Equal(got, body) {
+			t.Fatalf("%s: body not preserved", name)
+		}
+		if hex.EncodeToString(h.Sum(nil)) != requestFingerprint("POST", "/api/v1/orders/import", body) {
+			t.Fatalf("%s: streaming fingerprint differs", name)
+		}
+	}
+	if _, _, err := spoolBody(bytes.NewReader(large), fingerprintHash("POST", "/x"), maxIdempotentBody); !errors.Is(err, errIdempotentBodyTooLarge) {
+		t.Fatalf("expected errIdempotentBodyTooLarge, got %v", err)
+	}
+	if _, _, err := spoolBody(bytes.NewReader(large), fingerprintHash("POST", "/x"), maxIdempotentBody+5); !errors.Is(err, errIdempotentBodyTooLarge) {
+		t.Fatalf("expected errIdempotentBodyTooLarge for spooled body, got %v", err)
+	}
+}
+
+func TestHandlerHeaders(t *testing.T) {
+	base := http.Header{"X-Request-Id": {"a"}, "Vary": {"Origin"}}
+	final := http.Header{
+		"X-Request-Id":     {"a"},
+		"Vary":             {"Origin", "Accept"},
+		"Content-Type":     {"application/json"},
+		"Location":         {"/api/v1/orders/1"},
+		"Idempotent-Extra": {"x"},
+	}
+	got := handlerHeaders(base, final)
+	if _, ok := got["X-Request-Id"]; ok {
+		t.Fatalf("middleware header must not be stored: %v", got)
+	}
+	for _, k := range []string{"Vary", "Content-Type", "Location", "Idempotent-Extra"} {
+		if !slices.Equal(got[k], final[k]) {
+			t.Fatalf("%s: got %v, want %v", k, got[k], final[k])
+		}
+	}
+}
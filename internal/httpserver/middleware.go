@@ -30,6 +30,13 @@ func (w *statusWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Flush lets streaming handlers push data through the logging wrapper.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func Logger() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -69,6 +76,23 @@ func RateLimit(rps float64, burst int) func(next http.Handler) http.Handler {
 	}
 }
 
+// Timeout is middleware.Timeout for every route except the listed paths,
+// which stream long responses and set their own deadline.
+func Timeout(d time.Duration, except ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		timed := middleware.Timeout(d)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, p := range except {
+				if r.URL.Path == p {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			timed.ServeHTTP(w, r)
+		})
+	}
+}
+
 func clientIP(r *http.Request) string {
 	// middleware.RealIP already sets RemoteAddr appropriately, but try headers too
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
@@ -0,0 +1,25 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutExcept(t *testing.T) {
+	var hasDeadline bool
+	h := Timeout(30*time.Second, "/api/v1/orders/export")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline = r.Context().Deadline()
+	}))
+	cases := map[string]bool{
+		"/api/v1/orders":        true,
+		"/api/v1/orders/export": false,
+	}
+	for path, want := range cases {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		if hasDeadline != want {
+			t.Errorf("%s: deadline %v, want %v", path, hasDeadline, want)
+		}
+	}
+}
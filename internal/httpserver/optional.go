@@ -0,0 +1,15 @@
+package httpserver
+
+import "encoding/json"
+
+// optional tells an absent JSON field apart from an explicit null: Set is
+// true whenever the field is present, Value is nil for null.
+type optional[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (o *optional[T]) UnmarshalJSON(b []byte) error {
+	o.Set = true
+	return json.Unmarshal(b, &o.Value)
+}
@@ -0,0 +1,26 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOptional(t *testing.T) {
+	var req struct {
+		A optional[int]     `json:"a"`
+		B optional[float64] `json:"b"`
+		C optional[int]     `json:"c"`
+	}
+	if err := json.Unmarshal([]byte(`{"a":null,"b":2.5}`), &req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !req.A.Set || req.A.Value != nil {
+		t.Fatalf("explicit null: got %+v", req.A)
+	}
+	if !req.B.Set || req.B.Value == nil || *req.B.Value != 2.5 {
+		t.Fatalf("value: got %+v", req.B)
+	}
+	if req.C.Set {
+		t.Fatalf("absent field must not be set")
+	}
+}
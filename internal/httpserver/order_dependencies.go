@@ -0,0 +1,422 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	maxDurationDays     = 3650
+	durationDaysMessage = "duration_days must be between 0 and 3650"
+	// maxScheduleOrders caps the orders a project schedule is computed over.
+	maxScheduleOrders = 2000
+)
+
+var errScheduleCycle = errors.New("dependency cycle")
+
+type orderDependencies struct {
+	Predecessors []models.OrderDependency `json:"predecessors"`
+	Successors   []models.OrderDependency `json:"successors"`
+	// Pending lists the predecessors that still block the start of the order.
+	Pending []string `json:"pending"`
+}
+
+// dependencyCycle reports whether making predecessorID a predecessor of
+// orderID would close a cycle. upstream holds the dependencies predecessorID
+// transitively relies on; the returned path runs from orderID through the
+// new dependency back to orderID.
+func dependencyCycle(upstream []models.OrderDependency, orderID, predecessorID string) []string {
+	if orderID == predecessorID {
+		return []string{orderID, orderID}
+	}
+	preds := map[string][]string{}
+	for _, d := range upstream {
+		preds[d.OrderID] = append(preds[d.OrderID], d.PredecessorID)
+	}
+	// Depth-first search from the new predecessor towards its own predecessors.
+	parent := map[string]string{predecessorID: ""}
+	stack := []string{predecessorID}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, p := range preds[id] {
+			if _, seen := parent[p]; seen {
+				continue
+			}
+			parent[p] = id
+			if p == orderID {
+				// parent links lead from orderID back to predecessorID.
+				chain := []string{}
+				for at := parent[orderID]; at != ""; at = parent[at] {
+					chain = append(chain, at)
+				}
+				path := []string{orderID}
+				for i := len(chain) - 1; i >= 0; i-- {
+					path = append(path, chain[i])
+				}
+				return append(path, orderID)
+			}
+			stack = append(stack, p)
+		}
+	}
+	return nil
+}
+
+type scheduleTask struct {
+	OrderID      string             `json:"order_id"`
+	Status       models.OrderStatus `json:"status"`
+	DurationDays int                `json:"duration_days"`
+	Predecessors []string           `json:"predecessors"`
+	// Offsets in days from the schedule start.
+	EarliestStart  int  `json:"earliest_start_day"`
+	EarliestFinish int  `json:"earliest_finish_day"`
+	LatestStart    int  `json:"latest_start_day"`
+	LatestFinish   int  `json:"latest_finish_day"`
+	SlackDays      int  `json:"slack_days"`
+	Critical       bool `json:"critical"`
+	// Calendar dates (YYYY-MM-DD) of the earliest start and finish.
+	EarliestStartDate  string `json:"earliest_start"`
+	EarliestFinishDate string `json:"earliest_finish"`
+}
+
+type projectSchedule struct {
+	ProjectID    string         `json:"project_id"`
+	StartDate    string         `json:"start_date"`
+	FinishDate   string         `json:"finish_date"`
+	DurationDays int            `json:"duration_days"`
+	CriticalPath []string       `json:"critical_path"`
+	Tasks        []scheduleTask `json:"tasks"`
+}
+
+// computeSchedule runs the critical path method over the orders: earliest
+// start and finish in a forward pass, latest ones in a backward pass, slack as
+// their difference. Dependencies on orders outside the list are ignored.
+// Tasks keep the order of the input; dates are counted from start.
+func computeSchedule(orders []models.Order, deps []models.OrderDependency, start time.Time) (projectSchedule, error) {
+	index := make(map[string]int, len(orders))
+	tasks := make([]scheduleTask, len(orders))
+	for i, o := range orders {
+		index[o.ID] = i
+		tasks[i] = scheduleTask{OrderID: o.ID, Status: o.Status, DurationDays: o.DurationDays, Predecessors: []string{}}
+	}
+	succs := make([][]int, len(orders))
+	preds := make([][]int, len(orders))
+	indegree := make([]int, len(orders))
+	for _, d := range deps {
+		from, ok1 := index[d.PredecessorID]
+		to, ok2 := index[d.OrderID]
+		if !ok1 || !ok2 {
+			continue
+		}
+		succs[from] = append(succs[from], to)
+		preds[to] = append(preds[to], from)
+		indegree[to]++
+		tasks[to].Predecessors = append(tasks[to].Predecessors, d.PredecessorID)
+	}
+
+	// Kahn's algorithm gives the topological order for both passes.
+	topo := make([]int, 0, len(orders))
+	for i := range tasks {
+		if indegree[i] == 0 {
+			topo = append(topo, i)
+		}
+	}
+	for k := 0; k < len(topo); k++ {
+		for _, s := range succs[topo[k]] {
+			indegree[s]--
+			if indegree[s] == 0 {
+				topo = append(topo, s)
+			}
+		}
+	}
+	if len(topo) != len(orders) {
+		return projectSchedule{}, errScheduleCycle
+	}
+
+	total := 0
+	for _, i := range topo {
+		t := &tasks[i]
+		for _, p := range preds[i] {
+			if tasks[p].EarliestFinish > t.EarliestStart {
+				t.EarliestStart = tasks[p].EarliestFinish
+			}
+		}
+		t.EarliestFinish = t.EarliestStart + t.DurationDays
+		if t.EarliestFinish > total {
+			total = t.EarliestFinish
+		}
+	}
+	for k := len(topo) - 1; k >= 0; k-- {
+		i := topo[k]
+		t := &tasks[i]
+		t.LatestFinish = total
+		for _, s := range succs[i] {
+			if tasks[s].LatestStart < t.LatestFinish {
+				t.LatestFinish = tasks[s].LatestStart
+			}
+		}
+		t.LatestStart = t.LatestFinish - t.DurationDays
+		t.SlackDays = t.LatestStart - t.EarliestStart
+		t.Critical = t.SlackDays == 0
+		t.EarliestStartDate = start.AddDate(0, 0, t.EarliestStart).Format("2006-01-02")
+		t.EarliestFinishDate = start.AddDate(0, 0, t.EarliestFinish).Format("2006-01-02")
+	}
+
+	// Walk back from the critical task finishing last through critical
+	// predecessors that end exactly when it starts.
+	path := []string{}
+	at := -1
+	for _, i := range topo {
+		if tasks[i].Critical && tasks[i].EarliestFinish == total && (at < 0 || i < at) {
+			at = i
+		}
+	}
+	for at >= 0 {
+		path = append(path, tasks[at].OrderID)
+		next := -1
+		for _, p := range preds[at] {
+			if tasks[p].Critical && tasks[p].EarliestFinish == tasks[at].EarliestStart && (next < 0 || p < next) {
+				next = p
+			}
+		}
+		at = next
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return projectSchedule{
+		StartDate:    start.Format("2006-01-02"),
+		FinishDate:   start.AddDate(0, 0, total).Format("2006-01-02"),
+		DurationDays: total,
+		CriticalPath: path,
+		Tasks:        tasks,
+	}, nil
+}
+
+// canPlanOrder: whoever may change the order schedule, i.e. the owner,
+// admins and managers.
+func canPlanOrder(o *models.Order, ac *AuthContext) bool {
+	return canModifyOrder(o, ac) || hasRole(ac.Roles, "manager")
+}
+
+// ListOrderDependenciesHandler returns what the order waits for and what waits
+// for it.
+func ListOrderDependenciesHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewOrderDependencyRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		var res orderDependencies
+		var err error
+		res.Predecessors, err = repo.Predecessors(ctx, o.ID)
+		if err == nil {
+			res.Successors, err = repo.Successors(ctx, o.ID)
+		}
+		if err == nil {
+			res.Pending, err = repo.PendingPredecessors(ctx, o.ID)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: res})
+	}
+}
+
+// AddOrderDependencyHandler makes the order wait for a predecessor. Only
+// orders that have not started yet take new predecessors, and a dependency
+// that would close a cycle is rejected. Adding an existing one is a no-op.
+func AddOrderDependencyHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canPlanOrder(o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		if o.Status != models.OrderStatusCreated && o.Status != models.OrderStatusPendingApproval {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_editable", Message: "predecessors can only be added before the order starts"}})
+			return
+		}
+		pred, _, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "predecessorID"))
+		if apiErr != nil || pred.DeletedAt != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "predecessor not found"}})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewOrderDependencyRepository(tx)
+		upstream, err := repo.Upstream(ctx, pred.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if cycle := dependencyCycle(upstream, o.ID, pred.ID); cycle != nil {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "dependency_cycle", Message: "dependency would create a cycle: " + strings.Join(cycle, " -> ")}})
+			return
+		}
+		d := &models.OrderDependency{OrderID: o.ID, PredecessorID: pred.ID, CreatedBy: ac.UserID}
+		added, err := repo.Add(ctx, d)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if !added {
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: d})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderDependencyAdded, map[string]any{
+			"order_id":       o.ID,
+			"predecessor_id": pred.ID,
+			"added_by":       ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: d})
+	}
+}
+
+func RemoveOrderDependencyHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canPlanOrder(o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		predecessorID := chi.URLParam(r, "predecessorID")
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		if err := storage.NewOrderDependencyRepository(tx).Remove(ctx, o.ID, predecessorID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "dependency not found"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderDependencyRemoved, map[string]any{
+			"order_id":       o.ID,
+			"predecessor_id": predecessorID,
+			"removed_by":     ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"order_id": o.ID, "predecessor_id": predecessorID, "status": "removed"}})
+	}
+}
+
+// ProjectScheduleHandler computes the critical path of a project from the
+// planned durations of its orders and their dependencies. Cancelled orders
+// are left out; the schedule starts at the project start date, or today when
+// the project has none.
+func ProjectScheduleHandler(db *sql.DB) http.HandlerFunc {
+	orders := storage.NewOrderRepository(db)
+	deps := storage.NewOrderDependencyRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		p, _, status, apiErr := loadViewableProject(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		start := time.Now().UTC().Truncate(24 * time.Hour)
+		if p.StartDate != "" {
+			if d, err := time.Parse("2006-01-02", p.StartDate); err == nil {
+				start = d
+			}
+		}
+		list, err := orders.List(ctx, storage.ListOrdersParams{
+			ProjectID:       p.ID,
+			AdminView:       true,
+			IncludeArchived: true,
+			Statuses:        []string{string(models.OrderStatusCreated), string(models.OrderStatusPendingApproval), string(models.OrderStatusInProgress), string(models.OrderStatusDone)},
+			Sort:            "created_asc",
+			Limit:           maxScheduleOrders,
+		})
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		edges, err := deps.ListByProject(ctx, p.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		sched, err := computeSchedule(list, edges, start)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: err.Error()}})
+			return
+		}
+		sched.ProjectID = p.ID
+		// Stable output regardless of the storage order.
+		sort.SliceStable(sched.Tasks, func(i, j int) bool { return sched.Tasks[i].EarliestStart < sched.Tasks[j].EarliestStart })
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: sched})
+	}
+}
@@ -0,0 +1,81 @@
+package httpserver
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"frame_control_system/internal/models"
+)
+
+func dep(order, pred string) models.OrderDependency {
+	return models.OrderDependency{OrderID: order, PredecessorID: pred}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	// c waits for b, b waits for a.
+	upstreamOfC := []models.OrderDependency{dep("c", "b"), dep("b", "a")}
+	if got := dependencyCycle(upstreamOfC, "a", "c"); !reflect.DeepEqual(got, []string{"a", "c", "b", "a"}) {
+		t.Fatalf("unexpected cycle %v", got)
+	}
+	if got := dependencyCycle(upstreamOfC, "d", "c"); got != nil {
+		t.Fatalf("no cycle expected, got %v", got)
+	}
+	if got := dependencyCycle(nil, "a", "a"); got == nil {
+		t.Fatal("self dependency must be a cycle")
+	}
+	// Diamond: d waits for b and c, both wait for a; adding d -> e is fine,
+	// a -> d is not.
+	upstreamOfD := []models.OrderDependency{dep("d", "b"), dep("d", "c"), dep("b", "a"), dep("c", "a")}
+	if got := dependencyCycle(upstreamOfD, "e", "d"); got != nil {
+		t.Fatalf("no cycle expected, got %v", got)
+	}
+	if got := dependencyCycle(upstreamOfD, "a", "d"); got == nil || got[0] != "a" || got[len(got)-1] != "a" {
+		t.Fatalf("unexpected cycle %v", got)
+	}
+}
+
+func TestComputeSchedule(t *testing.T) {
+	orders := []models.Order{
+		{ID: "foundation", DurationDays: 5},
+		{ID: "walls", DurationDays: 10},
+		{ID: "wiring", DurationDays: 3},
+		{ID: "roof", DurationDays: 4},
+		{ID: "fence", DurationDays: 2},
+	}
+	deps := []models.OrderDependency{
+		dep("walls", "foundation"),
+		dep("wiring", "walls"),
+		dep("roof", "walls"),
+		dep("roof", "elsewhere"), // outside the project, ignored
+	}
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	s, err := computeSchedule(orders, deps, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.DurationDays != 19 || s.FinishDate != "2025-03-20" {
+		t.Fatalf("unexpected duration %d, finish %s", s.DurationDays, s.FinishDate)
+	}
+	if !reflect.DeepEqual(s.CriticalPath, []string{"foundation", "walls", "roof"}) {
+		t.Fatalf("unexpected critical path %v", s.CriticalPath)
+	}
+	byID := map[string]scheduleTask{}
+	for _, task := range s.Tasks {
+		byID[task.OrderID] = task
+	}
+	if w := byID["wiring"]; w.EarliestStart != 15 || w.EarliestFinishDate != "2025-03-19" || w.SlackDays != 1 || w.Critical {
+		t.Fatalf("unexpected wiring %+v", w)
+	}
+	if f := byID["fence"]; f.SlackDays != 17 || f.Critical {
+		t.Fatalf("unexpected fence %+v", f)
+	}
+	if r := byID["roof"]; !r.Critical || r.EarliestStartDate != "2025-03-16" || !reflect.DeepEqual(r.Predecessors, []string{"walls"}) {
+		t.Fatalf("unexpected roof %+v", r)
+	}
+
+	cyclic := []models.OrderDependency{dep("walls", "foundation"), dep("foundation", "walls")}
+	if _, err := computeSchedule(orders, cyclic, start); err != errScheduleCycle {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+}
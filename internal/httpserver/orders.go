@@ -6,7 +6,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,15 +22,115 @@ import (
 )
 
 type createOrderRequest struct {
-	Items []models.OrderItem `json:"items"`
+	// ProjectID ties the order to a project the caller can see.
+	ProjectID string               `json:"project_id"`
+	Items     []models.OrderItem   `json:"items"`
+	Notes     string               `json:"notes"`
+	DueAt     *time.Time           `json:"due_at"`
+	Priority  models.OrderPriority `json:"priority"`
+	// DurationDays is the planned duration in days used by the project schedule.
+	DurationDays int      `json:"duration_days"`
+	Tags         []string `json:"tags"`
+	// CustomFields values are checked against the admin-defined fields.
+	CustomFields map[string]any `json:"custom_fields"`
+	// Discounts are set by managers and admins; anyone may redeem a promo code.
+	DiscountPercent float64 `json:"discount_percent"`
+	DiscountFixed   float64 `json:"discount_fixed"`
+	PromoCode       string  `json:"promo_code"`
+}
+
+func (r createOrderRequest) hasDiscounts() bool {
+	if r.DiscountPercent != 0 || r.DiscountFixed != 0 {
+		return true
+	}
+	for _, it := range r.Items {
+		if it.DiscountPercent != 0 {
+			return true
+		}
+	}
+	return false
 }
 
 type updateStatusRequest struct {
 	Status string `json:"status"`
 }
 
-func CreateOrderHandler(db *sql.DB) http.HandlerFunc {
-	repo := storage.NewOrderRepository(db)
+type editOrderRequest struct {
+	Add    []models.OrderItem `json:"add"`
+	Update []itemPatch        `json:"update"`
+	Remove []string           `json:"remove"`
+	Notes  *string            `json:"notes"`
+	// DueAt and Priority may change while the order is created or in progress.
+	DueAt        optional[time.Time]   `json:"due_at"`
+	Priority     *models.OrderPriority `json:"priority"`
+	DurationDays *int                  `json:"duration_days"`
+	// Order-level discounts; managers and admins only.
+	DiscountPercent *float64 `json:"discount_percent"`
+	DiscountFixed   *float64 `json:"discount_fixed"`
+	// Tags replace the current ones; CustomFields are merged, null removes a
+	// field. Both may change while the order is open, managers included.
+	Tags         *[]string      `json:"tags"`
+	CustomFields map[string]any `json:"custom_fields"`
+	// ProjectID moves an open order to another project; "" detaches it.
+	ProjectID *string `json:"project_id"`
+}
+
+func (r editOrderRequest) changesContent() bool {
+	if len(r.Add) > 0 || len(r.Remove) > 0 || r.Notes != nil {
+		return true
+	}
+	for _, p := range r.Update {
+		if p.Quantity != nil || p.Price != nil || p.Category != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// changesDiscounts reports whether the request sets an order or line discount.
+func (r editOrderRequest) changesDiscounts() bool {
+	if r.DiscountPercent != nil || r.DiscountFixed != nil {
+		return true
+	}
+	for _, p := range r.Update {
+		if p.DiscountPercent != nil {
+			return true
+		}
+	}
+	for _, it := range r.Add {
+		if it.DiscountPercent != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// itemPatch changes an existing item, matched by name. Omitted fields are kept.
+type itemPatch struct {
+	Name            string   `json:"name"`
+	Quantity        *int     `json:"quantity"`
+	Price           *float64 `json:"price"`
+	Category        *string  `json:"category"`
+	DiscountPercent *float64 `json:"discount_percent"`
+}
+
+type itemChange struct {
+	Name   string           `json:"name"`
+	Before models.OrderItem `json:"before"`
+	After  models.OrderItem `json:"after"`
+}
+
+type itemsDiff struct {
+	Added   []models.OrderItem `json:"added,omitempty"`
+	Removed []models.OrderItem `json:"removed,omitempty"`
+	Updated []itemChange       `json:"updated,omitempty"`
+}
+
+func (d itemsDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Updated) == 0
+}
+
+func CreateOrderHandler(db *sql.DB, rates storage.TaxRates) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ac := GetAuth(r)
 		if ac == nil {
@@ -38,29 +142,141 @@ func CreateOrderHandler(db *sql.DB) http.HandlerFunc {
 			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid items"}})
 			return
 		}
+		if req.hasDiscounts() && !canSetDiscounts(ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "only managers and admins can set discounts"}})
+			return
+		}
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
-		order, err := storage.NewOrder(ac.UserID, req.Items)
+		order, err := storage.NewOrder(ac.UserID, normalizeItems(req.Items))
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid order items"}})
 			return
 		}
-		if err := repo.Create(ctx, order); err != nil {
+		order.Notes = strings.TrimSpace(req.Notes)
+		if req.Priority != "" {
+			if !req.Priority.Valid() {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "priority must be one of low, normal, high, urgent"}})
+				return
+			}
+			order.Priority = req.Priority
+		}
+		if req.DurationDays < 0 || req.DurationDays > maxDurationDays {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: durationDaysMessage}})
+			return
+		}
+		order.DurationDays = req.DurationDays
+		if req.DueAt != nil {
+			if !req.DueAt.After(time.Now()) {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "due_at must be in the future"}})
+				return
+			}
+			due := req.DueAt.UTC().Truncate(time.Second)
+			order.DueAt = &due
+		}
+		order.DiscountPercent = req.DiscountPercent
+		order.DiscountFixed = req.DiscountFixed
+		if order.Tags, err = normalizeTags(req.Tags); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		if len(req.CustomFields) > 0 {
+			defs, err := loadFieldDefs(ctx, tx)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			if order.CustomFields, err = mergeCustomFields(defs, nil, req.CustomFields); err != nil {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+				return
+			}
+		}
+		if code := normalizePromoCode(req.PromoCode); code != "" {
+			promos := storage.NewPromoCodeRepository(tx)
+			promo, err := promos.Get(ctx, code)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_promo_code", Message: "promo code not found"}})
+				return
+			}
+			now := time.Now()
+			if reason := promo.Unavailable(now); reason != "" {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_promo_code", Message: reason}})
+				return
+			}
+			ok, err := promos.Redeem(ctx, code, now)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			if !ok {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_promo_code", Message: "promo code usage limit reached"}})
+				return
+			}
+			order.Promo = &models.AppliedPromo{Code: promo.Code, Kind: promo.Kind, Value: promo.Value}
+		}
+		if order.ProjectID = strings.TrimSpace(req.ProjectID); order.ProjectID != "" {
+			if status, apiErr := checkOrderProject(ctx, tx, ac, order.ProjectID); apiErr != nil {
+				writeJSON(w, status, envelope{Success: false, Error: apiErr})
+				return
+			}
+		}
+		if err := storage.Reprice(&order, rates); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		steps, err := orderApprovalSteps(ctx, tx, order)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if len(steps) > 0 {
+			order.Status = models.OrderStatusPendingApproval
+		}
+		if err := storage.NewOrderRepository(tx).Create(ctx, order); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		payload := map[string]any{
+			"id":        order.ID,
+			"user_id":   order.UserID,
+			"status":    order.Status,
+			"total":     order.TotalAmount,
+			"breakdown": order.Breakdown,
+			"priority":  order.Priority,
+			"due_at":    order.DueAt,
+		}
+		if order.ProjectID != "" {
+			payload["project_id"] = order.ProjectID
+		}
+		if order.Promo != nil {
+			payload["promo_code"] = order.Promo.Code
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderCreated, payload); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if len(steps) > 0 {
+			if err := requestApproval(ctx, tx, &order, steps, ac.UserID); err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
 			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
 			return
 		}
-		_ = storage.AddOutboxEvent(ctx, db, events.OrderCreated, map[string]any{
-			"id":      order.ID,
-			"user_id": order.UserID,
-			"status":  order.Status,
-			"total":   order.TotalAmount,
-		})
 		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: order})
 	}
 }
 
 func GetOrderHandler(db *sql.DB) http.HandlerFunc {
-	repo := storage.NewOrderRepository(db)
 	return func(w http.ResponseWriter, r *http.Request) {
 		ac := GetAuth(r)
 		if ac == nil {
@@ -78,13 +294,9 @@ func GetOrderHandler(db *sql.DB) http.HandlerFunc {
 		}
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
-		o, err := repo.GetByID(ctx, id)
-		if err != nil {
-			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "order not found"}})
-			return
-		}
-		if o.UserID != ac.UserID && !hasRole(ac.Roles, "admin") {
-			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, id)
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
 			return
 		}
 		writeJSON(w, http.StatusOK, envelope{Success: true, Data: o})
@@ -100,36 +312,265 @@ func ListOrdersHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 		q := r.URL.Query()
-		limit := parseIntDefault(q.Get("limit"), 20, 1, 100)
-		page := parseIntDefault(q.Get("page"), 1, 1, 100000)
-		offset := (page - 1) * limit
-		status := strings.TrimSpace(q.Get("status"))
-		sort := strings.TrimSpace(q.Get("sort"))
-		params := storage.ListOrdersParams{
-			UserID:    ac.UserID,
-			Status:    status,
-			Sort:      sort,
-			Limit:     limit,
-			Offset:    offset,
-			AdminView: hasRole(ac.Roles, "admin"),
+		pg, err := parsePageRequest(q)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid cursor"}})
+			return
 		}
-		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-		defer cancel()
-		list, err := repo.List(ctx, params)
+		params, err := parseOrderFilters(q, ac)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		if apiErr := checkListScope(params, ac); apiErr != nil {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: apiErr})
+			return
+		}
+		writeOrderPage(w, r, repo, params, pg)
+	}
+}
+
+// writeOrderPage runs a paginated order listing and writes the page.
+func writeOrderPage(w http.ResponseWriter, r *http.Request, repo *storage.OrderRepository, params storage.ListOrdersParams, pg pageRequest) {
+	params.Limit = pg.Limit + 1 // one extra row tells whether there is a next page
+	params.Offset = pg.Offset
+	params.After = pg.After
+	if apiErr := checkCursorSort(pg, params.Sort, params.KeysetSort()); apiErr != nil {
+		writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: apiErr})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	list, err := repo.List(ctx, params)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+		return
+	}
+	var next string
+	more := len(list) > pg.Limit
+	if more {
+		list = list[:pg.Limit]
+		if params.KeysetSort() {
+			last := list[len(list)-1]
+			next = storage.EncodeCursor(storage.Cursor{CreatedAt: last.CreatedAt, ID: last.ID, Sort: cursorSort(params.Sort)})
+		}
+	}
+	var total *int
+	if pg.IncludeTotal {
+		n, err := repo.Count(ctx, params)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
 			return
 		}
-		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]any{
-			"items": list,
-			"page":  page,
-			"limit": limit,
-		}})
+		total = &n
 	}
+	data := pg.meta(next, more, total)
+	data["items"] = list
+	if more && next == "" {
+		setNextPageLink(w, r, pg.Page)
+	}
+	setNextLink(w, r, next)
+	writeJSON(w, http.StatusOK, envelope{Success: true, Data: data})
+}
+
+// parseOrderFilters reads and validates the order list filters from the query
+// string. Pagination fields are left for the caller to fill in.
+func parseOrderFilters(q url.Values, ac *AuthContext) (storage.ListOrdersParams, error) {
+	p := storage.ListOrdersParams{
+		UserID:    ac.UserID,
+		OwnerID:   strings.TrimSpace(q.Get("user_id")),
+		ProjectID: strings.TrimSpace(q.Get("project_id")),
+		ItemName:  strings.TrimSpace(q.Get("item")),
+		Sort:      strings.TrimSpace(q.Get("sort")),
+		AdminView: seesAllOrders(ac),
+	}
+	switch v := strings.TrimSpace(q.Get("assignee")); v {
+	case "":
+	case "me":
+		p.AssigneeID = ac.UserID
+	case "none":
+		p.Unassigned = true
+	default:
+		p.AssigneeID = v
+	}
+	// status and sort predate the stricter filters and stay lenient: an
+	// unknown status matches no orders, an unknown sort falls back to the default.
+	for _, raw := range q["status"] {
+		for _, st := range strings.Split(raw, ",") {
+			if st = strings.TrimSpace(st); st != "" {
+				p.Statuses = append(p.Statuses, st)
+			}
+		}
+	}
+	for _, raw := range q["priority"] {
+		for _, pr := range strings.Split(raw, ",") {
+			pr = strings.TrimSpace(pr)
+			if pr == "" {
+				continue
+			}
+			if !models.OrderPriority(pr).Valid() {
+				return p, fmt.Errorf("unknown priority %q", pr)
+			}
+			p.Priorities = append(p.Priorities, pr)
+		}
+	}
+	for _, raw := range q["tag"] {
+		for _, tag := range strings.Split(raw, ",") {
+			if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+				p.Tags = append(p.Tags, tag)
+			}
+		}
+	}
+	for name, vals := range q {
+		key, ok := strings.CutPrefix(name, "cf.")
+		if !ok {
+			continue
+		}
+		if !customFieldKeyRe.MatchString(key) {
+			return p, fmt.Errorf("invalid custom field filter %q", name)
+		}
+		if p.CustomFields == nil {
+			p.CustomFields = map[string]string{}
+		}
+		p.CustomFields[key] = strings.TrimSpace(vals[0])
+	}
+	if !storage.ValidOrderSort(p.Sort) {
+		p.Sort = ""
+	}
+	var err error
+	if p.Overdue, err = parseBoolParam(q, "overdue"); err != nil {
+		return p, err
+	}
+	if p.IncludeArchived, err = parseBoolParam(q, "include_archived"); err != nil {
+		return p, err
+	}
+	if p.Deleted, err = parseBoolParam(q, "deleted"); err != nil {
+		return p, err
+	}
+	if v := strings.TrimSpace(q.Get("due_within")); v != "" {
+		d, err := parseDueWithin(v)
+		if err != nil {
+			return p, err
+		}
+		until := time.Now().UTC().Add(d)
+		p.DueBefore = &until
+	}
+	if p.CreatedFrom, err = parseDateParam(q, "created_from", false); err != nil {
+		return p, err
+	}
+	if p.CreatedTo, err = parseDateParam(q, "created_to", true); err != nil {
+		return p, err
+	}
+	if p.UpdatedFrom, err = parseDateParam(q, "updated_from", false); err != nil {
+		return p, err
+	}
+	if p.UpdatedTo, err = parseDateParam(q, "updated_to", true); err != nil {
+		return p, err
+	}
+	if p.MinTotal, err = parseAmountParam(q, "min_total"); err != nil {
+		return p, err
+	}
+	if p.MaxTotal, err = parseAmountParam(q, "max_total"); err != nil {
+		return p, err
+	}
+	if p.CreatedFrom != nil && p.CreatedTo != nil && !p.CreatedFrom.Before(*p.CreatedTo) {
+		return p, errors.New("created_from must be before created_to")
+	}
+	if p.UpdatedFrom != nil && p.UpdatedTo != nil && !p.UpdatedFrom.Before(*p.UpdatedTo) {
+		return p, errors.New("updated_from must be before updated_to")
+	}
+	if p.MinTotal != nil && p.MaxTotal != nil && *p.MinTotal > *p.MaxTotal {
+		return p, errors.New("min_total must not exceed max_total")
+	}
+	return p, nil
+}
+
+// checkListScope rejects owner/assignee filters that reach beyond what the
+// caller may see.
+func checkListScope(p storage.ListOrdersParams, ac *AuthContext) *apiError {
+	if p.Deleted && !hasRole(ac.Roles, "admin") {
+		return &apiError{Code: "forbidden", Message: "deleted filter requires admin role"}
+	}
+	if p.AdminView {
+		return nil
+	}
+	if p.OwnerID != "" && p.OwnerID != ac.UserID {
+		return &apiError{Code: "forbidden", Message: "user_id filter requires admin or manager role"}
+	}
+	if p.AssigneeID != "" && p.AssigneeID != ac.UserID {
+		return &apiError{Code: "forbidden", Message: "assignee filter for other users requires admin or manager role"}
+	}
+	return nil
+}
+
+func parseBoolParam(q url.Values, name string) (bool, error) {
+	switch strings.TrimSpace(q.Get(name)) {
+	case "", "false", "0":
+		return false, nil
+	case "true", "1":
+		return true, nil
+	}
+	return false, fmt.Errorf("%s must be true or false", name)
+}
+
+// parseDateParam accepts RFC 3339 timestamps or YYYY-MM-DD dates. Upper bounds
+// are returned as exclusive: a date covers the whole day, a timestamp its second.
+func parseDateParam(q url.Values, name string, upper bool) (*time.Time, error) {
+	v := strings.TrimSpace(q.Get(name))
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		t = t.UTC()
+		if upper {
+			t = t.Truncate(time.Second).Add(time.Second)
+		}
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date (YYYY-MM-DD) or RFC 3339 timestamp", name)
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// parseDueWithin accepts Go durations ("36h") and whole days ("3d").
+func parseDueWithin(v string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, errors.New("due_within must be a duration such as 48h or 3d")
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(v); err != nil {
+			return 0, errors.New("due_within must be a duration such as 48h or 3d")
+		}
+	}
+	if d <= 0 {
+		return 0, errors.New("due_within must be positive")
+	}
+	return d, nil
+}
+
+func parseAmountParam(q url.Values, name string) (*float64, error) {
+	v := strings.TrimSpace(q.Get(name))
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return &f, nil
 }
 
 func UpdateOrderStatusHandler(db *sql.DB) http.HandlerFunc {
-	repo := storage.NewOrderRepository(db)
 	return func(w http.ResponseWriter, r *http.Request) {
 		ac := GetAuth(r)
 		if ac == nil {
@@ -157,34 +598,435 @@ func UpdateOrderStatusHandler(db *sql.DB) http.HandlerFunc {
 		}
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
-		o, err := repo.GetByID(ctx, id)
+		tx, err := db.BeginTx(ctx, nil)
 		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer func() { _ = tx.Rollback() }()
+		o, status, apiErr := transitionOrder(ctx, tx, ac, id, to)
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: o})
+	}
+}
+
+func EditOrderHandler(db *sql.DB, rates storage.TaxRates) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "id required"}})
+			return
+		}
+		var req editOrderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		if req.Priority != nil && !req.Priority.Valid() {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "priority must be one of low, normal, high, urgent"}})
+			return
+		}
+		if req.DurationDays != nil && (*req.DurationDays < 0 || *req.DurationDays > maxDurationDays) {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: durationDaysMessage}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		// Items are rewritten as a whole, so the order is read inside the write
+		// transaction: a concurrent edit cannot land in between and get lost.
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		txRepo := storage.NewOrderRepository(tx)
+		o, err := txRepo.GetByID(ctx, id)
+		if err != nil || o.DeletedAt != nil {
 			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "order not found"}})
 			return
 		}
-		if o.UserID != ac.UserID && !hasRole(ac.Roles, "admin") {
+		// Managers control deadlines and discounts but do not touch the order content.
+		if !canModifyOrder(o, ac) && (req.changesContent() || !hasRole(ac.Roles, "manager")) {
 			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
 			return
 		}
-		if err := validateTransition(o.Status, to); err != nil {
-			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_transition", Message: err.Error()}})
+		if req.changesDiscounts() && !canSetDiscounts(ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "only managers and admins can set discounts"}})
+			return
+		}
+		if (req.changesContent() || req.changesDiscounts()) && o.Status != models.OrderStatusCreated && o.Status != models.OrderStatusPendingApproval {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_editable", Message: fmt.Sprintf("order in status %s cannot be edited", o.Status)}})
+			return
+		}
+		if !o.Open() {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_editable", Message: fmt.Sprintf("order in status %s cannot be edited", o.Status)}})
+			return
+		}
+		items, diff, err := applyItemChanges(o.Items, req)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		notes := o.Notes
+		if req.Notes != nil {
+			notes = strings.TrimSpace(*req.Notes)
+		}
+		dueAt, priority := o.DueAt, o.Priority
+		if req.DueAt.Set {
+			dueAt = req.DueAt.Value
+			if dueAt != nil {
+				due := dueAt.UTC().Truncate(time.Second)
+				if !due.After(o.CreatedAt) {
+					writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "due_at must be after the order creation time"}})
+					return
+				}
+				dueAt = &due
+			}
+		}
+		if req.Priority != nil {
+			priority = *req.Priority
+		}
+		durationDays := o.DurationDays
+		if req.DurationDays != nil {
+			durationDays = *req.DurationDays
+		}
+		discountPercent, discountFixed := o.DiscountPercent, o.DiscountFixed
+		if req.DiscountPercent != nil {
+			discountPercent = *req.DiscountPercent
+		}
+		if req.DiscountFixed != nil {
+			discountFixed = *req.DiscountFixed
+		}
+		tags, fields := o.Tags, o.CustomFields
+		if req.Tags != nil {
+			if tags, err = normalizeTags(*req.Tags); err != nil {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+				return
+			}
+		}
+		if len(req.CustomFields) > 0 {
+			defs, err := loadFieldDefs(ctx, tx)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			if fields, err = mergeCustomFields(defs, o.CustomFields, req.CustomFields); err != nil {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+				return
+			}
+		}
+		projectID := o.ProjectID
+		if req.ProjectID != nil {
+			if projectID = strings.TrimSpace(*req.ProjectID); projectID != "" && projectID != o.ProjectID {
+				if status, apiErr := checkOrderProject(ctx, tx, ac, projectID); apiErr != nil {
+					writeJSON(w, status, envelope{Success: false, Error: apiErr})
+					return
+				}
+			}
+		}
+		contentChanged := !diff.empty() || notes != o.Notes
+		discountsChanged := discountPercent != o.DiscountPercent || discountFixed != o.DiscountFixed
+		scheduleChanged := !sameTime(dueAt, o.DueAt) || priority != o.Priority || durationDays != o.DurationDays
+		attributesChanged := !reflect.DeepEqual(tags, o.Tags) || !reflect.DeepEqual(fields, o.CustomFields)
+		projectChanged := projectID != o.ProjectID
+		if !contentChanged && !discountsChanged && !scheduleChanged && !attributesChanged && !projectChanged {
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: o})
 			return
 		}
-		if err := repo.UpdateStatus(ctx, id, to); err != nil {
+		before := *o
+		o.Items = items
+		o.Notes = notes
+		o.DiscountPercent = discountPercent
+		o.DiscountFixed = discountFixed
+		o.DueAt = dueAt
+		o.Priority = priority
+		o.DurationDays = durationDays
+		o.Tags = tags
+		o.CustomFields = fields
+		o.ProjectID = projectID
+		if contentChanged || discountsChanged {
+			if err := storage.Reprice(o, rates); err != nil {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+				return
+			}
+		}
+		if contentChanged || discountsChanged {
+			err = txRepo.UpdateEditable(ctx, *o)
+		}
+		if err == nil && scheduleChanged {
+			err = txRepo.UpdateSchedule(ctx, o.ID, dueAt, priority, durationDays)
+		}
+		if err == nil && attributesChanged {
+			err = txRepo.UpdateAttributes(ctx, o.ID, tags, fields)
+		}
+		if err == nil && projectChanged {
+			err = txRepo.SetProject(ctx, o.ID, projectID)
+		}
+		if err != nil {
+			if errors.Is(err, storage.ErrOrderNotEditable) {
+				writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_editable", Message: "order status changed, retry"}})
+				return
+			}
 			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
 			return
 		}
-		_ = storage.AddOutboxEvent(ctx, db, events.OrderStatusUpdate, map[string]any{
-			"id":     o.ID,
-			"status": to,
-		})
-		o.Status = to
+		if contentChanged {
+			payload := map[string]any{
+				"id":           o.ID,
+				"user_id":      o.UserID,
+				"changed_by":   ac.UserID,
+				"diff":         diff,
+				"total_before": before.TotalAmount,
+				"total_after":  o.TotalAmount,
+			}
+			if notes != before.Notes {
+				payload["notes_before"] = before.Notes
+				payload["notes_after"] = notes
+			}
+			_ = storage.AddOutboxEvent(ctx, tx, events.OrderItemsUpdated, payload)
+		}
+		if discountsChanged {
+			_ = storage.AddOutboxEvent(ctx, tx, events.OrderPricingUpdated, map[string]any{
+				"id":                      o.ID,
+				"user_id":                 o.UserID,
+				"changed_by":              ac.UserID,
+				"discount_percent_before": before.DiscountPercent,
+				"discount_percent_after":  o.DiscountPercent,
+				"discount_fixed_before":   before.DiscountFixed,
+				"discount_fixed_after":    o.DiscountFixed,
+				"breakdown_before":        before.Breakdown,
+				"breakdown_after":         o.Breakdown,
+			})
+		}
+		if attributesChanged {
+			_ = storage.AddOutboxEvent(ctx, tx, events.OrderAttributesUpdated, map[string]any{
+				"id":                   o.ID,
+				"user_id":              o.UserID,
+				"changed_by":           ac.UserID,
+				"tags_before":          before.Tags,
+				"tags_after":           tags,
+				"custom_fields_before": before.CustomFields,
+				"custom_fields_after":  fields,
+			})
+		}
+		if projectChanged {
+			_ = storage.AddOutboxEvent(ctx, tx, events.OrderProjectChanged, map[string]any{
+				"id":                o.ID,
+				"user_id":           o.UserID,
+				"changed_by":        ac.UserID,
+				"project_id_before": before.ProjectID,
+				"project_id_after":  projectID,
+			})
+		}
+		if scheduleChanged {
+			_ = storage.AddOutboxEvent(ctx, tx, events.OrderScheduleUpdated, map[string]any{
+				"id":              o.ID,
+				"user_id":         o.UserID,
+				"changed_by":      ac.UserID,
+				"due_at_before":   before.DueAt,
+				"due_at_after":    dueAt,
+				"priority_before": before.Priority,
+				"priority_after":  priority,
+				"duration_before": before.DurationDays,
+				"duration_after":  durationDays,
+			})
+		}
+		// Notes alone do not change what was approved.
+		if !diff.empty() || discountsChanged {
+			if err := reevaluateApproval(ctx, tx, o, ac.UserID); err != nil {
+				if errors.Is(err, storage.ErrOrderNotEditable) {
+					writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_editable", Message: "order status changed, retry"}})
+					return
+				}
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		o.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+		o.Overdue = o.IsOverdue(time.Now())
 		writeJSON(w, http.StatusOK, envelope{Success: true, Data: o})
 	}
 }
 
+// loadViewableOrder fetches an order the caller may see, mapping failures to
+// 404/403. Deleted orders exist only for admins; members of the order's
+// project see it too.
+func loadViewableOrder(ctx context.Context, q storage.DBTX, ac *AuthContext, id string) (*models.Order, int, *apiError) {
+	o, err := storage.NewOrderRepository(q).GetByID(ctx, id)
+	if err != nil || (o.DeletedAt != nil && !hasRole(ac.Roles, "admin")) {
+		return nil, http.StatusNotFound, &apiError{Code: "not_found", Message: "order not found"}
+	}
+	if !orderVisibleTo(ctx, q, o, ac) {
+		return nil, http.StatusForbidden, &apiError{Code: "forbidden", Message: "not allowed"}
+	}
+	return o, 0, nil
+}
+
+// orderVisibleTo reports whether loadViewableOrder would give o to ac.
+func orderVisibleTo(ctx context.Context, q storage.DBTX, o *models.Order, ac *AuthContext) bool {
+	if o.DeletedAt != nil && !hasRole(ac.Roles, "admin") {
+		return false
+	}
+	return canViewOrder(o, ac) || isProjectMember(ctx, q, o.ProjectID, ac.UserID) || isOrderViewer(ctx, q, o.ID, ac.UserID)
+}
+
+// loadWritableOrder is loadViewableOrder for requests that add to the order,
+// such as comments and attachments. Order viewers and viewer members of the
+// order's project only read it and get 403.
+func loadWritableOrder(ctx context.Context, q storage.DBTX, ac *AuthContext, id string) (*models.Order, int, *apiError) {
+	o, status, apiErr := loadViewableOrder(ctx, q, ac, id)
+	if apiErr != nil {
+		return nil, status, apiErr
+	}
+	var member *models.ProjectMember
+	if !canViewOrder(o, ac) && o.ProjectID != "" {
+		member, _ = storage.NewProjectRepository(q).GetMember(ctx, o.ProjectID, ac.UserID)
+	}
+	if !canWriteOrder(o, ac, member) {
+		return nil, http.StatusForbidden, &apiError{Code: "forbidden", Message: "read-only access"}
+	}
+	return o, 0, nil
+}
+
+// canWriteOrder: those who see the order by role or ownership, and members of
+// its project other than viewers. member is nil for non-members.
+func canWriteOrder(o *models.Order, ac *AuthContext, member *models.ProjectMember) bool {
+	return canViewOrder(o, ac) || (member != nil && member.Role != models.ProjectRoleViewer)
+}
+
+// checkOrderProject validates the project an order is being put into: it must
+// exist, be visible to the caller and not be finished.
+func checkOrderProject(ctx context.Context, q storage.DBTX, ac *AuthContext, projectID string) (int, *apiError) {
+	p, _, status, apiErr := loadViewableProject(ctx, q, ac, projectID)
+	if apiErr != nil {
+		if status == http.StatusNotFound {
+			return http.StatusBadRequest, &apiError{Code: "invalid_input", Message: "project not found"}
+		}
+		return status, apiErr
+	}
+	if p.Status == models.ProjectStatusCompleted || p.Status == models.ProjectStatusCancelled {
+		return http.StatusConflict, &apiError{Code: "project_closed", Message: fmt.Sprintf("project in status %s does not accept orders", p.Status)}
+	}
+	return 0, nil
+}
+
+// canViewOrder: the owner, the assignee, admins and managers.
+func canViewOrder(o *models.Order, ac *AuthContext) bool {
+	return o.UserID == ac.UserID || o.AssigneeID == ac.UserID || seesAllOrders(ac)
+}
+
+// seesAllOrders: admins and managers read every order, in lists, search and
+// export alike, since managers pick the orders to assign. Everyone else sees
+// their own, assigned, shared and project orders.
+func seesAllOrders(ac *AuthContext) bool {
+	return hasAnyRole(ac.Roles, "admin", "manager")
+}
+
+// canSetDiscounts: managers and admins.
+func canSetDiscounts(ac *AuthContext) bool {
+	return hasAnyRole(ac.Roles, "admin", "manager")
+}
+
+// normalizeItems lower-cases item categories so they match the VAT_RATES keys.
+func normalizeItems(items []models.OrderItem) []models.OrderItem {
+	for i := range items {
+		items[i].Category = strings.ToLower(strings.TrimSpace(items[i].Category))
+	}
+	return items
+}
+
+// canModifyOrder: the owner and admins.
+func canModifyOrder(o *models.Order, ac *AuthContext) bool {
+	return o.UserID == ac.UserID || hasRole(ac.Roles, "admin")
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// applyItemChanges returns the new item list and what changed. Removals are
+// applied first, then updates, then additions; items are matched by name.
+func applyItemChanges(items []models.OrderItem, req editOrderRequest) ([]models.OrderItem, itemsDiff, error) {
+	var diff itemsDiff
+	out := make([]models.OrderItem, len(items))
+	copy(out, items)
+	find := func(name string) int {
+		for i, it := range out {
+			if it.Name == name {
+				return i
+			}
+		}
+		return -1
+	}
+	for _, name := range req.Remove {
+		i := find(name)
+		if i < 0 {
+			return nil, itemsDiff{}, fmt.Errorf("item %q not found", name)
+		}
+		diff.Removed = append(diff.Removed, out[i])
+		out = append(out[:i], out[i+1:]...)
+	}
+	for _, p := range req.Update {
+		i := find(p.Name)
+		if i < 0 {
+			return nil, itemsDiff{}, fmt.Errorf("item %q not found", p.Name)
+		}
+		before := out[i]
+		if p.Quantity != nil {
+			out[i].Quantity = *p.Quantity
+		}
+		if p.Price != nil {
+			out[i].Price = *p.Price
+		}
+		if p.Category != nil {
+			out[i].Category = strings.ToLower(strings.TrimSpace(*p.Category))
+		}
+		if p.DiscountPercent != nil {
+			out[i].DiscountPercent = *p.DiscountPercent
+		}
+		if out[i] != before {
+			diff.Updated = append(diff.Updated, itemChange{Name: p.Name, Before: before, After: out[i]})
+		}
+	}
+	for _, it := range req.Add {
+		it.Name = strings.TrimSpace(it.Name)
+		it.Category = strings.ToLower(strings.TrimSpace(it.Category))
+		if it.Name == "" {
+			return nil, itemsDiff{}, errors.New("item name required")
+		}
+		if find(it.Name) >= 0 {
+			return nil, itemsDiff{}, fmt.Errorf("item %q already exists", it.Name)
+		}
+		out = append(out, it)
+		diff.Added = append(diff.Added, it)
+	}
+	if len(out) == 0 {
+		return nil, itemsDiff{}, errors.New("order must have at least one item")
+	}
+	return out, diff, nil
+}
+
 func CancelOrderHandler(db *sql.DB) http.HandlerFunc {
-	repo := storage.NewOrderRepository(db)
 	return func(w http.ResponseWriter, r *http.Request) {
 		ac := GetAuth(r)
 		if ac == nil {
@@ -201,39 +1043,200 @@ func CancelOrderHandler(db *sql.DB) http.HandlerFunc {
 		}
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
+		// Open orders are cancelled; finished ones go to the trash.
+		existing, status, apiErr := loadViewableOrder(ctx, db, ac, id)
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !existing.Open() {
+			o, status, apiErr := softDeleteOrder(ctx, db, ac, existing)
+			if apiErr != nil {
+				writeJSON(w, status, envelope{Success: false, Error: apiErr})
+				return
+			}
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: o})
+			return
+		}
+		// allowed cancel from created or in_progress
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer func() { _ = tx.Rollback() }()
+		o, status, apiErr := transitionOrder(ctx, tx, ac, id, models.OrderStatusCancelled)
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: o})
+	}
+}
+
+// softDeleteOrder moves a done or cancelled order to the trash (owner or admin)
+// and records order.deleted.
+func softDeleteOrder(ctx context.Context, db *sql.DB, ac *AuthContext, o *models.Order) (*models.Order, int, *apiError) {
+	if o.DeletedAt != nil {
+		return nil, http.StatusNotFound, &apiError{Code: "not_found", Message: "order not found"}
+	}
+	if !canModifyOrder(o, ac) {
+		return nil, http.StatusForbidden, &apiError{Code: "forbidden", Message: "not allowed"}
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	defer tx.Rollback()
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := storage.NewOrderRepository(tx).SoftDelete(ctx, o.ID, now); err != nil {
+		if errors.Is(err, storage.ErrOrderNotEditable) {
+			return nil, http.StatusConflict, &apiError{Code: "order_not_editable", Message: "order status changed, retry"}
+		}
+		return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	if err := storage.AddOutboxEvent(ctx, tx, events.OrderDeleted, map[string]any{
+		"id":         o.ID,
+		"user_id":    o.UserID,
+		"status":     o.Status,
+		"deleted_by": ac.UserID,
+	}); err != nil {
+		return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	o.DeletedAt = &now
+	o.UpdatedAt = now
+	return o, 0, nil
+}
+
+// RestoreOrderHandler takes an order out of the trash and the archive (admin).
+func RestoreOrderHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		id := chi.URLParam(r, "id")
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewOrderRepository(tx)
 		o, err := repo.GetByID(ctx, id)
 		if err != nil {
 			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "order not found"}})
 			return
 		}
-		if o.UserID != ac.UserID && !hasRole(ac.Roles, "admin") {
-			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+		now := time.Now().UTC().Truncate(time.Second)
+		ok, err := repo.Restore(ctx, id, now)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
 			return
 		}
-		// allowed cancel from created or in_progress
-		if err := validateTransition(o.Status, models.OrderStatusCancelled); err != nil {
-			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_transition", Message: err.Error()}})
+		if !ok {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "not_archived", Message: "order is neither deleted nor archived"}})
 			return
 		}
-		if err := repo.Cancel(ctx, id); err != nil {
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderRestored, map[string]any{
+			"id":          o.ID,
+			"user_id":     o.UserID,
+			"restored_by": ac.UserID,
+			"was_deleted": o.DeletedAt != nil,
+		}); err != nil {
 			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
 			return
 		}
-		_ = storage.AddOutboxEvent(ctx, db, events.OrderStatusUpdate, map[string]any{
-			"id":     o.ID,
-			"status": models.OrderStatusCancelled,
-		})
-		o.Status = models.OrderStatusCancelled
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		o.ArchivedAt, o.DeletedAt = nil, nil
+		o.UpdatedAt = now
 		writeJSON(w, http.StatusOK, envelope{Success: true, Data: o})
 	}
 }
 
+// transitionOrder loads the order, checks ownership and transition rules, then
+// updates the status and records the event. q should be a transaction so the
+// checks, history and event commit together with the status. On failure it
+// returns the HTTP status and error to report.
+func transitionOrder(ctx context.Context, q storage.DBTX, ac *AuthContext, id string, to models.OrderStatus) (*models.Order, int, *apiError) {
+	repo := storage.NewOrderRepository(q)
+	o, err := repo.GetByID(ctx, id)
+	if err != nil || o.DeletedAt != nil {
+		return nil, http.StatusNotFound, &apiError{Code: "not_found", Message: "order not found"}
+	}
+	// The assignee moves the work forward but cannot cancel someone else's order.
+	if !canModifyOrder(o, ac) && (o.AssigneeID != ac.UserID || to == models.OrderStatusCancelled) {
+		return nil, http.StatusForbidden, &apiError{Code: "forbidden", Message: "not allowed"}
+	}
+	if err := validateTransition(o.Status, to); err != nil {
+		return nil, http.StatusBadRequest, &apiError{Code: "invalid_transition", Message: err.Error()}
+	}
+	if to == models.OrderStatusInProgress {
+		pending, err := storage.NewOrderDependencyRepository(q).PendingPredecessors(ctx, id)
+		if err != nil {
+			return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+		}
+		if len(pending) > 0 {
+			return nil, http.StatusConflict, &apiError{Code: "predecessors_not_done", Message: "waiting for predecessors: " + strings.Join(pending, ", ")}
+		}
+	}
+	if to == models.OrderStatusDone {
+		open, err := storage.NewChecklistRepository(q).OpenMandatoryItems(ctx, id)
+		if err != nil {
+			return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+		}
+		if open > 0 {
+			return nil, http.StatusConflict, &apiError{Code: "checklist_incomplete", Message: fmt.Sprintf("%d mandatory checklist items are not completed", open)}
+		}
+	}
+	if o.Status == models.OrderStatusPendingApproval {
+		if err := storage.NewApprovalRepository(q).CancelPending(ctx, id); err != nil {
+			return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+		}
+	}
+	if err := repo.UpdateStatus(ctx, id, o.Status, to); err != nil {
+		if errors.Is(err, storage.ErrOrderNotEditable) {
+			return nil, http.StatusConflict, &apiError{Code: "order_not_editable", Message: "order status changed, retry"}
+		}
+		return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	if err := repo.AddStatusHistory(ctx, id, o.Status, to, ac.UserID); err != nil {
+		return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	if err := storage.AddOutboxEvent(ctx, q, events.OrderStatusUpdate, map[string]any{
+		"id":     o.ID,
+		"status": to,
+	}); err != nil {
+		return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	o.Status = to
+	return o, 0, nil
+}
+
 func validateTransition(from, to models.OrderStatus) error {
 	switch from {
 	case models.OrderStatusCreated:
 		if to == models.OrderStatusInProgress || to == models.OrderStatusCancelled {
 			return nil
 		}
+	case models.OrderStatusPendingApproval:
+		// Approvers move it on; the owner may only withdraw it.
+		if to == models.OrderStatusCancelled {
+			return nil
+		}
 	case models.OrderStatusInProgress:
 		if to == models.OrderStatusDone || to == models.OrderStatusCancelled {
 			return nil
@@ -0,0 +1,114 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+type assignOrderRequest struct {
+	AssigneeID string `json:"assignee_id"`
+}
+
+// AssignOrderHandler sets the executor of an open order (managers and admins).
+func AssignOrderHandler(db *sql.DB) http.HandlerFunc {
+	users := storage.NewUserRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req assignOrderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.AssigneeID) == "" {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "assignee_id required"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		assignee, err := users.GetByID(ctx, strings.TrimSpace(req.AssigneeID))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "assignee not found"}})
+			return
+		}
+		o, status, apiErr := setAssignee(ctx, db, ac, chi.URLParam(r, "id"), assignee.ID)
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: o})
+	}
+}
+
+// UnassignOrderHandler clears the executor of an open order.
+func UnassignOrderHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := setAssignee(ctx, db, ac, chi.URLParam(r, "id"), "")
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: o})
+	}
+}
+
+// setAssignee updates the assignee and records order.assigned/order.unassigned
+// in one transaction. Re-assigning the same user is a no-op without an event.
+func setAssignee(ctx context.Context, db *sql.DB, ac *AuthContext, id, assigneeID string) (*models.Order, int, *apiError) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	defer tx.Rollback()
+	repo := storage.NewOrderRepository(tx)
+	o, err := repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, http.StatusNotFound, &apiError{Code: "not_found", Message: "order not found"}
+	}
+	if o.AssigneeID == assigneeID {
+		return o, 0, nil
+	}
+	if err := repo.Assign(ctx, id, assigneeID); err != nil {
+		if errors.Is(err, storage.ErrOrderNotEditable) {
+			return nil, http.StatusConflict, &apiError{Code: "order_not_editable", Message: "only open orders can be assigned"}
+		}
+		return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	eventType, payload := events.OrderAssigned, map[string]any{
+		"id":                   o.ID,
+		"user_id":              o.UserID,
+		"assignee_id":          assigneeID,
+		"previous_assignee_id": o.AssigneeID,
+		"assigned_by":          ac.UserID,
+	}
+	if assigneeID == "" {
+		eventType = events.OrderUnassigned
+		delete(payload, "assignee_id")
+	}
+	if err := storage.AddOutboxEvent(ctx, tx, eventType, payload); err != nil {
+		return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	o.AssigneeID = assigneeID
+	o.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	return o, 0, nil
+}
@@ -0,0 +1,160 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const maxBulkOrders = 100
+
+type bulkOrdersRequest struct {
+	IDs    []string `json:"ids"`
+	Status string   `json:"status"`
+	// Mode is "per_item" (default): each order is changed on its own, or
+	// "atomic": all orders change in one transaction or none do.
+	Mode string `json:"mode"`
+}
+
+type bulkOrderResult struct {
+	ID      string             `json:"id"`
+	Success bool               `json:"success"`
+	Status  models.OrderStatus `json:"status,omitempty"`
+	Error   *apiError          `json:"error,omitempty"`
+}
+
+func BulkOrdersHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req bulkOrdersRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		ids := dedupeIDs(req.IDs)
+		if len(ids) == 0 || len(ids) > maxBulkOrders {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "ids must contain 1 to 100 order ids"}})
+			return
+		}
+		to := models.OrderStatus(strings.TrimSpace(req.Status))
+		if to != models.OrderStatusInProgress && to != models.OrderStatusDone && to != models.OrderStatusCancelled {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "unsupported status"}})
+			return
+		}
+		mode := strings.TrimSpace(req.Mode)
+		if mode == "" {
+			mode = "per_item"
+		}
+		if mode != "per_item" && mode != "atomic" {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "mode must be per_item or atomic"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+		defer cancel()
+
+		results := make([]bulkOrderResult, 0, len(ids))
+		if mode == "per_item" {
+			for _, id := range ids {
+				results = append(results, bulkTransitionTx(ctx, db, ac, id, to))
+			}
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: bulkSummary(mode, results)})
+			return
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer func() { _ = tx.Rollback() }()
+		failed := false
+		for _, id := range ids {
+			res := bulkTransition(ctx, tx, ac, id, to)
+			failed = failed || !res.Success
+			results = append(results, res)
+		}
+		if !failed {
+			if err := tx.Commit(); err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: bulkSummary(mode, results)})
+			return
+		}
+		// Nothing was applied: report the orders that would have succeeded as rolled back.
+		for i := range results {
+			if results[i].Success {
+				results[i] = bulkOrderResult{ID: results[i].ID, Error: &apiError{Code: "rolled_back", Message: "not applied because another order failed"}}
+			}
+		}
+		writeJSON(w, http.StatusConflict, envelope{
+			Success: false,
+			Data:    bulkSummary(mode, results),
+			Error:   &apiError{Code: "bulk_failed", Message: "no orders were changed"},
+		})
+	}
+}
+
+func bulkTransition(ctx context.Context, q storage.DBTX, ac *AuthContext, id string, to models.OrderStatus) bulkOrderResult {
+	o, _, apiErr := transitionOrder(ctx, q, ac, id, to)
+	if apiErr != nil {
+		return bulkOrderResult{ID: id, Error: apiErr}
+	}
+	return bulkOrderResult{ID: id, Success: true, Status: o.Status}
+}
+
+// bulkTransitionTx runs one per_item transition in its own transaction, so
+// an order either changes with its history and event or not at all.
+func bulkTransitionTx(ctx context.Context, db *sql.DB, ac *AuthContext, id string, to models.OrderStatus) bulkOrderResult {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return bulkOrderResult{ID: id, Error: &apiError{Code: "internal_error", Message: "db error"}}
+	}
+	defer func() { _ = tx.Rollback() }()
+	res := bulkTransition(ctx, tx, ac, id, to)
+	if res.Success {
+		if err := tx.Commit(); err != nil {
+			return bulkOrderResult{ID: id, Error: &apiError{Code: "internal_error", Message: "db error"}}
+		}
+	}
+	return res
+}
+
+func bulkSummary(mode string, results []bulkOrderResult) map[string]any {
+	succeeded := 0
+	for _, res := range results {
+		if res.Success {
+			succeeded++
+		}
+	}
+	return map[string]any{
+		"mode":      mode,
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	}
+}
+
+func dedupeIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
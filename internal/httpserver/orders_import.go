@@ -0,0 +1,309 @@
+package httpserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	maxImportBytes = 2 << 20
+	maxImportRows  = 1000
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// importColumns maps accepted header names (English or Russian) to fields.
+var importColumns = map[string]string{
+	"order_ref":  "order_ref",
+	"order":      "order_ref",
+	"заказ":      "order_ref",
+	"name":       "name",
+	"item":       "name",
+	"позиция":    "name",
+	"quantity":   "quantity",
+	"qty":        "quantity",
+	"количество": "quantity",
+	"price":      "price",
+	"цена":       "price",
+	"notes":      "notes",
+	"примечание": "notes",
+	"category":   "category",
+	"категория":  "category",
+}
+
+type importRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+type importedOrder struct {
+	Ref    string             `json:"ref"`
+	Rows   []int              `json:"rows"`
+	Items  []models.OrderItem `json:"items"`
+	Notes  string             `json:"notes,omitempty"`
+	Total  float64            `json:"total_amount"`
+	ID     string             `json:"id,omitempty"`
+	Status models.OrderStatus `json:"status,omitempty"`
+}
+
+// parseOrderImport reads order lines from CSV. The first line is a header;
+// both "," and ";" separators are accepted. Lines sharing order_ref form one
+// order; without that column the whole file is a single order. Row numbers
+// in errors are 1-based file lines.
+func parseOrderImport(r io.Reader) ([]*importedOrder, []importRowError, error) {
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, utf8BOM) {
+		_, _ = br.Discard(3)
+	}
+	head, err := br.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, nil, err
+	}
+	if len(bytes.TrimSpace(head)) == 0 {
+		return nil, nil, errors.New("file is empty")
+	}
+	firstLine := head
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		firstLine = head[:i]
+	}
+	cr := csv.NewReader(br)
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("read header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		if f, ok := importColumns[strings.ToLower(strings.TrimSpace(h))]; ok {
+			cols[f] = i
+		}
+	}
+	for _, f := range []string{"name", "quantity", "price"} {
+		if _, ok := cols[f]; !ok {
+			return nil, nil, fmt.Errorf("missing required column %q", f)
+		}
+	}
+	field := func(rec []string, name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	var (
+		orders []*importedOrder
+		byRef  = map[string]*importedOrder{}
+		errs   []importRowError
+	)
+	for n := 1; ; n++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if !errors.As(err, &pe) {
+				return nil, nil, err
+			}
+			errs = append(errs, importRowError{Row: pe.StartLine, Message: pe.Err.Error()})
+			continue
+		}
+		if n > maxImportRows {
+			return nil, nil, fmt.Errorf("too many rows, max %d", maxImportRows)
+		}
+		// Quoted fields may span lines, so ask the reader where the record starts.
+		rowNum, _ := cr.FieldPos(0)
+		if strings.TrimSpace(strings.Join(rec, "")) == "" {
+			continue
+		}
+		ref := field(rec, "order_ref")
+		if _, ok := cols["order_ref"]; !ok {
+			ref = "1"
+		}
+		if ref == "" {
+			errs = append(errs, importRowError{Row: rowNum, Column: "order_ref", Message: "order_ref required"})
+			continue
+		}
+		item := models.OrderItem{Name: field(rec, "name"), Category: strings.ToLower(field(rec, "category"))}
+		if item.Name == "" {
+			errs = append(errs, importRowError{Row: rowNum, Column: "name", Message: "name required"})
+			continue
+		}
+		qty, err := strconv.Atoi(field(rec, "quantity"))
+		if err != nil {
+			errs = append(errs, importRowError{Row: rowNum, Column: "quantity", Message: "quantity must be an integer"})
+			continue
+		}
+		item.Quantity = qty
+		price, err := strconv.ParseFloat(strings.Replace(field(rec, "price"), ",", ".", 1), 64)
+		if err != nil {
+			errs = append(errs, importRowError{Row: rowNum, Column: "price", Message: "price must be a number"})
+			continue
+		}
+		item.Price = price
+		// Same rules as order creation.
+		if _, err := storage.CalculateTotal([]models.OrderItem{item}); err != nil {
+			errs = append(errs, importRowError{Row: rowNum, Message: "quantity must be positive and price a finite non-negative number"})
+			continue
+		}
+		o := byRef[ref]
+		if o == nil {
+			o = &importedOrder{Ref: ref}
+			byRef[ref] = o
+			orders = append(orders, o)
+		}
+		dup := false
+		for _, it := range o.Items {
+			if it.Name == item.Name {
+				dup = true
+				break
+			}
+		}
+		if dup {
+			errs = append(errs, importRowError{Row: rowNum, Column: "name", Message: fmt.Sprintf("duplicate item %q in order %s", item.Name, ref)})
+			continue
+		}
+		o.Items = append(o.Items, item)
+		o.Rows = append(o.Rows, rowNum)
+		if o.Notes == "" {
+			o.Notes = field(rec, "notes")
+		}
+	}
+	for _, o := range orders {
+		if o.Total, err = storage.CalculateTotal(o.Items); err != nil {
+			errs = append(errs, importRowError{Row: o.Rows[0], Message: fmt.Sprintf("order %s: %v", o.Ref, err)})
+		}
+	}
+	if len(orders) == 0 && len(errs) == 0 {
+		return nil, nil, errors.New("file has no order lines")
+	}
+	return orders, errs, nil
+}
+
+func ImportOrdersHandler(db *sql.DB, rates storage.TaxRates) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		dryRun := r.URL.Query().Get("dry_run") == "true"
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+		var src io.Reader = r.Body
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			f, _, err := r.FormFile("file")
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "multipart field \"file\" required"}})
+				return
+			}
+			defer f.Close()
+			src = f
+		}
+		orders, rowErrs, err := parseOrderImport(src)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		if orders == nil {
+			orders = []*importedOrder{}
+		}
+		for _, o := range orders {
+			if b, err := storage.PriceOrder(o.Items, 0, 0, nil, rates); err == nil {
+				o.Total = b.Total
+			}
+		}
+		if rowErrs == nil {
+			rowErrs = []importRowError{}
+		}
+		report := map[string]any{
+			"dry_run": dryRun,
+			"orders":  orders,
+			"errors":  rowErrs,
+		}
+		if len(rowErrs) > 0 {
+			writeJSON(w, http.StatusUnprocessableEntity, envelope{Success: false, Data: report, Error: &apiError{Code: "invalid_rows", Message: "some rows are invalid, nothing was imported"}})
+			return
+		}
+		if dryRun {
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: report})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+		defer cancel()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer func() { _ = tx.Rollback() }()
+		repo := storage.NewOrderRepository(tx)
+		for _, imp := range orders {
+			order, err := storage.NewOrder(ac.UserID, imp.Items)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid order items"}})
+				return
+			}
+			order.Notes = imp.Notes
+			if err := storage.Reprice(&order, rates); err != nil {
+				writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid order items"}})
+				return
+			}
+			steps, err := orderApprovalSteps(ctx, tx, order)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			if len(steps) > 0 {
+				order.Status = models.OrderStatusPendingApproval
+			}
+			if err := repo.Create(ctx, order); err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			if err := storage.AddOutboxEvent(ctx, tx, events.OrderCreated, map[string]any{
+				"id":      order.ID,
+				"user_id": order.UserID,
+				"status":  order.Status,
+				"total":   order.TotalAmount,
+				"source":  "import",
+			}); err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			if len(steps) > 0 {
+				if err := requestApproval(ctx, tx, &order, steps, ac.UserID); err != nil {
+					writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+					return
+				}
+			}
+			imp.ID, imp.Status = order.ID, order.Status
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: report})
+	}
+}
@@ -0,0 +1,91 @@
+package httpserver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOrderImport(t *testing.T) {
+	csvData := "\xEF\xBB\xBForder_ref;name;quantity;price;notes\n" +
+		"A;rebar;10;2,5;block C\n" +
+		"A;cement;2;30;\n" +
+		"B;sand;1;15;\n"
+	orders, errs, err := parseOrderImport(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(errs) != 0 {
+		t.Fatalf("unexpected row errors: %+v", errs)
+	}
+	if len(orders) != 2 || orders[0].Ref != "A" || len(orders[0].Items) != 2 || orders[0].Total != 85 || orders[0].Notes != "block C" {
+		t.Fatalf("unexpected orders: %+v", orders[0])
+	}
+}
+
+func TestParseOrderImport_RowErrors(t *testing.T) {
+	csvData := "name,quantity,price\n" +
+		"rebar,10,2\n" +
+		",1,1\n" +
+		"sand,zero,1\n" +
+		"gravel,0,1\n" +
+		"rebar,1,1\n"
+	orders, errs, err := parseOrderImport(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orders) != 1 || orders[0].Ref != "1" {
+		t.Fatalf("expected a single implicit order, got %+v", orders)
+	}
+	wantRows := []int{3, 4, 5, 6}
+	if len(errs) != len(wantRows) {
+		t.Fatalf("want %d row errors, got %+v", len(wantRows), errs)
+	}
+	for i, row := range wantRows {
+		if errs[i].Row != row {
+			t.Fatalf("error %d: want row %d, got %+v", i, row, errs[i])
+		}
+	}
+}
+
+func TestParseOrderImport_MissingColumn(t *testing.T) {
+	if _, _, err := parseOrderImport(strings.NewReader("name,price\nrebar,1\n")); err == nil {
+		t.Fatalf("expected error for missing quantity column")
+	}
+}
+
+func TestParseOrderImport_NonFinitePrices(t *testing.T) {
+	csvData := "order_ref,name,quantity,price\n" +
+		"A,rebar,1,NaN\n" +
+		"A,sand,1,Inf\n" +
+		"A,cement,2,1e308\n" +
+		"B,gravel,1,1e308\n" +
+		"B,stone,1,1e308\n"
+	orders, errs, err := parseOrderImport(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// B overflows only once its lines are added up; that is reported on its first line.
+	wantRows := []int{2, 3, 4, 5}
+	if len(errs) != len(wantRows) {
+		t.Fatalf("want %d row errors, got %+v (orders %+v)", len(wantRows), errs, orders)
+	}
+	for i, row := range wantRows {
+		if errs[i].Row != row {
+			t.Fatalf("error %d: want row %d, got %+v", i, row, errs[i])
+		}
+	}
+}
+
+func TestParseOrderImport_RowsAreFileLines(t *testing.T) {
+	csvData := "name,quantity,price,notes\n" +
+		"rebar,1,1,\"two\nlines\"\n" +
+		"\n" +
+		"sand,zero,1,\n"
+	_, errs, err := parseOrderImport(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(errs) != 1 || errs[0].Row != 5 {
+		t.Fatalf("want the error on line 5, got %+v", errs)
+	}
+}
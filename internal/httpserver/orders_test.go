@@ -1,7 +1,9 @@
 package httpserver
 
 import (
+	"net/url"
 	"testing"
+	"time"
 
 	"frame_control_system/internal/models"
 )
@@ -16,6 +18,9 @@ func TestValidateTransition(t *testing.T) {
 		{models.OrderStatusCreated, models.OrderStatusCancelled, true},
 		{models.OrderStatusInProgress, models.OrderStatusDone, true},
 		{models.OrderStatusInProgress, models.OrderStatusCancelled, true},
+		{models.OrderStatusPendingApproval, models.OrderStatusCancelled, true},
+		{models.OrderStatusPendingApproval, models.OrderStatusInProgress, false},
+		{models.OrderStatusPendingApproval, models.OrderStatusCreated, false},
 		{models.OrderStatusDone, models.OrderStatusCancelled, false},
 		{models.OrderStatusCancelled, models.OrderStatusDone, false},
 	}
@@ -30,4 +35,180 @@ func TestValidateTransition(t *testing.T) {
 	}
 }
 
+func TestApplyItemChanges(t *testing.T) {
+	items := []models.OrderItem{
+		{Name: "rebar", Quantity: 10, Price: 5},
+		{Name: "cement", Quantity: 2, Price: 30},
+	}
+	qty := 12
+	req := editOrderRequest{
+		Add:    []models.OrderItem{{Name: "sand", Quantity: 1, Price: 15}},
+		Update: []itemPatch{{Name: "rebar", Quantity: &qty}},
+		Remove: []string{"cement"},
+	}
+	out, diff, err := applyItemChanges(items, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out) != 2 || out[0].Name != "rebar" || out[0].Quantity != 12 || out[1].Name != "sand" {
+		t.Fatalf("unexpected items: %+v", out)
+	}
+	if len(diff.Added) != 1 || len(diff.Removed) != 1 || len(diff.Updated) != 1 {
+		t.Fatalf("unexpected diff: %+v", diff)
+	}
+	if items[0].Quantity != 10 {
+		t.Fatalf("input items must not be modified")
+	}
+
+	if _, _, err := applyItemChanges(items, editOrderRequest{Remove: []string{"missing"}}); err == nil {
+		t.Fatalf("expected error for unknown item")
+	}
+	if _, _, err := applyItemChanges(items, editOrderRequest{Add: []models.OrderItem{{Name: "rebar", Quantity: 1}}}); err == nil {
+		t.Fatalf("expected error for duplicate item")
+	}
+	if _, _, err := applyItemChanges(items, editOrderRequest{Remove: []string{"rebar", "cement"}}); err == nil {
+		t.Fatalf("expected error for empty order")
+	}
+}
+
+func TestParseOrderFilters(t *testing.T) {
+	ac := &AuthContext{UserID: "u1", Roles: []string{"admin"}}
+	q := url.Values{}
+	q.Set("status", "created,in_progress")
+	q.Set("created_from", "2025-01-01")
+	q.Set("created_to", "2025-01-31")
+	q.Set("min_total", "10")
+	q.Set("max_total", "100.5")
+	q.Set("item", "rebar")
+	q.Set("sort", "total_desc")
+	p, err := parseOrderFilters(q, ac)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Statuses) != 2 || !p.AdminView || p.ItemName != "rebar" || *p.MaxTotal != 100.5 {
+		t.Fatalf("unexpected params: %+v", p)
+	}
+	if want := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC); !p.CreatedTo.Equal(want) {
+		t.Fatalf("created_to should be exclusive end of day, got %v", p.CreatedTo)
+	}
+
+	// Unknown status and sort values are tolerated as before the new filters.
+	p, err = parseOrderFilters(url.Values{"status": {"archived"}, "sort": {"name_asc"}}, ac)
+	if err != nil || len(p.Statuses) != 1 || p.Sort != "" || !p.KeysetSort() {
+		t.Fatalf("status and sort must stay lenient: %+v, %v", p, err)
+	}
+
+	bad := []url.Values{
+		{"created_from": {"yesterday"}},
+		{"min_total": {"-1"}},
+		{"min_total": {"50"}, "max_total": {"10"}},
+		{"updated_from": {"2025-02-01"}, "updated_to": {"2025-01-01"}},
+		{"priority": {"critical"}},
+		{"overdue": {"maybe"}},
+		{"due_within": {"-2h"}},
+		{"due_within": {"soon"}},
+	}
+	for _, q := range bad {
+		if _, err := parseOrderFilters(q, ac); err == nil {
+			t.Fatalf("expected error for %v", q)
+		}
+	}
+}
+
+func TestParseOrderFiltersDue(t *testing.T) {
+	ac := &AuthContext{UserID: "u1"}
+	q := url.Values{"priority": {"high,urgent"}, "overdue": {"true"}, "due_within": {"2d"}, "sort": {"due_asc"}}
+	p, err := parseOrderFilters(q, ac)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Priorities) != 2 || !p.Overdue || p.DueBefore == nil {
+		t.Fatalf("unexpected params: %+v", p)
+	}
+	if d := time.Until(*p.DueBefore); d < 47*time.Hour || d > 48*time.Hour {
+		t.Fatalf("due_within=2d should end in 48h, got %v", d)
+	}
+}
 
+func TestOrderIsOverdue(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	cases := []struct {
+		o    models.Order
+		want bool
+	}{
+		{models.Order{Status: models.OrderStatusCreated}, false},
+		{models.Order{Status: models.OrderStatusInProgress, DueAt: &past}, true},
+		{models.Order{Status: models.OrderStatusDone, DueAt: &past}, false},
+		{models.Order{Status: models.OrderStatusCreated, DueAt: &now}, false},
+	}
+	for i, c := range cases {
+		if got := c.o.IsOverdue(now); got != c.want {
+			t.Fatalf("case %d: got %v, want %v", i, got, c.want)
+		}
+	}
+}
+
+func TestParseOrderFiltersAssignee(t *testing.T) {
+	user := &AuthContext{UserID: "u1", Roles: []string{"user"}}
+	p, err := parseOrderFilters(url.Values{"assignee": {"me"}}, user)
+	if err != nil || p.AssigneeID != "u1" || p.AdminView {
+		t.Fatalf("unexpected params: %+v, %v", p, err)
+	}
+	if apiErr := checkListScope(p, user); apiErr != nil {
+		t.Fatalf("assignee=me should be allowed: %v", apiErr.Message)
+	}
+	p, _ = parseOrderFilters(url.Values{"assignee": {"u2"}}, user)
+	if checkListScope(p, user) == nil {
+		t.Fatal("expected forbidden for another user's assignments")
+	}
+	mgr := &AuthContext{UserID: "m1", Roles: []string{"user", "manager"}}
+	p, _ = parseOrderFilters(url.Values{"assignee": {"none"}, "user_id": {"u2"}}, mgr)
+	if !p.Unassigned || !p.AdminView || checkListScope(p, mgr) != nil {
+		t.Fatalf("manager should see unassigned orders of any owner: %+v", p)
+	}
+}
+
+func TestSeesAllOrders(t *testing.T) {
+	o := &models.Order{UserID: "owner"}
+	for _, c := range []struct {
+		roles []string
+		want  bool
+	}{
+		{[]string{"admin"}, true},
+		{[]string{"user", "manager"}, true},
+		{[]string{"user"}, false},
+		{[]string{"executive"}, false},
+	} {
+		ac := &AuthContext{UserID: "x", Roles: c.roles}
+		p, _ := parseOrderFilters(url.Values{}, ac)
+		// Lists, search and single-order reads must agree.
+		if seesAllOrders(ac) != c.want || p.AdminView != c.want || canViewOrder(o, ac) != c.want {
+			t.Errorf("%v: want %v", c.roles, c.want)
+		}
+	}
+}
+
+func TestParseOrderFiltersArchive(t *testing.T) {
+	mgr := &AuthContext{UserID: "m1", Roles: []string{"user", "manager"}}
+	p, err := parseOrderFilters(url.Values{}, mgr)
+	if err != nil || p.IncludeArchived || p.Deleted {
+		t.Fatalf("archived and deleted orders should be hidden by default: %+v, %v", p, err)
+	}
+	p, err = parseOrderFilters(url.Values{"include_archived": {"true"}}, mgr)
+	if err != nil || !p.IncludeArchived {
+		t.Fatalf("include_archived not parsed: %+v, %v", p, err)
+	}
+	if _, err := parseOrderFilters(url.Values{"include_archived": {"yes"}}, mgr); err == nil {
+		t.Fatal("expected error for include_archived=yes")
+	}
+	p, _ = parseOrderFilters(url.Values{"deleted": {"true"}}, mgr)
+	if checkListScope(p, mgr) == nil {
+		t.Fatal("the trash should be admin-only")
+	}
+	admin := &AuthContext{UserID: "a1", Roles: []string{"user", "admin"}}
+	p, _ = parseOrderFilters(url.Values{"deleted": {"true"}}, admin)
+	if !p.Deleted || checkListScope(p, admin) != nil {
+		t.Fatalf("admin should see the trash: %+v", p)
+	}
+}
@@ -0,0 +1,99 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"frame_control_system/internal/storage"
+)
+
+// pageRequest holds the pagination parameters shared by list endpoints. Clients
+// either use page/limit (offset pagination) or pass the opaque cursor returned
+// as next_cursor (keyset pagination over created_at, id).
+type pageRequest struct {
+	Limit        int
+	Page         int
+	Offset       int
+	After        *storage.Cursor
+	IncludeTotal bool
+}
+
+func parsePageRequest(q url.Values) (pageRequest, error) {
+	p := pageRequest{
+		Limit:        parseIntDefault(q.Get("limit"), 20, 1, 100),
+		Page:         parseIntDefault(q.Get("page"), 1, 1, 100000),
+		IncludeTotal: q.Get("include_total") == "true",
+	}
+	p.Offset = (p.Page - 1) * p.Limit
+	if s := strings.TrimSpace(q.Get("cursor")); s != "" {
+		c, err := storage.DecodeCursor(s)
+		if err != nil {
+			return pageRequest{}, err
+		}
+		p.After = &c
+		p.Page = 0
+		p.Offset = 0
+	}
+	return p, nil
+}
+
+// meta builds the pagination part of a list response. more tells whether a
+// next page exists, also for sorts that only page by offset.
+func (p pageRequest) meta(nextCursor string, more bool, total *int) map[string]any {
+	m := map[string]any{"limit": p.Limit, "has_more": more}
+	if p.After == nil {
+		m["page"] = p.Page
+	}
+	if nextCursor != "" {
+		m["next_cursor"] = nextCursor
+	}
+	if total != nil {
+		m["total"] = *total
+	}
+	return m
+}
+
+// setNextLink advertises the next page via an RFC 8288 Link header.
+func setNextLink(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	if nextCursor == "" {
+		return
+	}
+	q := r.URL.Query()
+	q.Del("page")
+	q.Set("cursor", nextCursor)
+	u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	w.Header().Set("Link", "<"+u.String()+">; rel=\"next\"")
+}
+
+// setNextPageLink is setNextLink for sorts without a cursor: it points at the
+// following page number.
+func setNextPageLink(w http.ResponseWriter, r *http.Request, page int) {
+	q := r.URL.Query()
+	q.Set("page", strconv.Itoa(page+1))
+	u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	w.Header().Set("Link", "<"+u.String()+">; rel=\"next\"")
+}
+
+// cursorSort names the sort a cursor continues; "" is the default created_desc.
+func cursorSort(sort string) string {
+	if sort == "" {
+		return "created_desc"
+	}
+	return sort
+}
+
+// checkCursorSort rejects a cursor that was issued for another sort order.
+func checkCursorSort(pg pageRequest, sort string, keyset bool) *apiError {
+	if pg.After == nil {
+		return nil
+	}
+	if !keyset {
+		return &apiError{Code: "invalid_input", Message: "cursor requires created_asc or created_desc sort"}
+	}
+	if pg.After.Sort != cursorSort(sort) {
+		return &apiError{Code: "invalid_input", Message: "cursor was issued for a different sort"}
+	}
+	return nil
+}
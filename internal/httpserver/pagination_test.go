@@ -0,0 +1,47 @@
+package httpserver
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"frame_control_system/internal/storage"
+)
+
+func TestCheckCursorSort(t *testing.T) {
+	cursor := func(sort string) pageRequest {
+		q := url.Values{"cursor": {storage.EncodeCursor(storage.Cursor{ID: "o1", Sort: sort})}}
+		pg, err := parsePageRequest(q)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		return pg
+	}
+	cases := []struct {
+		pg     pageRequest
+		sort   string
+		keyset bool
+		ok     bool
+	}{
+		{pageRequest{}, "total_desc", false, true},
+		{cursor("created_desc"), "", true, true},
+		{cursor("created_asc"), "created_asc", true, true},
+		{cursor("created_asc"), "created_desc", true, false},
+		{cursor(""), "", true, false},
+		{cursor("created_desc"), "total_desc", false, false},
+	}
+	for i, c := range cases {
+		if got := checkCursorSort(c.pg, c.sort, c.keyset) == nil; got != c.ok {
+			t.Errorf("case %d: got ok=%v, want %v", i, got, c.ok)
+		}
+	}
+}
+
+func TestSetNextPageLink(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/orders?sort=total_desc&page=2&limit=5", nil)
+	w := httptest.NewRecorder()
+	setNextPageLink(w, r, 2)
+	if got, want := w.Header().Get("Link"), `</api/v1/orders?limit=5&page=3&sort=total_desc>; rel="next"`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
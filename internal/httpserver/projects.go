@@ -0,0 +1,584 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	maxProjectNameLength  = 200
+	maxProjectFieldLength = 500
+)
+
+type createProjectRequest struct {
+	Name      string               `json:"name"`
+	Address   string               `json:"address"`
+	Customer  string               `json:"customer"`
+	StartDate string               `json:"start_date"`
+	EndDate   string               `json:"end_date"`
+	Status    models.ProjectStatus `json:"status"`
+}
+
+// updateProjectRequest changes a project; omitted fields are kept and an
+// empty date clears it.
+type updateProjectRequest struct {
+	Name      *string               `json:"name"`
+	Address   *string               `json:"address"`
+	Customer  *string               `json:"customer"`
+	StartDate *string               `json:"start_date"`
+	EndDate   *string               `json:"end_date"`
+	Status    *models.ProjectStatus `json:"status"`
+}
+
+type projectMemberRequest struct {
+	Role models.ProjectRole `json:"role"`
+}
+
+func validateProject(p *models.Project) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Address = strings.TrimSpace(p.Address)
+	p.Customer = strings.TrimSpace(p.Customer)
+	p.StartDate = strings.TrimSpace(p.StartDate)
+	p.EndDate = strings.TrimSpace(p.EndDate)
+	if p.Name == "" || utf8.RuneCountInString(p.Name) > maxProjectNameLength {
+		return fmt.Errorf("name must be 1-%d characters", maxProjectNameLength)
+	}
+	if utf8.RuneCountInString(p.Address) > maxProjectFieldLength || utf8.RuneCountInString(p.Customer) > maxProjectFieldLength {
+		return fmt.Errorf("address and customer must be at most %d characters", maxProjectFieldLength)
+	}
+	if !p.Status.Valid() {
+		return errors.New("status must be one of planned, active, on_hold, completed, cancelled")
+	}
+	var start, end time.Time
+	var err error
+	if p.StartDate != "" {
+		if start, err = time.Parse("2006-01-02", p.StartDate); err != nil {
+			return errors.New("start_date must be a date YYYY-MM-DD")
+		}
+	}
+	if p.EndDate != "" {
+		if end, err = time.Parse("2006-01-02", p.EndDate); err != nil {
+			return errors.New("end_date must be a date YYYY-MM-DD")
+		}
+	}
+	if p.StartDate != "" && p.EndDate != "" && end.Before(start) {
+		return errors.New("end_date must not be before start_date")
+	}
+	return nil
+}
+
+// loadViewableProject fetches a project the caller may see together with the
+// caller's membership (nil for non-members). Admins and managers see every
+// project, other users only those they are members of.
+func loadViewableProject(ctx context.Context, q storage.DBTX, ac *AuthContext, id string) (*models.Project, *models.ProjectMember, int, *apiError) {
+	repo := storage.NewProjectRepository(q)
+	p, err := repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, http.StatusNotFound, &apiError{Code: "not_found", Message: "project not found"}
+	}
+	m, err := repo.GetMember(ctx, id, ac.UserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, http.StatusInternalServerError, &apiError{Code: "internal_error", Message: "db error"}
+	}
+	if m == nil && !hasAnyRole(ac.Roles, "admin", "manager") {
+		return nil, nil, http.StatusForbidden, &apiError{Code: "forbidden", Message: "not allowed"}
+	}
+	return p, m, 0, nil
+}
+
+// canManageProject: admins, managers and the project's own managers.
+func canManageProject(ac *AuthContext, m *models.ProjectMember) bool {
+	return hasAnyRole(ac.Roles, "admin", "manager") || (m != nil && m.Role == models.ProjectRoleManager)
+}
+
+// isProjectMember reports whether the user is a member of the project; lookup
+// errors count as not a member.
+func isProjectMember(ctx context.Context, q storage.DBTX, projectID, userID string) bool {
+	if projectID == "" {
+		return false
+	}
+	_, err := storage.NewProjectRepository(q).GetMember(ctx, projectID, userID)
+	return err == nil
+}
+
+func ListProjectsHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewProjectRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		q := r.URL.Query()
+		pg, err := parsePageRequest(q)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid cursor"}})
+			return
+		}
+		params := storage.ListProjectsParams{
+			UserID:    ac.UserID,
+			AdminView: hasAnyRole(ac.Roles, "admin", "manager"),
+			Limit:     pg.Limit + 1, // one extra row tells whether there is a next page
+			Offset:    pg.Offset,
+			After:     pg.After,
+		}
+		for _, raw := range q["status"] {
+			for _, st := range strings.Split(raw, ",") {
+				st = strings.TrimSpace(st)
+				if st == "" {
+					continue
+				}
+				if !models.ProjectStatus(st).Valid() {
+					writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: fmt.Sprintf("unknown status %q", st)}})
+					return
+				}
+				params.Statuses = append(params.Statuses, st)
+			}
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		list, err := repo.List(ctx, params)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		var next string
+		if len(list) > pg.Limit {
+			list = list[:pg.Limit]
+			last := list[len(list)-1]
+			next = storage.EncodeCursor(storage.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		}
+		var total *int
+		if pg.IncludeTotal {
+			n, err := repo.Count(ctx, params)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			total = &n
+		}
+		data := pg.meta(next, next != "", total)
+		data["items"] = list
+		setNextLink(w, r, next)
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: data})
+	}
+}
+
+// CreateProjectHandler creates a project (admins and managers); the creator
+// becomes its first project manager.
+func CreateProjectHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req createProjectRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		p := &models.Project{
+			Name:      req.Name,
+			Address:   req.Address,
+			Customer:  req.Customer,
+			StartDate: req.StartDate,
+			EndDate:   req.EndDate,
+			Status:    req.Status,
+			CreatedBy: ac.UserID,
+		}
+		if p.Status == "" {
+			p.Status = models.ProjectStatusPlanned
+		}
+		if err := validateProject(p); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewProjectRepository(tx)
+		if err := repo.Create(ctx, p); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := repo.SetMember(ctx, &models.ProjectMember{ProjectID: p.ID, UserID: ac.UserID, Role: models.ProjectRoleManager, AddedBy: ac.UserID}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.ProjectCreated, map[string]any{
+			"id":         p.ID,
+			"name":       p.Name,
+			"status":     p.Status,
+			"created_by": ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: p})
+	}
+}
+
+func GetProjectHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		p, _, status, apiErr := loadViewableProject(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: p})
+	}
+}
+
+func UpdateProjectHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req updateProjectRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		p, m, status, apiErr := loadViewableProject(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canManageProject(ac, m) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "only project managers can edit the project"}})
+			return
+		}
+		before := *p
+		if req.Name != nil {
+			p.Name = *req.Name
+		}
+		if req.Address != nil {
+			p.Address = *req.Address
+		}
+		if req.Customer != nil {
+			p.Customer = *req.Customer
+		}
+		if req.StartDate != nil {
+			p.StartDate = *req.StartDate
+		}
+		if req.EndDate != nil {
+			p.EndDate = *req.EndDate
+		}
+		if req.Status != nil {
+			p.Status = *req.Status
+		}
+		if err := validateProject(p); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		if *p == before {
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: p})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		if err := storage.NewProjectRepository(tx).Update(ctx, p); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.ProjectUpdated, map[string]any{
+			"id":         p.ID,
+			"changed_by": ac.UserID,
+			"before":     before,
+			"after":      p,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: p})
+	}
+}
+
+// DeleteProjectHandler removes a project that has no orders or defects
+// (admin); finished projects are closed through their status instead.
+func DeleteProjectHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewProjectRepository(tx)
+		p, err := repo.GetByID(ctx, chi.URLParam(r, "id"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "project not found"}})
+			return
+		}
+		inUse, err := repo.HasOrders(ctx, p.ID)
+		if err == nil && !inUse {
+			inUse, err = storage.NewDefectRepository(tx).HasDefects(ctx, p.ID)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if inUse {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "project_in_use", Message: "project has orders or defects"}})
+			return
+		}
+		if err := repo.Delete(ctx, p.ID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.ProjectDeleted, map[string]any{
+			"id":         p.ID,
+			"name":       p.Name,
+			"deleted_by": ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"id": p.ID, "status": "deleted"}})
+	}
+}
+
+func ListProjectMembersHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewProjectRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		p, _, status, apiErr := loadViewableProject(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		members, err := repo.ListMembers(ctx, p.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: members})
+	}
+}
+
+// SetProjectMemberHandler adds a user to the project or changes their role.
+func SetProjectMemberHandler(db *sql.DB) http.HandlerFunc {
+	users := storage.NewUserRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req projectMemberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		if !req.Role.Valid() {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "role must be one of manager, engineer, executor, viewer"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		p, m, status, apiErr := loadViewableProject(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canManageProject(ac, m) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "only project managers can change members"}})
+			return
+		}
+		u, err := users.GetByID(ctx, chi.URLParam(r, "userID"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "user not found"}})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewProjectRepository(tx)
+		previous, err := repo.GetMember(ctx, p.ID, u.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if previous != nil && previous.Role == req.Role {
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: previous})
+			return
+		}
+		member := &models.ProjectMember{ProjectID: p.ID, UserID: u.ID, Role: req.Role, AddedBy: ac.UserID}
+		if err := repo.SetMember(ctx, member); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		payload := map[string]any{
+			"project_id": p.ID,
+			"user_id":    u.ID,
+			"role":       req.Role,
+			"added_by":   ac.UserID,
+		}
+		if previous != nil {
+			// The original membership record is kept; only the role changes.
+			member.AddedBy, member.AddedAt = previous.AddedBy, previous.AddedAt
+			payload["previous_role"] = previous.Role
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.ProjectMemberAdded, payload); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: member})
+	}
+}
+
+func RemoveProjectMemberHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		p, m, status, apiErr := loadViewableProject(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		userID := chi.URLParam(r, "userID")
+		// Members may leave a project on their own.
+		if !canManageProject(ac, m) && userID != ac.UserID {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "only project managers can change members"}})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		if err := storage.NewProjectRepository(tx).RemoveMember(ctx, p.ID, userID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "member not found"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.ProjectMemberRemoved, map[string]any{
+			"project_id": p.ID,
+			"user_id":    userID,
+			"removed_by": ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"project_id": p.ID, "user_id": userID, "status": "removed"}})
+	}
+}
+
+// ListProjectOrdersHandler lists the orders of a project. Everyone who sees
+// the project sees all of its orders; the usual order filters apply.
+func ListProjectOrdersHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewOrderRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		q := r.URL.Query()
+		pg, err := parsePageRequest(q)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid cursor"}})
+			return
+		}
+		params, err := parseOrderFilters(q, ac)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		// Everyone who sees the project sees all of its orders, so only the
+		// deleted filter is left to check.
+		params.AdminView = true
+		if apiErr := checkListScope(params, ac); apiErr != nil {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: apiErr})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		p, _, status, apiErr := loadViewableProject(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		params.ProjectID = p.ID
+		writeOrderPage(w, r, repo, params, pg)
+	}
+}
@@ -0,0 +1,52 @@
+package httpserver
+
+import (
+	"net/url"
+	"testing"
+
+	"frame_control_system/internal/models"
+)
+
+func TestValidateProject(t *testing.T) {
+	p := &models.Project{Name: "  Tower A ", StartDate: "2025-03-01", EndDate: "2025-12-31", Status: models.ProjectStatusActive}
+	if err := validateProject(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name != "Tower A" {
+		t.Fatalf("name should be trimmed, got %q", p.Name)
+	}
+	bad := []models.Project{
+		{Name: "", Status: models.ProjectStatusPlanned},
+		{Name: "x", Status: "paused"},
+		{Name: "x", Status: models.ProjectStatusPlanned, StartDate: "01.03.2025"},
+		{Name: "x", Status: models.ProjectStatusPlanned, StartDate: "2025-03-02", EndDate: "2025-03-01"},
+	}
+	for _, b := range bad {
+		if err := validateProject(&b); err == nil {
+			t.Fatalf("expected error for %+v", b)
+		}
+	}
+}
+
+func TestCanManageProject(t *testing.T) {
+	user := &AuthContext{UserID: "u1", Roles: []string{"user"}}
+	if canManageProject(user, nil) {
+		t.Fatal("non-members cannot manage the project")
+	}
+	if canManageProject(user, &models.ProjectMember{Role: models.ProjectRoleEngineer}) {
+		t.Fatal("engineers cannot manage the project")
+	}
+	if !canManageProject(user, &models.ProjectMember{Role: models.ProjectRoleManager}) {
+		t.Fatal("project managers can manage the project")
+	}
+	if !canManageProject(&AuthContext{UserID: "m1", Roles: []string{"manager"}}, nil) {
+		t.Fatal("managers can manage every project")
+	}
+}
+
+func TestParseOrderFiltersProject(t *testing.T) {
+	p, err := parseOrderFilters(url.Values{"project_id": {" p1 "}}, &AuthContext{UserID: "u1"})
+	if err != nil || p.ProjectID != "p1" || p.AdminView {
+		t.Fatalf("unexpected params: %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,208 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+var promoCodeRe = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+type createPromoCodeRequest struct {
+	Code      string           `json:"code"`
+	Kind      models.PromoKind `json:"kind"`
+	Value     float64          `json:"value"`
+	ValidFrom *time.Time       `json:"valid_from"`
+	ValidTo   *time.Time       `json:"valid_to"`
+	MaxUses   *int             `json:"max_uses"`
+	Active    *bool            `json:"active"`
+}
+
+// updatePromoCodeRequest changes a code; omitted fields are kept and an
+// explicit null clears valid_from, valid_to or max_uses.
+type updatePromoCodeRequest struct {
+	Kind      *models.PromoKind   `json:"kind"`
+	Value     *float64            `json:"value"`
+	ValidFrom optional[time.Time] `json:"valid_from"`
+	ValidTo   optional[time.Time] `json:"valid_to"`
+	MaxUses   optional[int]       `json:"max_uses"`
+	Active    *bool               `json:"active"`
+}
+
+// normalizePromoCode upper-cases the code; codes are matched case-insensitively.
+func normalizePromoCode(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
+
+func validatePromoCode(p *models.PromoCode) error {
+	if !promoCodeRe.MatchString(p.Code) {
+		return errors.New("code must be 3-32 characters: letters, digits, - or _")
+	}
+	switch p.Kind {
+	case models.PromoPercent:
+		if p.Value <= 0 || p.Value > 100 {
+			return errors.New("percent value must be in (0, 100]")
+		}
+	case models.PromoFixed:
+		if p.Value <= 0 || math.IsInf(p.Value, 0) {
+			return errors.New("fixed value must be positive")
+		}
+	default:
+		return errors.New("kind must be percent or fixed")
+	}
+	if p.ValidFrom != nil && p.ValidTo != nil && !p.ValidTo.After(*p.ValidFrom) {
+		return errors.New("valid_to must be after valid_from")
+	}
+	if p.MaxUses != nil && *p.MaxUses <= 0 {
+		return errors.New("max_uses must be positive")
+	}
+	return nil
+}
+
+func truncateTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC().Truncate(time.Second)
+	return &v
+}
+
+func ListPromoCodesHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewPromoCodeRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		codes, err := repo.List(ctx)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: codes})
+	}
+}
+
+func CreatePromoCodeHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewPromoCodeRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req createPromoCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		p := &models.PromoCode{
+			Code:      normalizePromoCode(req.Code),
+			Kind:      req.Kind,
+			Value:     req.Value,
+			ValidFrom: truncateTime(req.ValidFrom),
+			ValidTo:   truncateTime(req.ValidTo),
+			MaxUses:   req.MaxUses,
+			Active:    req.Active == nil || *req.Active,
+		}
+		if err := validatePromoCode(p); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		if _, err := repo.Get(ctx, p.Code); err == nil {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "promo_code_taken", Message: "promo code already exists"}})
+			return
+		}
+		if err := repo.Create(ctx, p); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: p})
+	}
+}
+
+func GetPromoCodeHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewPromoCodeRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		p, err := repo.Get(ctx, normalizePromoCode(chi.URLParam(r, "code")))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "promo code not found"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: p})
+	}
+}
+
+func UpdatePromoCodeHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewPromoCodeRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req updatePromoCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		p, err := repo.Get(ctx, normalizePromoCode(chi.URLParam(r, "code")))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "promo code not found"}})
+			return
+		}
+		if req.Kind != nil {
+			p.Kind = *req.Kind
+		}
+		if req.Value != nil {
+			p.Value = *req.Value
+		}
+		if req.ValidFrom.Set {
+			p.ValidFrom = truncateTime(req.ValidFrom.Value)
+		}
+		if req.ValidTo.Set {
+			p.ValidTo = truncateTime(req.ValidTo.Value)
+		}
+		if req.MaxUses.Set {
+			p.MaxUses = req.MaxUses.Value
+		}
+		if req.Active != nil {
+			p.Active = *req.Active
+		}
+		if err := validatePromoCode(p); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		if err := repo.Update(ctx, p); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: p})
+	}
+}
+
+// DeletePromoCodeHandler deactivates the code. It is kept so the history of
+// redemptions stays readable and the code cannot be recreated by accident.
+func DeletePromoCodeHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewPromoCodeRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		p, err := repo.Get(ctx, normalizePromoCode(chi.URLParam(r, "code")))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "promo code not found"}})
+			return
+		}
+		p.Active = false
+		if err := repo.Update(ctx, p); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: p})
+	}
+}
@@ -0,0 +1,200 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"frame_control_system/internal/storage"
+)
+
+// reportRequest holds the query parameters shared by all report endpoints.
+type reportRequest struct {
+	Filter storage.ReportFilter
+	CSV    bool
+}
+
+func parseReportRequest(w http.ResponseWriter, r *http.Request) (reportRequest, bool) {
+	q := r.URL.Query()
+	var rr reportRequest
+	var err error
+	if rr.Filter.From, err = parseDateParam(q, "from", false); err == nil {
+		rr.Filter.To, err = parseDateParam(q, "to", true)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+		return rr, false
+	}
+	if rr.Filter.From != nil && rr.Filter.To != nil && !rr.Filter.From.Before(*rr.Filter.To) {
+		writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "from must be before to"}})
+		return rr, false
+	}
+	switch f := strings.TrimSpace(q.Get("format")); f {
+	case "", "json":
+	case "csv":
+		rr.CSV = true
+	default:
+		writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "format must be json or csv"}})
+		return rr, false
+	}
+	return rr, true
+}
+
+func writeCSV(w http.ResponseWriter, name string, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	_ = cw.Write(header)
+	_ = cw.WriteAll(rows)
+}
+
+func formatAmount(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func reportError(w http.ResponseWriter) {
+	writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+}
+
+func OrdersByStatusReportHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewReportRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		rr, ok := parseReportRequest(w, r)
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		rows, err := repo.ByStatus(ctx, rr.Filter)
+		if err != nil {
+			reportError(w)
+			return
+		}
+		if rr.CSV {
+			out := make([][]string, 0, len(rows))
+			for _, row := range rows {
+				out = append(out, []string{row.Status, strconv.Itoa(row.Count), formatAmount(row.Total)})
+			}
+			writeCSV(w, "orders-by-status", []string{"status", "count", "total_amount"}, out)
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]any{"items": rows}})
+	}
+}
+
+func OrdersByPeriodReportHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewReportRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		rr, ok := parseReportRequest(w, r)
+		if !ok {
+			return
+		}
+		granularity := strings.TrimSpace(r.URL.Query().Get("granularity"))
+		if granularity == "" {
+			granularity = "day"
+		}
+		if !storage.ValidReportGranularity(granularity) {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "granularity must be day, week or month"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		rows, err := repo.ByPeriod(ctx, rr.Filter, granularity)
+		if err != nil {
+			reportError(w)
+			return
+		}
+		if rr.CSV {
+			out := make([][]string, 0, len(rows))
+			for _, row := range rows {
+				out = append(out, []string{row.Period, strconv.Itoa(row.Count), formatAmount(row.Total)})
+			}
+			writeCSV(w, "orders-by-"+granularity, []string{"period", "count", "total_amount"}, out)
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]any{"granularity": granularity, "items": rows}})
+	}
+}
+
+func OrdersByUserReportHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewReportRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		rr, ok := parseReportRequest(w, r)
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		rows, err := repo.ByUser(ctx, rr.Filter)
+		if err != nil {
+			reportError(w)
+			return
+		}
+		if rr.CSV {
+			out := make([][]string, 0, len(rows))
+			for _, row := range rows {
+				out = append(out, []string{row.UserID, row.Name, row.Email, strconv.Itoa(row.Count), formatAmount(row.Total)})
+			}
+			writeCSV(w, "orders-by-user", []string{"user_id", "name", "email", "count", "total_amount"}, out)
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]any{"items": rows}})
+	}
+}
+
+// HoursReportHandler returns the logged labor hours per user; from/to bound
+// when the work started.
+func HoursReportHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewReportRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		rr, ok := parseReportRequest(w, r)
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		rows, err := repo.HoursByUser(ctx, rr.Filter)
+		if err != nil {
+			reportError(w)
+			return
+		}
+		if rr.CSV {
+			out := make([][]string, 0, len(rows))
+			for _, row := range rows {
+				out = append(out, []string{row.UserID, row.Name, row.Email, strconv.Itoa(row.Orders), strconv.Itoa(row.Entries), formatAmount(row.Hours)})
+			}
+			writeCSV(w, "orders-hours", []string{"user_id", "name", "email", "orders", "entries", "hours"}, out)
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]any{"items": rows}})
+	}
+}
+
+func LeadTimeReportHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewReportRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		rr, ok := parseReportRequest(w, r)
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		res, err := repo.LeadTime(ctx, rr.Filter)
+		if err != nil {
+			reportError(w)
+			return
+		}
+		if rr.CSV {
+			writeCSV(w, "orders-lead-time", []string{"count", "avg_hours", "min_hours", "max_hours"}, [][]string{{
+				strconv.Itoa(res.Count), formatAmount(res.AvgHours), formatAmount(res.MinHours), formatAmount(res.MaxHours),
+			}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: res})
+	}
+}
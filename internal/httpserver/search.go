@@ -0,0 +1,118 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"frame_control_system/internal/storage"
+)
+
+type orderSearchHitDTO struct {
+	ID          string            `json:"id"`
+	UserID      string            `json:"user_id"`
+	Status      string            `json:"status"`
+	TotalAmount float64           `json:"total_amount"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Score       float64           `json:"score"`
+	Highlights  map[string]string `json:"highlights"`
+}
+
+type userSearchHitDTO struct {
+	ID         string            `json:"id"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+var highlightReplacer = strings.NewReplacer(storage.HighlightStart, "<mark>", storage.HighlightEnd, "</mark>")
+
+// highlightHTML escapes the text and marks matched terms with <mark>.
+func highlightHTML(s string) string {
+	return highlightReplacer.Replace(html.EscapeString(s))
+}
+
+func SearchHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewSearchRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		q := r.URL.Query()
+		text := strings.TrimSpace(q.Get("q"))
+		if storage.BuildMatchQuery(text) == "" {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "q must contain at least one word"}})
+			return
+		}
+		kind := strings.TrimSpace(q.Get("type"))
+		if kind == "" {
+			kind = "all"
+		}
+		if kind != "all" && kind != "orders" && kind != "users" {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "type must be all, orders or users"}})
+			return
+		}
+		isAdmin := hasRole(ac.Roles, "admin")
+		if kind == "users" && !isAdmin {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "user search requires admin role"}})
+			return
+		}
+		params := storage.SearchParams{
+			Query:     text,
+			UserID:    ac.UserID,
+			AdminView: seesAllOrders(ac),
+			Limit:     parseIntDefault(q.Get("limit"), 20, 1, 100),
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		data := map[string]any{"query": text}
+		if kind == "all" || kind == "orders" {
+			hits, err := repo.SearchOrders(ctx, params)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "search error"}})
+				return
+			}
+			items := make([]orderSearchHitDTO, 0, len(hits))
+			for _, h := range hits {
+				items = append(items, orderSearchHitDTO{
+					ID:          h.ID,
+					UserID:      h.UserID,
+					Status:      h.Status,
+					TotalAmount: h.TotalAmount,
+					CreatedAt:   h.CreatedAt,
+					Score:       h.Score,
+					Highlights: map[string]string{
+						"items": highlightHTML(h.Items),
+						"notes": highlightHTML(h.Notes),
+					},
+				})
+			}
+			data["orders"] = items
+		}
+		// Only admins may look up other users.
+		if isAdmin && (kind == "all" || kind == "users") {
+			hits, err := repo.SearchUsers(ctx, params)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "search error"}})
+				return
+			}
+			items := make([]userSearchHitDTO, 0, len(hits))
+			for _, h := range hits {
+				items = append(items, userSearchHitDTO{
+					ID:    h.ID,
+					Score: h.Score,
+					Highlights: map[string]string{
+						"name":  highlightHTML(h.Name),
+						"email": highlightHTML(h.Email),
+					},
+				})
+			}
+			data["users"] = items
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: data})
+	}
+}
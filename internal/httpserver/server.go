@@ -1,16 +1,18 @@
 package httpserver
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
-	"database/sql"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 
+	"frame_control_system/internal/blobstore"
 	"frame_control_system/internal/config"
+	"frame_control_system/internal/storage"
 )
 
 type envelope struct {
@@ -24,19 +26,20 @@ type apiError struct {
 	Message string `json:"message"`
 }
 
-func NewRouter(cfg config.Config, db *sql.DB) http.Handler {
+func NewRouter(cfg config.Config, db *sql.DB, blobs blobstore.Store) http.Handler {
 	r := chi.NewRouter()
+	rates := storage.TaxRates{Default: cfg.VATDefaultRate, ByCategory: cfg.VATRates}
 
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(30 * time.Second))
+	r.Use(Timeout(30*time.Second, "/api/v1/orders/export"))
 
 	corsMw := cors.Handler(cors.Options{
 		AllowedOrigins:   cfg.CORSOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
-		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID", "Idempotency-Key"},
+		ExposedHeaders:   []string{"X-Request-ID", "Idempotent-Replayed", "Link"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	})
@@ -55,24 +58,138 @@ func NewRouter(cfg config.Config, db *sql.DB) http.Handler {
 		v1.Post("/users/register", RegisterHandler(db))
 		v1.Post("/users/login", LoginHandler(db, cfg.JWTSecret))
 
+		// Share links: the signed token replaces the login
+		v1.Get("/share/{token}", SharedViewHandler(db, cfg.JWTSecret))
+
+		// Calendar feed: calendar apps cannot log in, the feed token is the credential
+		v1.Get("/calendar/{token}.ics", CalendarFeedHandler(db))
+
 		// Protected
 		v1.Group(func(pr chi.Router) {
 			pr.Use(AuthMiddleware(cfg.JWTSecret))
+			pr.Use(ReadOnlyViewers())
+			pr.Use(Idempotency(db, cfg.IdempotencyTTL, max(cfg.AttachmentMaxBytes+64<<10, maxImportBytes)))
 
 			// Me
 			pr.Get("/users/me", GetMeHandler(db))
 			pr.Patch("/users/me", UpdateMeHandler(db))
+			pr.Post("/users/me/calendar-feed", CreateCalendarFeedHandler(db))
+			pr.Delete("/users/me/calendar-feed", RevokeCalendarFeedHandler(db))
+			pr.Get("/users/me/time-entries", ListMyTimeEntriesHandler(db))
 
 			// Admin
 			pr.With(RequireRole("admin")).Get("/users", AdminListUsersHandler(db))
 			pr.With(RequireRole("admin")).Get("/events/outbox", AdminListOutboxHandler(db))
 
 			// Orders
-			pr.Post("/orders", CreateOrderHandler(db))
+			pr.Post("/orders", CreateOrderHandler(db, rates))
 			pr.Get("/orders", ListOrdersHandler(db))
+			pr.Post("/orders/bulk", BulkOrdersHandler(db))
+			pr.Get("/orders/export", ExportOrdersHandler(db))
+			pr.Post("/orders/import", ImportOrdersHandler(db, rates))
 			pr.Get("/orders/{id}", GetOrderHandler(db)) // prefer path param
+			pr.Patch("/orders/{id}", EditOrderHandler(db, rates))
 			pr.Patch("/orders/{id}/status", UpdateOrderStatusHandler(db))
 			pr.Delete("/orders/{id}", CancelOrderHandler(db))
+			pr.With(RequireRole("admin")).Post("/orders/{id}/restore", RestoreOrderHandler(db))
+			pr.With(RequireAnyRole("manager", "admin")).Put("/orders/{id}/assignee", AssignOrderHandler(db))
+			pr.With(RequireAnyRole("manager", "admin")).Delete("/orders/{id}/assignee", UnassignOrderHandler(db))
+			pr.Get("/orders/{id}/comments", ListCommentsHandler(db))
+			pr.Post("/orders/{id}/comments", CreateCommentHandler(db))
+			pr.Patch("/orders/{id}/comments/{commentID}", UpdateCommentHandler(db, cfg.CommentEditWindow))
+			pr.Delete("/orders/{id}/comments/{commentID}", DeleteCommentHandler(db))
+			pr.Get("/orders/{id}/attachments", ListAttachmentsHandler(db))
+			pr.Post("/orders/{id}/attachments", UploadAttachmentHandler(db, blobs, cfg.AttachmentMaxBytes, cfg.AttachmentAllowedTypes))
+			pr.Get("/orders/{id}/attachments/{attachmentID}", DownloadAttachmentHandler(db, blobs, cfg.AttachmentStripEXIF))
+			pr.Get("/orders/{id}/attachments/{attachmentID}/thumbnails/{size}", ThumbnailHandler(db, blobs))
+			pr.Delete("/orders/{id}/attachments/{attachmentID}", DeleteAttachmentHandler(db))
+			pr.Get("/orders/{id}/viewers", ListOrderViewersHandler(db))
+			pr.Put("/orders/{id}/viewers/{userID}", AddOrderViewerHandler(db))
+			pr.Delete("/orders/{id}/viewers/{userID}", RemoveOrderViewerHandler(db))
+			pr.Post("/orders/{id}/share-links", CreateOrderShareLinkHandler(db, cfg.JWTSecret))
+			pr.Get("/orders/{id}/dependencies", ListOrderDependenciesHandler(db))
+			pr.Put("/orders/{id}/dependencies/{predecessorID}", AddOrderDependencyHandler(db))
+			pr.Delete("/orders/{id}/dependencies/{predecessorID}", RemoveOrderDependencyHandler(db))
+			pr.Get("/orders/{id}/checklists", ListOrderChecklistsHandler(db))
+			pr.Post("/orders/{id}/checklists", CreateOrderChecklistHandler(db))
+			pr.Delete("/orders/{id}/checklists/{checklistID}", DeleteOrderChecklistHandler(db))
+			pr.Patch("/orders/{id}/checklists/{checklistID}/items/{itemID}", CheckChecklistItemHandler(db))
+			pr.Get("/orders/{id}/time-entries", ListOrderTimeEntriesHandler(db))
+			pr.Post("/orders/{id}/time-entries", CreateTimeEntryHandler(db))
+			pr.Delete("/orders/{id}/time-entries/{entryID}", DeleteTimeEntryHandler(db))
+			pr.Post("/orders/{id}/timer/start", StartTimerHandler(db))
+			pr.Post("/orders/{id}/timer/stop", StopTimerHandler(db))
+			pr.Get("/orders/{id}/approvals", ListOrderApprovalsHandler(db))
+			pr.Post("/orders/{id}/approve", ApproveOrderHandler(db))
+			pr.Post("/orders/{id}/reject", RejectOrderHandler(db))
+			pr.Get("/approvals", ApprovalQueueHandler(db))
+
+			// Projects
+			pr.Get("/projects", ListProjectsHandler(db))
+			pr.With(RequireAnyRole("manager", "admin")).Post("/projects", CreateProjectHandler(db))
+			pr.Get("/projects/{id}", GetProjectHandler(db))
+			pr.Patch("/projects/{id}", UpdateProjectHandler(db))
+			pr.With(RequireRole("admin")).Delete("/projects/{id}", DeleteProjectHandler(db))
+			pr.Get("/projects/{id}/orders", ListProjectOrdersHandler(db))
+			pr.Get("/projects/{id}/schedule", ProjectScheduleHandler(db))
+			pr.Get("/projects/{id}/members", ListProjectMembersHandler(db))
+			pr.Put("/projects/{id}/members/{userID}", SetProjectMemberHandler(db))
+			pr.Delete("/projects/{id}/members/{userID}", RemoveProjectMemberHandler(db))
+			pr.Post("/projects/{id}/share-links", CreateProjectShareLinkHandler(db, cfg.JWTSecret))
+
+			// Defects
+			pr.Get("/defects", ListDefectsHandler(db))
+			pr.Post("/defects", CreateDefectHandler(db))
+			pr.Get("/defects/{id}", GetDefectHandler(db))
+			pr.Patch("/defects/{id}", UpdateDefectHandler(db))
+			pr.Patch("/defects/{id}/status", UpdateDefectStatusHandler(db))
+			pr.Delete("/defects/{id}", DeleteDefectHandler(db))
+			pr.Post("/defects/{id}/photos", UploadDefectPhotoHandler(db, blobs, cfg.AttachmentMaxBytes, cfg.AttachmentAllowedTypes))
+			pr.Get("/defects/{id}/photos/{photoID}", DownloadDefectPhotoHandler(db, blobs))
+			pr.Delete("/defects/{id}/photos/{photoID}", DeleteDefectPhotoHandler(db))
+
+			// Custom fields
+			pr.Get("/custom-fields", ListCustomFieldsHandler(db))
+			pr.With(RequireRole("admin")).Post("/custom-fields", CreateCustomFieldHandler(db))
+			pr.With(RequireRole("admin")).Patch("/custom-fields/{key}", UpdateCustomFieldHandler(db))
+			pr.With(RequireRole("admin")).Delete("/custom-fields/{key}", DeleteCustomFieldHandler(db))
+
+			// Checklist templates
+			pr.Get("/checklist-templates", ListChecklistTemplatesHandler(db))
+			pr.Get("/checklist-templates/{id}", GetChecklistTemplateHandler(db))
+			pr.With(RequireRole("admin")).Post("/checklist-templates", CreateChecklistTemplateHandler(db))
+			pr.With(RequireRole("admin")).Patch("/checklist-templates/{id}", UpdateChecklistTemplateHandler(db))
+			pr.With(RequireRole("admin")).Delete("/checklist-templates/{id}", DeleteChecklistTemplateHandler(db))
+
+			// Approval rules
+			pr.Get("/approval-rules", ListApprovalRulesHandler(db))
+			pr.Get("/approval-rules/{id}", GetApprovalRuleHandler(db))
+			pr.With(RequireRole("admin")).Post("/approval-rules", CreateApprovalRuleHandler(db))
+			pr.With(RequireRole("admin")).Patch("/approval-rules/{id}", UpdateApprovalRuleHandler(db))
+			pr.With(RequireRole("admin")).Delete("/approval-rules/{id}", DeleteApprovalRuleHandler(db))
+
+			// Promo codes
+			pr.Route("/promo-codes", func(pc chi.Router) {
+				pc.Use(RequireRole("admin"))
+				pc.Get("/", ListPromoCodesHandler(db))
+				pc.Post("/", CreatePromoCodeHandler(db))
+				pc.Get("/{code}", GetPromoCodeHandler(db))
+				pc.Patch("/{code}", UpdatePromoCodeHandler(db))
+				pc.Delete("/{code}", DeletePromoCodeHandler(db))
+			})
+
+			// Reports
+			pr.Route("/reports/orders", func(rep chi.Router) {
+				rep.Use(RequireAnyRole("admin", "manager", "executive"))
+				rep.Get("/by-status", OrdersByStatusReportHandler(db))
+				rep.Get("/by-period", OrdersByPeriodReportHandler(db))
+				rep.Get("/by-user", OrdersByUserReportHandler(db))
+				rep.Get("/lead-time", LeadTimeReportHandler(db))
+				rep.Get("/hours", HoursReportHandler(db))
+			})
+
+			// Search
+			pr.Get("/search", SearchHandler(db))
 		})
 	})
 
@@ -0,0 +1,425 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/auth"
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	defaultShareTTL = 7 * 24 * time.Hour
+	maxShareTTL     = 90 * 24 * time.Hour
+	// maxSharedProjectOrders caps the order list of a shared project view.
+	maxSharedProjectOrders = 100
+)
+
+type shareLinkRequest struct {
+	// ExpiresIn is a Go duration, e.g. "72h"; defaults to a week.
+	ExpiresIn string `json:"expires_in"`
+}
+
+type shareLink struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// sharedOrder is the read-only progress view of an order: no items, prices or
+// user ids.
+type sharedOrder struct {
+	Type      string                     `json:"type"`
+	ID        string                     `json:"id"`
+	ProjectID string                     `json:"project_id,omitempty"`
+	Status    models.OrderStatus         `json:"status"`
+	Priority  models.OrderPriority       `json:"priority"`
+	DueAt     *time.Time                 `json:"due_at"`
+	Overdue   bool                       `json:"overdue"`
+	CreatedAt time.Time                  `json:"created_at"`
+	UpdatedAt time.Time                  `json:"updated_at"`
+	History   []models.OrderStatusChange `json:"history"`
+	ExpiresAt time.Time                  `json:"expires_at"`
+}
+
+type sharedProjectOrder struct {
+	ID      string             `json:"id"`
+	Status  models.OrderStatus `json:"status"`
+	DueAt   *time.Time         `json:"due_at"`
+	Overdue bool               `json:"overdue"`
+}
+
+type sharedProject struct {
+	Type      string                      `json:"type"`
+	ID        string                      `json:"id"`
+	Name      string                      `json:"name"`
+	Address   string                      `json:"address"`
+	Status    models.ProjectStatus        `json:"status"`
+	StartDate string                      `json:"start_date,omitempty"`
+	EndDate   string                      `json:"end_date,omitempty"`
+	Orders    map[models.OrderStatus]int  `json:"orders_by_status"`
+	Defects   map[models.DefectStatus]int `json:"defects_by_status"`
+	// Items are the most recent orders, at most maxSharedProjectOrders.
+	Items     []sharedProjectOrder `json:"items"`
+	ExpiresAt time.Time            `json:"expires_at"`
+}
+
+// parseShareTTL reads the link lifetime; an empty value means the default.
+func parseShareTTL(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return defaultShareTTL, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 || d > maxShareTTL {
+		return 0, fmt.Errorf("expires_in must be a positive duration up to %s", maxShareTTL)
+	}
+	return d, nil
+}
+
+// decodeShareLinkRequest accepts an empty body for the default lifetime.
+func decodeShareLinkRequest(r *http.Request) (time.Duration, error) {
+	var req shareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return 0, errors.New("invalid json")
+	}
+	return parseShareTTL(req.ExpiresIn)
+}
+
+func newShareLink(kind, id string, ttl time.Duration, secret string) shareLink {
+	exp := time.Now().UTC().Add(ttl).Truncate(time.Second)
+	token := auth.SignShareToken(kind, id, exp, secret)
+	return shareLink{Token: token, URL: "/api/v1/share/" + token, ExpiresAt: exp}
+}
+
+// canShareOrder: whoever may change the order, and managers.
+func canShareOrder(o *models.Order, ac *AuthContext) bool {
+	return canModifyOrder(o, ac) || hasRole(ac.Roles, "manager")
+}
+
+// isOrderViewer reports whether the order was shared with the user; lookup
+// errors count as not shared.
+func isOrderViewer(ctx context.Context, q storage.DBTX, orderID, userID string) bool {
+	_, err := storage.NewOrderViewerRepository(q).Get(ctx, orderID, userID)
+	return err == nil
+}
+
+// CreateOrderShareLinkHandler issues a signed, expiring link to the read-only
+// progress view of an order. Links are not stored; they stop working when they
+// expire or the server key changes.
+func CreateOrderShareLinkHandler(db *sql.DB, secret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ttl, err := decodeShareLinkRequest(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canShareOrder(o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		link := newShareLink("order", o.ID, ttl, secret)
+		if err := storage.AddOutboxEvent(ctx, db, events.OrderShareLinkCreated, map[string]any{
+			"order_id":   o.ID,
+			"expires_at": link.ExpiresAt,
+			"created_by": ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: link})
+	}
+}
+
+// CreateProjectShareLinkHandler issues a share link to the progress view of a
+// project; project managers only.
+func CreateProjectShareLinkHandler(db *sql.DB, secret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ttl, err := decodeShareLinkRequest(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		p, m, status, apiErr := loadViewableProject(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canManageProject(ac, m) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "only project managers can share the project"}})
+			return
+		}
+		link := newShareLink("project", p.ID, ttl, secret)
+		if err := storage.AddOutboxEvent(ctx, db, events.ProjectShareLinkCreated, map[string]any{
+			"project_id": p.ID,
+			"expires_at": link.ExpiresAt,
+			"created_by": ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: link})
+	}
+}
+
+// SharedViewHandler serves the read-only view behind a share link; no login
+// is needed, the signed token is the credential.
+func SharedViewHandler(db *sql.DB, secret string) http.HandlerFunc {
+	orders := storage.NewOrderRepository(db)
+	projects := storage.NewProjectRepository(db)
+	defects := storage.NewDefectRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("X-Robots-Tag", "noindex")
+		kind, id, exp, err := auth.ParseShareToken(chi.URLParam(r, "token"), secret, time.Now())
+		if errors.Is(err, auth.ErrShareTokenExpired) {
+			writeJSON(w, http.StatusGone, envelope{Success: false, Error: &apiError{Code: "share_link_expired", Message: "share link expired"}})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "share link not found"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		switch kind {
+		case "order":
+			o, err := orders.GetByID(ctx, id)
+			if err != nil || o.DeletedAt != nil {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "order not found"}})
+				return
+			}
+			history, err := orders.StatusHistory(ctx, o.ID)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: sharedOrder{
+				Type:      "order",
+				ID:        o.ID,
+				ProjectID: o.ProjectID,
+				Status:    o.Status,
+				Priority:  o.Priority,
+				DueAt:     o.DueAt,
+				Overdue:   o.Overdue,
+				CreatedAt: o.CreatedAt,
+				UpdatedAt: o.UpdatedAt,
+				History:   history,
+				ExpiresAt: exp,
+			}})
+		case "project":
+			p, err := projects.GetByID(ctx, id)
+			if err != nil {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "project not found"}})
+				return
+			}
+			view := sharedProject{
+				Type:      "project",
+				ID:        p.ID,
+				Name:      p.Name,
+				Address:   p.Address,
+				Status:    p.Status,
+				StartDate: p.StartDate,
+				EndDate:   p.EndDate,
+				Items:     []sharedProjectOrder{},
+				ExpiresAt: exp,
+			}
+			view.Orders, err = projects.OrderStatusCounts(ctx, p.ID)
+			if err == nil {
+				view.Defects, err = defects.StatusCounts(ctx, p.ID)
+			}
+			var list []models.Order
+			if err == nil {
+				list, err = orders.List(ctx, storage.ListOrdersParams{
+					ProjectID:       p.ID,
+					AdminView:       true,
+					IncludeArchived: true,
+					Limit:           maxSharedProjectOrders,
+				})
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			for _, o := range list {
+				view.Items = append(view.Items, sharedProjectOrder{ID: o.ID, Status: o.Status, DueAt: o.DueAt, Overdue: o.Overdue})
+			}
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: view})
+		default:
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "share link not found"}})
+		}
+	}
+}
+
+// ListOrderViewersHandler lists the users an order is shared with.
+func ListOrderViewersHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewOrderViewerRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canShareOrder(o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		list, err := repo.List(ctx, o.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: list})
+	}
+}
+
+// AddOrderViewerHandler gives a user read-only access to a single order.
+// Granting access twice is a no-op without an event.
+func AddOrderViewerHandler(db *sql.DB) http.HandlerFunc {
+	users := storage.NewUserRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canShareOrder(o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		u, err := users.GetByID(ctx, chi.URLParam(r, "userID"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "user not found"}})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewOrderViewerRepository(tx)
+		v := &models.OrderViewer{OrderID: o.ID, UserID: u.ID, AddedBy: ac.UserID}
+		added, err := repo.Add(ctx, v)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if !added {
+			existing, err := repo.Get(ctx, o.ID, u.ID)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			writeJSON(w, http.StatusOK, envelope{Success: true, Data: existing})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderViewerAdded, map[string]any{
+			"order_id": o.ID,
+			"user_id":  u.ID,
+			"added_by": ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: v})
+	}
+}
+
+func RemoveOrderViewerHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canShareOrder(o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		userID := chi.URLParam(r, "userID")
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		if err := storage.NewOrderViewerRepository(tx).Remove(ctx, o.ID, userID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "viewer not found"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderViewerRemoved, map[string]any{
+			"order_id":   o.ID,
+			"user_id":    userID,
+			"removed_by": ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"order_id": o.ID, "user_id": userID, "status": "removed"}})
+	}
+}
@@ -0,0 +1,74 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"frame_control_system/internal/models"
+)
+
+func TestParseShareTTL(t *testing.T) {
+	if d, err := parseShareTTL(""); err != nil || d != defaultShareTTL {
+		t.Fatalf("expected the default lifetime, got %v, %v", d, err)
+	}
+	if d, err := parseShareTTL("72h"); err != nil || d != 72*time.Hour {
+		t.Fatalf("unexpected result: %v, %v", d, err)
+	}
+	for _, s := range []string{"soon", "-1h", "0s", "2200h"} {
+		if _, err := parseShareTTL(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
+
+func TestReadOnlyViewers(t *testing.T) {
+	h := ReadOnlyViewers()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	cases := []struct {
+		roles  []string
+		method string
+		path   string
+		want   int
+	}{
+		{[]string{"viewer"}, http.MethodGet, "/api/v1/orders/1", http.StatusNoContent},
+		{[]string{"viewer"}, http.MethodPatch, "/api/v1/users/me", http.StatusNoContent},
+		{[]string{"viewer"}, http.MethodPost, "/api/v1/orders/1/comments", http.StatusForbidden},
+		{[]string{"viewer"}, http.MethodPatch, "/api/v1/orders/1/status", http.StatusForbidden},
+		{[]string{"user"}, http.MethodPost, "/api/v1/orders", http.StatusNoContent},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), authCtxKey{}, &AuthContext{UserID: "u1", Roles: c.roles}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Fatalf("%v %s %s: got %d, want %d", c.roles, c.method, c.path, rec.Code, c.want)
+		}
+	}
+}
+
+func TestCanWriteOrder(t *testing.T) {
+	o := &models.Order{UserID: "owner", AssigneeID: "worker", ProjectID: "p1"}
+	cases := []struct {
+		name   string
+		ac     *AuthContext
+		member *models.ProjectMember
+		want   bool
+	}{
+		{"owner", &AuthContext{UserID: "owner", Roles: []string{"user"}}, nil, true},
+		{"assignee", &AuthContext{UserID: "worker", Roles: []string{"user"}}, nil, true},
+		{"manager", &AuthContext{UserID: "m", Roles: []string{"manager"}}, nil, true},
+		{"project engineer", &AuthContext{UserID: "e", Roles: []string{"user"}}, &models.ProjectMember{Role: models.ProjectRoleEngineer}, true},
+		{"project viewer", &AuthContext{UserID: "c", Roles: []string{"user"}}, &models.ProjectMember{Role: models.ProjectRoleViewer}, false},
+		{"order viewer", &AuthContext{UserID: "c", Roles: []string{"user"}}, nil, false},
+	}
+	for _, c := range cases {
+		if got := canWriteOrder(o, c.ac, c.member); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
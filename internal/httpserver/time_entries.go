@@ -0,0 +1,471 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	// maxTimeEntry caps a single entry; longer work is logged per day.
+	maxTimeEntry       = 24 * time.Hour
+	maxTimeEntryNote   = 1000
+	timeEntryClockSkew = time.Minute
+)
+
+type timeEntryRequest struct {
+	StartedAt       *time.Time `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at"`
+	DurationMinutes *int       `json:"duration_minutes"`
+	Note            string     `json:"note"`
+}
+
+type timerRequest struct {
+	Note string `json:"note"`
+}
+
+// timeEntrySpan resolves a manual entry to [start, end): the end is given
+// either directly or as a duration. Entries cannot end in the future.
+func timeEntrySpan(req timeEntryRequest, now time.Time) (time.Time, time.Time, error) {
+	if req.StartedAt == nil {
+		return time.Time{}, time.Time{}, errors.New("started_at required")
+	}
+	start := req.StartedAt.UTC().Truncate(time.Second)
+	var end time.Time
+	switch {
+	case req.EndedAt != nil && req.DurationMinutes != nil:
+		return time.Time{}, time.Time{}, errors.New("give either ended_at or duration_minutes, not both")
+	case req.EndedAt != nil:
+		end = req.EndedAt.UTC().Truncate(time.Second)
+	case req.DurationMinutes != nil:
+		if *req.DurationMinutes <= 0 {
+			return time.Time{}, time.Time{}, errors.New("duration_minutes must be positive")
+		}
+		end = start.Add(time.Duration(*req.DurationMinutes) * time.Minute)
+	default:
+		return time.Time{}, time.Time{}, errors.New("ended_at or duration_minutes required")
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, errors.New("ended_at must be after started_at")
+	}
+	if end.Sub(start) > maxTimeEntry {
+		return time.Time{}, time.Time{}, fmt.Errorf("an entry may span at most %d hours", int(maxTimeEntry/time.Hour))
+	}
+	if end.After(now.Add(timeEntryClockSkew)) {
+		return time.Time{}, time.Time{}, errors.New("time entries cannot end in the future")
+	}
+	return start, end, nil
+}
+
+func validTimeEntryNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxTimeEntryNote {
+		return "", fmt.Errorf("note must be at most %d characters", maxTimeEntryNote)
+	}
+	return note, nil
+}
+
+// sumTimeTotals adds up per-user or per-order totals.
+func sumTimeTotals(totals []models.TimeTotal) models.TimeTotal {
+	var sum models.TimeTotal
+	for _, t := range totals {
+		sum.Entries += t.Entries
+		sum.DurationSeconds += t.DurationSeconds
+	}
+	sum.Hours = models.Hours(sum.DurationSeconds)
+	return sum
+}
+
+// canLogTime: the people who carry out the order, the same as for checking
+// off its checklist items.
+func canLogTime(ctx context.Context, q storage.DBTX, o *models.Order, ac *AuthContext) bool {
+	return canCheckOffItems(ctx, q, o, ac)
+}
+
+func timeEntryOverlap(e *models.TimeEntry) *apiError {
+	msg := fmt.Sprintf("overlaps the entry started at %s on order %s", e.StartedAt.Format(time.RFC3339), e.OrderID)
+	if e.Running {
+		msg = fmt.Sprintf("overlaps the timer running on order %s since %s", e.OrderID, e.StartedAt.Format(time.RFC3339))
+	}
+	return &apiError{Code: "time_entry_overlap", Message: msg}
+}
+
+// ListOrderTimeEntriesHandler returns the order's time entries together with
+// the logged hours per user and in total. Running timers are listed but not
+// counted.
+func ListOrderTimeEntriesHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewTimeEntryRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		items, err := repo.ListByOrder(ctx, o.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		byUser, err := repo.TotalsByOrder(ctx, o.ID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		total := sumTimeTotals(byUser)
+		total.OrderID = o.ID
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]any{
+			"items":   items,
+			"by_user": byUser,
+			"total":   total,
+		}})
+	}
+}
+
+// CreateTimeEntryHandler logs finished work of the caller by hand. It must
+// not overlap the caller's other entries or running timer.
+func CreateTimeEntryHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req timeEntryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		start, end, err := timeEntrySpan(req, time.Now())
+		if err == nil {
+			req.Note, err = validTimeEntryNote(req.Note)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canLogTime(ctx, db, o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		// Work is often logged after the order is done, but not on cancelled ones.
+		if o.Status == models.OrderStatusCancelled || o.DeletedAt != nil {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_editable", Message: fmt.Sprintf("order in status %s does not accept time entries", o.Status)}})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewTimeEntryRepository(tx)
+		other, err := repo.Overlapping(ctx, ac.UserID, start, &end)
+		if err == nil {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: timeEntryOverlap(other)})
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		e := &models.TimeEntry{OrderID: o.ID, UserID: ac.UserID, StartedAt: start, EndedAt: &end, Note: req.Note}
+		if err := repo.Create(ctx, e); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderTimeLogged, map[string]any{
+			"order_id":         o.ID,
+			"entry_id":         e.ID,
+			"user_id":          ac.UserID,
+			"duration_seconds": e.DurationSeconds,
+			"source":           "manual",
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: e})
+	}
+}
+
+// DeleteTimeEntryHandler removes an entry; a running timer is discarded.
+// Users remove their own entries, whoever plans the order removes any.
+func DeleteTimeEntryHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewTimeEntryRepository(tx)
+		e, err := repo.Get(ctx, o.ID, chi.URLParam(r, "entryID"))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "time entry not found"}})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if e.UserID != ac.UserID && !canPlanOrder(o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		if err := repo.Delete(ctx, o.ID, e.ID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderTimeEntryDeleted, map[string]any{
+			"order_id":         o.ID,
+			"entry_id":         e.ID,
+			"user_id":          e.UserID,
+			"duration_seconds": e.DurationSeconds,
+			"running":          e.Running,
+			"deleted_by":       ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]string{"id": e.ID, "status": "deleted"}})
+	}
+}
+
+// StartTimerHandler starts the caller's timer on an open order. A user runs
+// one timer at a time.
+func StartTimerHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		var req timerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid json"}})
+			return
+		}
+		note, err := validTimeEntryNote(req.Note)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		if !canLogTime(ctx, db, o, ac) {
+			writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+			return
+		}
+		if !o.Open() || o.DeletedAt != nil {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "order_not_editable", Message: fmt.Sprintf("order in status %s does not accept time entries", o.Status)}})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewTimeEntryRepository(tx)
+		running, err := repo.Running(ctx, ac.UserID)
+		if err == nil {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: &apiError{Code: "timer_running", Message: "a timer is already running on order " + running.OrderID}})
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		now := time.Now().UTC().Truncate(time.Second)
+		// Entries logged by hand may reach a little into the future.
+		other, err := repo.Overlapping(ctx, ac.UserID, now, nil)
+		if err == nil {
+			writeJSON(w, http.StatusConflict, envelope{Success: false, Error: timeEntryOverlap(other)})
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		e := &models.TimeEntry{OrderID: o.ID, UserID: ac.UserID, StartedAt: now, Note: note}
+		if err := repo.Create(ctx, e); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderTimerStarted, map[string]any{
+			"order_id": o.ID,
+			"entry_id": e.ID,
+			"user_id":  ac.UserID,
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusCreated, envelope{Success: true, Data: e})
+	}
+}
+
+// StopTimerHandler stops the caller's timer on the order and logs the time.
+// It works on closed orders as well, so a timer left running is not lost.
+func StopTimerHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		o, status, apiErr := loadViewableOrder(ctx, db, ac, chi.URLParam(r, "id"))
+		if apiErr != nil {
+			writeJSON(w, status, envelope{Success: false, Error: apiErr})
+			return
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		defer tx.Rollback()
+		repo := storage.NewTimeEntryRepository(tx)
+		e, err := repo.Running(ctx, ac.UserID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err != nil || e.OrderID != o.ID {
+			writeJSON(w, http.StatusNotFound, envelope{Success: false, Error: &apiError{Code: "not_found", Message: "no timer running on this order"}})
+			return
+		}
+		if err := repo.Stop(ctx, e, time.Now()); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderTimeLogged, map[string]any{
+			"order_id":         o.ID,
+			"entry_id":         e.ID,
+			"user_id":          ac.UserID,
+			"duration_seconds": e.DurationSeconds,
+			"source":           "timer",
+		}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: e})
+	}
+}
+
+// ListMyTimeEntriesHandler returns the caller's entries started in
+// [from, to), the hours per order and the running timer, if any. Admins and
+// managers may look at another user with ?user_id=.
+func ListMyTimeEntriesHandler(db *sql.DB) http.HandlerFunc {
+	repo := storage.NewTimeEntryRepository(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		ac := GetAuth(r)
+		if ac == nil {
+			writeJSON(w, http.StatusUnauthorized, envelope{Success: false, Error: &apiError{Code: "unauthorized", Message: "no auth"}})
+			return
+		}
+		q := r.URL.Query()
+		userID := ac.UserID
+		if u := strings.TrimSpace(q.Get("user_id")); u != "" && u != ac.UserID {
+			if !hasAnyRole(ac.Roles, "admin", "manager") {
+				writeJSON(w, http.StatusForbidden, envelope{Success: false, Error: &apiError{Code: "forbidden", Message: "not allowed"}})
+				return
+			}
+			userID = u
+		}
+		from, err := parseDateParam(q, "from", false)
+		var to *time.Time
+		if err == nil {
+			to, err = parseDateParam(q, "to", true)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: err.Error()}})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		items, err := repo.ListByUser(ctx, userID, from, to)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		byOrder, err := repo.TotalsByUser(ctx, userID, from, to)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		var running *models.TimeEntry
+		if running, err = repo.Running(ctx, userID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+			return
+		}
+		total := sumTimeTotals(byOrder)
+		total.UserID = userID
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]any{
+			"items":    items,
+			"by_order": byOrder,
+			"total":    total,
+			"running":  running,
+		}})
+	}
+}
@@ -0,0 +1,54 @@
+package httpserver
+
+import (
+	"testing"
+	"time"
+
+	"frame_control_system/internal/models"
+)
+
+func TestTimeEntrySpan(t *testing.T) {
+	now := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+	at := func(h, m int) *time.Time {
+		v := time.Date(2024, 5, 10, h, m, 0, 0, time.UTC)
+		return &v
+	}
+	minutes := func(n int) *int { return &n }
+
+	start, end, err := timeEntrySpan(timeEntryRequest{StartedAt: at(9, 0), EndedAt: at(12, 30)}, now)
+	if err != nil || !start.Equal(*at(9, 0)) || !end.Equal(*at(12, 30)) {
+		t.Fatalf("start/end: got %v %v %v", start, end, err)
+	}
+	_, end, err = timeEntrySpan(timeEntryRequest{StartedAt: at(9, 0), DurationMinutes: minutes(90)}, now)
+	if err != nil || !end.Equal(*at(10, 30)) {
+		t.Fatalf("duration: got %v %v", end, err)
+	}
+
+	bad := map[string]timeEntryRequest{
+		"no start":      {EndedAt: at(12, 0)},
+		"no end":        {StartedAt: at(9, 0)},
+		"both":          {StartedAt: at(9, 0), EndedAt: at(10, 0), DurationMinutes: minutes(60)},
+		"zero duration": {StartedAt: at(9, 0), DurationMinutes: minutes(0)},
+		"end first":     {StartedAt: at(9, 0), EndedAt: at(8, 0)},
+		"too long":      {StartedAt: at(9, 0), DurationMinutes: minutes(25 * 60)},
+		"future":        {StartedAt: at(17, 0), EndedAt: at(19, 0)},
+	}
+	for name, req := range bad {
+		if _, _, err := timeEntrySpan(req, now); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSumTimeTotals(t *testing.T) {
+	sum := sumTimeTotals([]models.TimeTotal{
+		{UserID: "u1", Entries: 2, DurationSeconds: 5400},
+		{UserID: "u2", Entries: 1, DurationSeconds: 1200},
+	})
+	if sum.Entries != 3 || sum.DurationSeconds != 6600 || sum.Hours != 1.83 {
+		t.Fatalf("unexpected sum %+v", sum)
+	}
+	if got := models.Hours(0); got != 0 {
+		t.Fatalf("zero seconds: got %v", got)
+	}
+}
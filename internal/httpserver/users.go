@@ -190,16 +190,23 @@ func AdminListUsersHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// pagination and filters
 		q := r.URL.Query()
-		limit := parseIntDefault(q.Get("limit"), 20, 1, 100)
-		page := parseIntDefault(q.Get("page"), 1, 1, 100000)
-		offset := (page - 1) * limit
+		pg, err := parsePageRequest(q)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: &apiError{Code: "invalid_input", Message: "invalid cursor"}})
+			return
+		}
 		params := storage.ListUsersParams{
 			Email:  strings.TrimSpace(q.Get("email")),
 			Name:   strings.TrimSpace(q.Get("name")),
 			Role:   strings.TrimSpace(q.Get("role")),
 			Sort:   strings.TrimSpace(q.Get("sort")),
-			Limit:  limit,
-			Offset: offset,
+			Limit:  pg.Limit + 1, // one extra row tells whether there is a next page
+			Offset: pg.Offset,
+			After:  pg.After,
+		}
+		if apiErr := checkCursorSort(pg, params.Sort, params.KeysetSort()); apiErr != nil {
+			writeJSON(w, http.StatusBadRequest, envelope{Success: false, Error: apiErr})
+			return
 		}
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
@@ -208,6 +215,24 @@ func AdminListUsersHandler(db *sql.DB) http.HandlerFunc {
 			writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
 			return
 		}
+		var next string
+		more := len(list) > pg.Limit
+		if more {
+			list = list[:pg.Limit]
+			if params.KeysetSort() {
+				last := list[len(list)-1]
+				next = storage.EncodeCursor(storage.Cursor{CreatedAt: last.CreatedAt, ID: last.ID, Sort: cursorSort(params.Sort)})
+			}
+		}
+		var total *int
+		if pg.IncludeTotal {
+			n, err := userRepo.Count(ctx, params)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, envelope{Success: false, Error: &apiError{Code: "internal_error", Message: "db error"}})
+				return
+			}
+			total = &n
+		}
 		// map output (omit password)
 		items := make([]map[string]interface{}, 0, len(list))
 		for _, u := range list {
@@ -218,11 +243,13 @@ func AdminListUsersHandler(db *sql.DB) http.HandlerFunc {
 				"roles": u.Roles,
 			})
 		}
-		writeJSON(w, http.StatusOK, envelope{Success: true, Data: map[string]interface{}{
-			"items": items,
-			"page":  page,
-			"limit": limit,
-		}})
+		data := pg.meta(next, more, total)
+		data["items"] = items
+		if more && next == "" {
+			setNextPageLink(w, r, pg.Page)
+		}
+		setNextLink(w, r, next)
+		writeJSON(w, http.StatusOK, envelope{Success: true, Data: data})
 	}
 }
 
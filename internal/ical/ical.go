@@ -0,0 +1,114 @@
+// Package ical writes iCalendar (RFC 5545) streams: CRLF line endings, lines
+// folded at 75 octets and escaped TEXT values.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the longest content line allowed before folding, without CRLF.
+const maxLineOctets = 75
+
+// Writer emits content lines. The first write error is kept and returned by
+// Flush; later writes are no-ops.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Begin opens a component such as VCALENDAR, VEVENT or VTODO.
+func (w *Writer) Begin(component string) {
+	w.line("BEGIN:" + component)
+}
+
+func (w *Writer) End(component string) {
+	w.line("END:" + component)
+}
+
+// Prop writes a property whose value is already in its iCalendar form.
+func (w *Writer) Prop(name, value string) {
+	w.line(name + ":" + value)
+}
+
+// Text writes a TEXT property, escaping the value.
+func (w *Writer) Text(name, value string) {
+	w.line(name + ":" + EscapeText(value))
+}
+
+// Time writes a DATE-TIME property in UTC.
+func (w *Writer) Time(name string, t time.Time) {
+	w.line(name + ":" + FormatTime(t))
+}
+
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+func (w *Writer) line(s string) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.WriteString(Fold(s) + "\r\n")
+}
+
+// FormatTime renders t as a UTC DATE-TIME, e.g. 20250601T120000Z.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// EscapeText escapes backslashes, semicolons, commas and newlines; other
+// control characters are dropped.
+func EscapeText(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ReplaceAll(s, "\r\n", "\n") {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == ';':
+			b.WriteString(`\;`)
+		case r == ',':
+			b.WriteString(`\,`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r < 0x20 && r != '\t', r == 0x7f:
+			// not allowed in TEXT
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Fold splits a content line into chunks of at most 75 octets, continuation
+// lines starting with a space. Multi-byte characters are never split.
+func Fold(s string) string {
+	if len(s) <= maxLineOctets {
+		return s
+	}
+	var b strings.Builder
+	limit := maxLineOctets
+	n := 0
+	for _, r := range s {
+		size := utf8.RuneLen(r)
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			// The leading space counts towards the continuation line.
+			limit = maxLineOctets - 1
+			n = 0
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscapeText(t *testing.T) {
+	got := EscapeText("a;b,c\\d\r\nline\x01")
+	want := `a\;b\,c\\d\nline`
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestFold(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("ж", 100)
+	folded := Fold(line)
+	for i, l := range strings.Split(folded, "\r\n") {
+		if len(l) > maxLineOctets {
+			t.Fatalf("line %d has %d octets", i, len(l))
+		}
+		if i > 0 && !strings.HasPrefix(l, " ") {
+			t.Fatalf("continuation line %d must start with a space", i)
+		}
+	}
+	if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != line {
+		t.Fatalf("unfolding must restore the line")
+	}
+	if Fold("SUMMARY:short") != "SUMMARY:short" {
+		t.Fatal("short lines are kept")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Begin("VCALENDAR")
+	w.Text("SUMMARY", "Order, urgent")
+	w.Time("DUE", time.Date(2025, 6, 1, 15, 4, 5, 0, time.FixedZone("MSK", 3*3600)))
+	w.End("VCALENDAR")
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "BEGIN:VCALENDAR\r\nSUMMARY:Order\\, urgent\r\nDUE:20250601T120405Z\r\nEND:VCALENDAR\r\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,240 @@
+// Package imaging holds the pure-Go image helpers used for photo
+// attachments: EXIF parsing, metadata stripping and thumbnail scaling.
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+var ErrNoEXIF = errors.New("no exif data")
+
+// EXIF is the subset of photo metadata the application uses.
+type EXIF struct {
+	// TakenAt is DateTimeOriginal, in the offset from OffsetTimeOriginal or UTC.
+	TakenAt *time.Time
+	Lat     *float64
+	Lon     *float64
+	// Orientation is the TIFF orientation tag (1..8); 0 when absent.
+	Orientation int
+}
+
+const (
+	tagOrientation        = 0x0112
+	tagExifIFD            = 0x8769
+	tagGPSIFD             = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+	tagGPSLatitudeRef     = 0x0001
+	tagGPSLatitude        = 0x0002
+	tagGPSLongitudeRef    = 0x0003
+	tagGPSLongitude       = 0x0004
+)
+
+// exifHeader prefixes the TIFF structure inside a JPEG APP1 segment.
+var exifHeader = []byte("Exif\x00\x00")
+
+// ParseJPEGEXIF extracts EXIF from a JPEG file. It returns ErrNoEXIF when the
+// file has no EXIF segment.
+func ParseJPEGEXIF(data []byte) (EXIF, error) {
+	segs, err := jpegSegments(data)
+	if err != nil {
+		return EXIF{}, err
+	}
+	for _, s := range segs {
+		if s.marker == 0xE1 && bytes.HasPrefix(s.payload, exifHeader) {
+			return ParseTIFF(s.payload[len(exifHeader):])
+		}
+	}
+	return EXIF{}, ErrNoEXIF
+}
+
+// ParseTIFF reads the fields of EXIF from a TIFF structure.
+func ParseTIFF(b []byte) (EXIF, error) {
+	if len(b) < 8 {
+		return EXIF{}, errors.New("exif: short header")
+	}
+	var bo binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return EXIF{}, errors.New("exif: bad byte order")
+	}
+	if bo.Uint16(b[2:]) != 42 {
+		return EXIF{}, errors.New("exif: bad magic")
+	}
+	t := tiff{b: b, bo: bo}
+	ifd0, err := t.ifd(bo.Uint32(b[4:]))
+	if err != nil {
+		return EXIF{}, err
+	}
+	var x EXIF
+	if e, ok := ifd0[tagOrientation]; ok {
+		if v, ok := t.uint(e); ok && v >= 1 && v <= 8 {
+			x.Orientation = int(v)
+		}
+	}
+	if e, ok := ifd0[tagExifIFD]; ok {
+		if off, ok := t.uint(e); ok {
+			if sub, err := t.ifd(off); err == nil {
+				x.TakenAt = t.takenAt(sub)
+			}
+		}
+	}
+	if e, ok := ifd0[tagGPSIFD]; ok {
+		if off, ok := t.uint(e); ok {
+			if sub, err := t.ifd(off); err == nil {
+				x.Lat = t.coord(sub, tagGPSLatitude, tagGPSLatitudeRef, "S", 90)
+				x.Lon = t.coord(sub, tagGPSLongitude, tagGPSLongitudeRef, "W", 180)
+			}
+		}
+	}
+	return x, nil
+}
+
+type tiff struct {
+	b  []byte
+	bo binary.ByteOrder
+}
+
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte // the 4-byte value/offset field
+}
+
+var typeSizes = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+
+func (t tiff) ifd(off uint32) (map[uint16]ifdEntry, error) {
+	if uint64(off)+2 > uint64(len(t.b)) {
+		return nil, errors.New("exif: ifd out of range")
+	}
+	n := uint32(t.bo.Uint16(t.b[off:]))
+	start := off + 2
+	if uint64(start)+uint64(n)*12 > uint64(len(t.b)) {
+		return nil, errors.New("exif: ifd truncated")
+	}
+	m := make(map[uint16]ifdEntry, n)
+	for i := uint32(0); i < n; i++ {
+		e := t.b[start+i*12:]
+		m[t.bo.Uint16(e)] = ifdEntry{typ: t.bo.Uint16(e[2:]), count: t.bo.Uint32(e[4:]), value: e[8:12]}
+	}
+	return m, nil
+}
+
+// data returns the bytes of an entry, inline or at its offset.
+func (t tiff) data(e ifdEntry) ([]byte, bool) {
+	size, ok := typeSizes[e.typ]
+	if !ok || e.count == 0 || e.count > 1<<16 {
+		return nil, false
+	}
+	total := size * e.count
+	if total <= 4 {
+		return e.value[:total], true
+	}
+	off := t.bo.Uint32(e.value)
+	if uint64(off)+uint64(total) > uint64(len(t.b)) {
+		return nil, false
+	}
+	return t.b[off : off+total], true
+}
+
+func (t tiff) uint(e ifdEntry) (uint32, bool) {
+	d, ok := t.data(e)
+	if !ok {
+		return 0, false
+	}
+	switch e.typ {
+	case 3:
+		return uint32(t.bo.Uint16(d)), true
+	case 4:
+		return t.bo.Uint32(d), true
+	}
+	return 0, false
+}
+
+func (t tiff) ascii(e ifdEntry) (string, bool) {
+	if e.typ != 2 {
+		return "", false
+	}
+	d, ok := t.data(e)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimRight(string(d), "\x00 "), true
+}
+
+func (t tiff) rationals(e ifdEntry) ([]float64, bool) {
+	if e.typ != 5 {
+		return nil, false
+	}
+	d, ok := t.data(e)
+	if !ok {
+		return nil, false
+	}
+	out := make([]float64, e.count)
+	for i := range out {
+		num, den := t.bo.Uint32(d[i*8:]), t.bo.Uint32(d[i*8+4:])
+		if den == 0 {
+			return nil, false
+		}
+		out[i] = float64(num) / float64(den)
+	}
+	return out, true
+}
+
+func (t tiff) takenAt(ifd map[uint16]ifdEntry) *time.Time {
+	e, ok := ifd[tagDateTimeOriginal]
+	if !ok {
+		return nil
+	}
+	s, ok := t.ascii(e)
+	if !ok {
+		return nil
+	}
+	loc := time.UTC
+	if oe, ok := ifd[tagOffsetTimeOriginal]; ok {
+		if off, ok := t.ascii(oe); ok {
+			if z, err := time.Parse("-07:00", off); err == nil {
+				_, secs := z.Zone()
+				loc = time.FixedZone(off, secs)
+			}
+		}
+	}
+	ts, err := time.ParseInLocation("2006:01:02 15:04:05", s, loc)
+	if err != nil {
+		return nil
+	}
+	return &ts
+}
+
+// coord converts a degrees/minutes/seconds triple and its hemisphere
+// reference into signed decimal degrees.
+func (t tiff) coord(ifd map[uint16]ifdEntry, tag, refTag uint16, negRef string, limit float64) *float64 {
+	e, ok := ifd[tag]
+	if !ok {
+		return nil
+	}
+	dms, ok := t.rationals(e)
+	if !ok || len(dms) != 3 {
+		return nil
+	}
+	v := dms[0] + dms[1]/60 + dms[2]/3600
+	if re, ok := ifd[refTag]; ok {
+		if ref, ok := t.ascii(re); ok && ref == negRef {
+			v = -v
+		}
+	}
+	if math.IsNaN(v) || math.Abs(v) > limit {
+		return nil
+	}
+	v = math.Round(v*1e6) / 1e6
+	return &v
+}
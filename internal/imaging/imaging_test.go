@@ -0,0 +1,140 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+	"time"
+)
+
+// buildTIFF assembles a little-endian TIFF with orientation, an EXIF IFD
+// holding DateTimeOriginal/OffsetTimeOriginal and a GPS IFD.
+func buildTIFF(t *testing.T) []byte {
+	t.Helper()
+	le := binary.LittleEndian
+	b := make([]byte, 512)
+	copy(b, "II\x2a\x00\x08\x00\x00\x00")
+	entry := func(at int, tag, typ uint16, count, value uint32) {
+		le.PutUint16(b[at:], tag)
+		le.PutUint16(b[at+2:], typ)
+		le.PutUint32(b[at+4:], count)
+		le.PutUint32(b[at+8:], value)
+	}
+	// IFD0 at 8: orientation, exif pointer, gps pointer.
+	le.PutUint16(b[8:], 3)
+	entry(10, tagOrientation, 3, 1, 6)
+	entry(22, tagExifIFD, 4, 1, 100)
+	entry(34, tagGPSIFD, 4, 1, 200)
+	// EXIF IFD at 100.
+	le.PutUint16(b[100:], 2)
+	entry(102, tagDateTimeOriginal, 2, 20, 300)
+	entry(114, tagOffsetTimeOriginal, 2, 7, 330)
+	copy(b[300:], "2025:06:01 14:30:00\x00")
+	copy(b[330:], "+03:00\x00")
+	// GPS IFD at 200.
+	le.PutUint16(b[200:], 4)
+	entry(202, tagGPSLatitudeRef, 2, 2, uint32('N'))
+	entry(214, tagGPSLatitude, 5, 3, 400)
+	entry(226, tagGPSLongitudeRef, 2, 2, uint32('W'))
+	entry(238, tagGPSLongitude, 5, 3, 424)
+	rat := func(at int, vals ...uint32) {
+		for i, v := range vals {
+			le.PutUint32(b[at+i*4:], v)
+		}
+	}
+	rat(400, 55, 1, 45, 1, 36, 1) // 55°45'36" = 55.76
+	rat(424, 37, 1, 37, 1, 18, 1) // 37°37'18" = 37.621667
+	return b
+}
+
+func testJPEG(t *testing.T, w, h int, tiff []byte) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	var enc bytes.Buffer
+	if err := EncodeJPEG(&enc, img, 80); err != nil {
+		t.Fatal(err)
+	}
+	if tiff == nil {
+		return enc.Bytes()
+	}
+	var out bytes.Buffer
+	out.Write(enc.Bytes()[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	_ = binary.Write(&out, binary.BigEndian, uint16(2+len(exifHeader)+len(tiff)))
+	out.Write(exifHeader)
+	out.Write(tiff)
+	out.Write(enc.Bytes()[2:])
+	return out.Bytes()
+}
+
+func TestParseJPEGEXIF(t *testing.T) {
+	x, err := ParseJPEGEXIF(testJPEG(t, 4, 4, buildTIFF(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Orientation != 6 {
+		t.Fatalf("orientation = %d", x.Orientation)
+	}
+	want := time.Date(2025, 6, 1, 11, 30, 0, 0, time.UTC)
+	if x.TakenAt == nil || !x.TakenAt.Equal(want) {
+		t.Fatalf("taken at = %v, want %v", x.TakenAt, want)
+	}
+	if x.Lat == nil || math.Abs(*x.Lat-55.76) > 1e-6 || x.Lon == nil || math.Abs(*x.Lon+37.621667) > 1e-6 {
+		t.Fatalf("gps = %v, %v", x.Lat, x.Lon)
+	}
+	if _, err := ParseJPEGEXIF(testJPEG(t, 4, 4, nil)); err != ErrNoEXIF {
+		t.Fatalf("expected ErrNoEXIF, got %v", err)
+	}
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	var out bytes.Buffer
+	if err := StripJPEGMetadata(&out, testJPEG(t, 8, 8, buildTIFF(t))); err != nil {
+		t.Fatal(err)
+	}
+	x, err := ParseJPEGEXIF(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Orientation != 6 || x.TakenAt != nil || x.Lat != nil {
+		t.Fatalf("only orientation should survive, got %+v", x)
+	}
+	if _, err := Decode(out.Bytes(), 1<<20); err != nil {
+		t.Fatalf("stripped file does not decode: %v", err)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 200; x++ {
+		for y := 0; y < 200; y++ {
+			src.Set(x, y, color.RGBA{255, 0, 0, 255}) // left half red
+		}
+	}
+	th := Thumbnail(src, 100, 1)
+	if th.Rect.Dx() != 100 || th.Rect.Dy() != 50 {
+		t.Fatalf("size = %v", th.Rect)
+	}
+	if c := th.RGBAAt(10, 10); c.R != 255 || c.G != 0 {
+		t.Fatalf("left pixel = %v", c)
+	}
+	rot := Thumbnail(src, 100, 6) // rotate 90 clockwise: red ends up on top
+	if rot.Rect.Dx() != 50 || rot.Rect.Dy() != 100 {
+		t.Fatalf("rotated size = %v", rot.Rect)
+	}
+	if c := rot.RGBAAt(25, 10); c.R != 255 || c.G != 0 {
+		t.Fatalf("top pixel after rotation = %v", c)
+	}
+	if small := Thumbnail(image.NewRGBA(image.Rect(0, 0, 10, 5)), 100, 1); small.Rect.Dx() != 10 {
+		t.Fatalf("must not upscale, got %v", small.Rect)
+	}
+}
+
+func TestDecodeRejectsHugeImages(t *testing.T) {
+	if _, err := Decode(testJPEG(t, 64, 64, nil), 1000); err != ErrTooLarge {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+}
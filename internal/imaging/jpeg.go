@@ -0,0 +1,96 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var ErrNotJPEG = errors.New("not a jpeg file")
+
+type jpegSegment struct {
+	marker  byte
+	payload []byte // without the marker and length bytes
+}
+
+// jpegSegments splits the header of a JPEG file into its marker segments, up
+// to (not including) the start of scan.
+func jpegSegments(data []byte) ([]jpegSegment, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrNotJPEG
+	}
+	var segs []jpegSegment
+	for p := 2; p+4 <= len(data); {
+		if data[p] != 0xFF {
+			return nil, errors.New("jpeg: bad marker")
+		}
+		marker := data[p+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return segs, nil
+		}
+		n := int(binary.BigEndian.Uint16(data[p+2:]))
+		if n < 2 || p+2+n > len(data) {
+			return nil, errors.New("jpeg: truncated segment")
+		}
+		segs = append(segs, jpegSegment{marker: marker, payload: data[p+4 : p+2+n]})
+		p += 2 + n
+	}
+	return segs, nil
+}
+
+// StripJPEGMetadata writes the JPEG without its EXIF/XMP (APP1) and IPTC
+// (APP13) segments. A non-default orientation is kept in a minimal EXIF
+// segment so that viewers still rotate the photo correctly.
+func StripJPEGMetadata(w io.Writer, data []byte) error {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return ErrNotJPEG
+	}
+	orientation := 0
+	if x, err := ParseJPEGEXIF(data); err == nil {
+		orientation = x.Orientation
+	}
+	var out bytes.Buffer
+	out.Write(data[:2])
+	if orientation > 1 {
+		writeOrientationSegment(&out, orientation)
+	}
+	p := 2
+	for p+4 <= len(data) {
+		if data[p] != 0xFF {
+			return errors.New("jpeg: bad marker")
+		}
+		marker := data[p+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		n := int(binary.BigEndian.Uint16(data[p+2:]))
+		if n < 2 || p+2+n > len(data) {
+			return errors.New("jpeg: truncated segment")
+		}
+		if marker != 0xE1 && marker != 0xED {
+			out.Write(data[p : p+2+n])
+		}
+		p += 2 + n
+	}
+	out.Write(data[p:])
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// writeOrientationSegment emits an APP1 segment whose TIFF structure holds
+// only the orientation tag.
+func writeOrientationSegment(b *bytes.Buffer, orientation int) {
+	var tiff [26]byte
+	copy(tiff[:], "MM\x00\x2a\x00\x00\x00\x08") // big endian, IFD0 at offset 8
+	binary.BigEndian.PutUint16(tiff[8:], 1)     // one entry
+	binary.BigEndian.PutUint16(tiff[10:], tagOrientation)
+	binary.BigEndian.PutUint16(tiff[12:], 3) // SHORT
+	binary.BigEndian.PutUint32(tiff[14:], 1)
+	binary.BigEndian.PutUint16(tiff[18:], uint16(orientation))
+	// tiff[22:26] is the zero offset of the next IFD.
+	b.Write([]byte{0xFF, 0xE1})
+	_ = binary.Write(b, binary.BigEndian, uint16(2+len(exifHeader)+len(tiff)))
+	b.Write(exifHeader)
+	b.Write(tiff[:])
+}
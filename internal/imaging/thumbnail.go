@@ -0,0 +1,133 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // register decoders for image.Decode
+	"image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+var ErrTooLarge = errors.New("image dimensions too large")
+
+// Decode decodes a JPEG, PNG or GIF image, refusing images with more than
+// maxPixels pixels before allocating them.
+func Decode(data []byte, maxPixels int) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Thumbnail scales img down so that its longer side is at most maxSide (it
+// never upscales), applies the EXIF orientation and flattens transparency
+// onto white.
+func Thumbnail(img image.Image, maxSide, orientation int) *image.RGBA {
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Over)
+
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if long := max(w, h); long > maxSide {
+		dw = max(1, (w*maxSide+long/2)/long)
+		dh = max(1, (h*maxSide+long/2)/long)
+	}
+	return orient(boxScale(src, dw, dh), orientation)
+}
+
+// boxScale downsamples by averaging the source pixels covered by each
+// destination pixel.
+func boxScale(src *image.RGBA, dw, dh int) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if dw == w && dh == h {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		y0 := dy * h / dh
+		y1 := max(y0+1, (dy+1)*h/dh)
+		for dx := 0; dx < dw; dx++ {
+			x0 := dx * w / dw
+			x1 := max(x0+1, (dx+1)*w/dw)
+			var r, g, bl, a, n int
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < x1; x++ {
+					p := row[x*4 : x*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					bl += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+			o := dst.PixOffset(dx, dy)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(bl / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// orient applies the EXIF orientation (2..8) so the result displays upright.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirror horizontal
+				sx, sy = w-1-x, y
+			case 3: // rotate 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirror vertical
+				sx, sy = x, h-1-y
+			case 5: // transpose
+				sx, sy = y, x
+			case 6: // rotate 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // transverse
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotate 90 counter-clockwise
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], src.Pix[src.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
+
+// EncodeJPEG writes img as a JPEG of the given quality (1..100).
+func EncodeJPEG(w io.Writer, img image.Image, quality int) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
+
+// Supported reports whether thumbnails can be made for the MIME type.
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
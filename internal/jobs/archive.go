@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/storage"
+)
+
+const archiveBatch = 100
+
+// RunArchiver archives done and cancelled orders that have not changed for
+// longer than after. It blocks until ctx is cancelled.
+func RunArchiver(ctx context.Context, db *sql.DB, interval, after time.Duration) {
+	if interval <= 0 || after <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		total := 0
+		for {
+			n, err := ArchiveOrders(ctx, db, time.Now(), after)
+			if err != nil {
+				log.Printf("archiver: %v", err)
+				break
+			}
+			total += n
+			if n < archiveBatch {
+				break
+			}
+		}
+		if total > 0 {
+			log.Printf("archiver: %d order(s) archived", total)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// ArchiveOrders archives one batch and returns how many orders it archived.
+// Each order gets an order.archived event in the same transaction.
+func ArchiveOrders(ctx context.Context, db *sql.DB, now time.Time, after time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	repo := storage.NewOrderRepository(tx)
+	orders, err := repo.ListArchivable(ctx, now.Add(-after), archiveBatch)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, o := range orders {
+		ok, err := repo.MarkArchived(ctx, o.ID, now)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderArchived, map[string]any{
+			"id":      o.ID,
+			"user_id": o.UserID,
+			"status":  o.Status,
+		}); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"frame_control_system/internal/blobstore"
+	"frame_control_system/internal/storage"
+)
+
+// RunBlobSweeper deletes blobs that no attachment, thumbnail or defect photo
+// references any more and that were stored longer than grace ago. Handlers
+// never delete blobs themselves: an identical upload may be about to reuse
+// one. It blocks until ctx is cancelled.
+func RunBlobSweeper(ctx context.Context, db *sql.DB, blobs blobstore.Store, interval, grace time.Duration) {
+	if interval <= 0 || grace <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if n, err := SweepBlobs(ctx, db, blobs, time.Now(), grace); err != nil {
+			log.Printf("blob sweeper: %v", err)
+		} else if n > 0 {
+			log.Printf("blob sweeper: %d blob(s) deleted", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// SweepBlobs runs one pass and returns how many blobs it deleted.
+func SweepBlobs(ctx context.Context, db *sql.DB, blobs blobstore.Store, now time.Time, grace time.Duration) (int, error) {
+	repo := storage.NewAttachmentRepository(db)
+	return blobs.Sweep(ctx, now.Add(-grace), repo.BlobInUse)
+}
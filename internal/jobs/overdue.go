@@ -0,0 +1,79 @@
+// Package jobs holds background workers started by cmd/server.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"frame_control_system/internal/events"
+	"frame_control_system/internal/storage"
+)
+
+// overdueBatch caps how many orders a single tick handles.
+const overdueBatch = 100
+
+// RunOverdueChecker emits order.overdue once per order that passes its due
+// date while still open. It blocks until ctx is cancelled.
+func RunOverdueChecker(ctx context.Context, db *sql.DB, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if n, err := CheckOverdue(ctx, db, time.Now()); err != nil {
+			log.Printf("overdue checker: %v", err)
+		} else if n > 0 {
+			log.Printf("overdue checker: %d order(s) flagged", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// CheckOverdue runs one pass and returns how many events were emitted. The
+// flag and the outbox event are written in the same transaction, so an order
+// is reported exactly once even if several instances run the checker.
+func CheckOverdue(ctx context.Context, db *sql.DB, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	repo := storage.NewOrderRepository(tx)
+	orders, err := repo.ListOverdueUnnotified(ctx, now, overdueBatch)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, o := range orders {
+		ok, err := repo.MarkOverdueNotified(ctx, o.ID, now)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+		if err := storage.AddOutboxEvent(ctx, tx, events.OrderOverdue, map[string]any{
+			"id":       o.ID,
+			"user_id":  o.UserID,
+			"status":   o.Status,
+			"priority": o.Priority,
+			"due_at":   o.DueAt.UTC().Format(time.RFC3339),
+		}); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
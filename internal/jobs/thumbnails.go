@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"frame_control_system/internal/blobstore"
+	"frame_control_system/internal/imaging"
+	"frame_control_system/internal/models"
+	"frame_control_system/internal/storage"
+)
+
+const (
+	thumbnailBatch   = 10
+	thumbnailQuality = 80
+	// maxImagePixels guards against decompression bombs (about 50 MP).
+	maxImagePixels = 50_000_000
+	// maxImageBytes bounds how much of an original is read into memory.
+	maxImageBytes = 64 << 20
+)
+
+// RunThumbnailWorker makes thumbnails of the given sizes (longer side, px)
+// for pending image attachments and extracts their EXIF data. It blocks until
+// ctx is cancelled.
+func RunThumbnailWorker(ctx context.Context, db *sql.DB, blobs blobstore.Store, sizes []int, interval time.Duration) {
+	if interval <= 0 || len(sizes) == 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		for {
+			n, err := ProcessPendingMedia(ctx, db, blobs, sizes)
+			if err != nil {
+				log.Printf("thumbnail worker: %v", err)
+			}
+			if err != nil || n < thumbnailBatch {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// ProcessPendingMedia handles one batch and returns how many attachments it
+// looked at. A broken image marks its attachment as failed without stopping
+// the batch.
+func ProcessPendingMedia(ctx context.Context, db *sql.DB, blobs blobstore.Store, sizes []int) (int, error) {
+	repo := storage.NewAttachmentRepository(db)
+	list, err := repo.ListPendingMedia(ctx, thumbnailBatch)
+	if err != nil {
+		return 0, err
+	}
+	for _, a := range list {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		a.MediaStatus = models.MediaReady
+		if err := processImage(ctx, repo, blobs, &a, sizes); err != nil {
+			log.Printf("thumbnail worker: attachment %s: %v", a.ID, err)
+			a.MediaStatus = models.MediaFailed
+		}
+		if err := repo.SetMedia(ctx, a); err != nil {
+			return 0, err
+		}
+	}
+	return len(list), nil
+}
+
+func processImage(ctx context.Context, repo *storage.AttachmentRepository, blobs blobstore.Store, a *models.Attachment, sizes []int) error {
+	rc, err := blobs.Open(ctx, a.BlobKey)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, maxImageBytes+1))
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	if len(data) > maxImageBytes {
+		return errors.New("image file too large")
+	}
+	var orientation int
+	if a.ContentType == "image/jpeg" {
+		if x, err := imaging.ParseJPEGEXIF(data); err == nil {
+			orientation = x.Orientation
+			a.TakenAt = x.TakenAt
+			if x.Lat != nil && x.Lon != nil {
+				a.Location = &models.GeoPoint{Lat: *x.Lat, Lon: *x.Lon}
+			}
+		}
+	}
+	img, err := imaging.Decode(data, maxImagePixels)
+	if err != nil {
+		return err
+	}
+	for _, size := range sizes {
+		th := imaging.Thumbnail(img, size, orientation)
+		var buf bytes.Buffer
+		if err := imaging.EncodeJPEG(&buf, th, thumbnailQuality); err != nil {
+			return err
+		}
+		key, _, err := blobs.Put(ctx, &buf)
+		if err != nil {
+			return err
+		}
+		t := models.Thumbnail{Size: size, Width: th.Rect.Dx(), Height: th.Rect.Dy(), BlobKey: key}
+		if err := repo.AddThumbnail(ctx, a.ID, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
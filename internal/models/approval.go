@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// ApprovalRule sends matching orders to approval. An order matches when its
+// total reaches MinTotal or one of its items has one of the Categories. The
+// rule asks for an approval at Level by a user with ApproverRole; levels are
+// decided in ascending order.
+type ApprovalRule struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	MinTotal     *float64  `json:"min_total,omitempty"`
+	Categories   []string  `json:"categories"`
+	Level        int       `json:"level"`
+	ApproverRole string    `json:"approver_role"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Matches reports whether the order falls under the rule.
+func (r ApprovalRule) Matches(o Order) bool {
+	if r.MinTotal != nil && o.TotalAmount >= *r.MinTotal {
+		return true
+	}
+	for _, it := range o.Items {
+		for _, c := range r.Categories {
+			if it.Category == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+	// ApprovalCancelled closes steps left open by a rejection, a cancelled
+	// order or a new approval round.
+	ApprovalCancelled ApprovalStatus = "cancelled"
+)
+
+// OrderApproval is one step of an order's approval: a decision expected from
+// a user with ApproverRole at Level. Rule names are copied, so later rule
+// changes do not affect it. Every request for approval starts a new Round.
+type OrderApproval struct {
+	ID           string         `json:"id"`
+	OrderID      string         `json:"order_id"`
+	Round        int            `json:"round"`
+	Level        int            `json:"level"`
+	ApproverRole string         `json:"approver_role"`
+	Rules        []string       `json:"rules"`
+	Status       ApprovalStatus `json:"status"`
+	DecidedBy    string         `json:"decided_by,omitempty"`
+	DecidedAt    *time.Time     `json:"decided_at,omitempty"`
+	Comment      string         `json:"comment,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
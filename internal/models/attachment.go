@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Media processing states of an attachment.
+const (
+	MediaNone        = "none" // not an image
+	MediaPending     = "pending"
+	MediaReady       = "ready"
+	MediaFailed      = "failed"
+	MediaUnsupported = "unsupported" // an image format without a decoder
+)
+
+type Attachment struct {
+	ID          string    `json:"id"`
+	OrderID     string    `json:"order_id"`
+	BlobKey     string    `json:"-"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	UploadedBy  string    `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Photo metadata, filled in by the thumbnail worker.
+	MediaStatus string      `json:"media_status"`
+	TakenAt     *time.Time  `json:"taken_at,omitempty"`
+	Location    *GeoPoint   `json:"location,omitempty"`
+	Thumbnails  []Thumbnail `json:"thumbnails,omitempty"`
+}
+
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type Thumbnail struct {
+	Size    int    `json:"size"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	BlobKey string `json:"-"`
+	URL     string `json:"url"`
+}
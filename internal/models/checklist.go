@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+type ChecklistTemplateItem struct {
+	Text      string `json:"text"`
+	Mandatory bool   `json:"mandatory"`
+}
+
+// ChecklistTemplate is an admin-defined list of inspection items that can be
+// attached to orders.
+type ChecklistTemplate struct {
+	ID          string                  `json:"id"`
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Items       []ChecklistTemplateItem `json:"items"`
+	CreatedBy   string                  `json:"created_by"`
+	CreatedAt   time.Time               `json:"created_at"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+}
+
+// Checklist is a template instantiated on an order. Items are copied, so
+// later template changes do not affect it.
+type Checklist struct {
+	ID         string          `json:"id"`
+	OrderID    string          `json:"order_id"`
+	TemplateID string          `json:"template_id,omitempty"`
+	Name       string          `json:"name"`
+	Items      []ChecklistItem `json:"items"`
+	// MandatoryOpen counts mandatory items not checked yet; computed on read.
+	MandatoryOpen int       `json:"mandatory_open"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ChecklistItem struct {
+	ID          string     `json:"id"`
+	ChecklistID string     `json:"checklist_id"`
+	Position    int        `json:"position"`
+	Text        string     `json:"text"`
+	Mandatory   bool       `json:"mandatory"`
+	Checked     bool       `json:"checked"`
+	CheckedBy   string     `json:"checked_by,omitempty"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type Comment struct {
+	ID       string `json:"id"`
+	OrderID  string `json:"order_id"`
+	AuthorID string `json:"author_id"`
+	Body     string `json:"body"`
+	// Mentions holds the ids of users mentioned as @email in the body.
+	Mentions  []string   `json:"mentions"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type CustomFieldType string
+
+const (
+	CustomFieldText   CustomFieldType = "text"
+	CustomFieldNumber CustomFieldType = "number"
+	CustomFieldDate   CustomFieldType = "date" // YYYY-MM-DD
+	CustomFieldEnum   CustomFieldType = "enum"
+)
+
+func (t CustomFieldType) Valid() bool {
+	switch t {
+	case CustomFieldText, CustomFieldNumber, CustomFieldDate, CustomFieldEnum:
+		return true
+	}
+	return false
+}
+
+// CustomFieldDefinition describes an attribute orders may carry in
+// Order.CustomFields under Key.
+type CustomFieldDefinition struct {
+	Key       string          `json:"key"`
+	Label     string          `json:"label"`
+	Type      CustomFieldType `json:"type"`
+	Options   []string        `json:"options,omitempty"` // enum only
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+type DefectSeverity string
+
+const (
+	DefectSeverityLow      DefectSeverity = "low"
+	DefectSeverityMedium   DefectSeverity = "medium"
+	DefectSeverityHigh     DefectSeverity = "high"
+	DefectSeverityCritical DefectSeverity = "critical"
+)
+
+func (s DefectSeverity) Valid() bool {
+	switch s {
+	case DefectSeverityLow, DefectSeverityMedium, DefectSeverityHigh, DefectSeverityCritical:
+		return true
+	}
+	return false
+}
+
+type DefectStatus string
+
+const (
+	DefectStatusNew      DefectStatus = "new"
+	DefectStatusInWork   DefectStatus = "in_work"
+	DefectStatusOnReview DefectStatus = "on_review"
+	DefectStatusClosed   DefectStatus = "closed"
+	DefectStatusRejected DefectStatus = "rejected"
+)
+
+func (s DefectStatus) Valid() bool {
+	switch s {
+	case DefectStatusNew, DefectStatusInWork, DefectStatusOnReview, DefectStatusClosed, DefectStatusRejected:
+		return true
+	}
+	return false
+}
+
+// Defect is a construction defect registered by an engineer within a project.
+type Defect struct {
+	ID          string         `json:"id"`
+	ProjectID   string         `json:"project_id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Severity    DefectSeverity `json:"severity"`
+	// Location is free text within the project: building, floor, axis, room.
+	Location      string        `json:"location"`
+	ResponsibleID string        `json:"responsible_id,omitempty"`
+	DueAt         *time.Time    `json:"due_at"`
+	Status        DefectStatus  `json:"status"`
+	Overdue       bool          `json:"overdue"` // computed on read
+	ReportedBy    string        `json:"reported_by"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	Photos        []DefectPhoto `json:"photos"`
+}
+
+// Open reports whether the defect still needs work; closed and rejected are final.
+func (d Defect) Open() bool {
+	return d.Status != DefectStatusClosed && d.Status != DefectStatusRejected
+}
+
+// IsOverdue reports whether the defect is open and past its deadline at now.
+func (d Defect) IsOverdue(now time.Time) bool {
+	return d.DueAt != nil && d.Open() && d.DueAt.Before(now)
+}
+
+type DefectPhoto struct {
+	ID          string    `json:"id"`
+	DefectID    string    `json:"defect_id"`
+	BlobKey     string    `json:"-"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	UploadedBy  string    `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	URL         string    `json:"url"`
+}
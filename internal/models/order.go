@@ -6,25 +6,102 @@ type OrderItem struct {
 	Name     string  `json:"name"`
 	Quantity int     `json:"quantity"`
 	Price    float64 `json:"price"`
+	// Category selects the VAT rate; empty uses the default rate.
+	Category        string  `json:"category,omitempty"`
+	DiscountPercent float64 `json:"discount_percent,omitempty"`
 }
 
 type OrderStatus string
 
 const (
-	OrderStatusCreated    OrderStatus = "created"
-	OrderStatusInProgress OrderStatus = "in_progress"
-	OrderStatusDone       OrderStatus = "done"
-	OrderStatusCancelled  OrderStatus = "cancelled"
+	OrderStatusCreated         OrderStatus = "created"
+	OrderStatusPendingApproval OrderStatus = "pending_approval"
+	OrderStatusInProgress      OrderStatus = "in_progress"
+	OrderStatusDone            OrderStatus = "done"
+	OrderStatusCancelled       OrderStatus = "cancelled"
 )
 
+type OrderPriority string
+
+const (
+	OrderPriorityLow    OrderPriority = "low"
+	OrderPriorityNormal OrderPriority = "normal"
+	OrderPriorityHigh   OrderPriority = "high"
+	OrderPriorityUrgent OrderPriority = "urgent"
+)
+
+func (p OrderPriority) Valid() bool {
+	switch p {
+	case OrderPriorityLow, OrderPriorityNormal, OrderPriorityHigh, OrderPriorityUrgent:
+		return true
+	}
+	return false
+}
+
 type Order struct {
-	ID          string       `json:"id"`
-	UserID      string       `json:"user_id"`
-	Items       []OrderItem  `json:"items"`
-	Status      OrderStatus  `json:"status"`
-	TotalAmount float64      `json:"total_amount"`
-	CreatedAt   time.Time    `json:"created_at"`
-	UpdatedAt   time.Time    `json:"updated_at"`
+	ID          string        `json:"id"`
+	UserID      string        `json:"user_id"`
+	AssigneeID  string        `json:"assignee_id,omitempty"`
+	ProjectID   string        `json:"project_id,omitempty"`
+	Items       []OrderItem   `json:"items"`
+	Status      OrderStatus   `json:"status"`
+	TotalAmount float64       `json:"total_amount"`
+	Notes       string        `json:"notes"`
+	DueAt       *time.Time    `json:"due_at"`
+	Priority    OrderPriority `json:"priority"`
+	Overdue     bool          `json:"overdue"` // computed on read
+	// DurationDays is the planned duration used by the project schedule; 0 when unknown.
+	DurationDays int      `json:"duration_days"`
+	LoggedHours  float64  `json:"logged_hours"` // finished time entries, computed on read
+	Tags         []string `json:"tags"`
+	// CustomFields holds values of admin-defined fields: strings for text,
+	// date and enum fields, numbers for number fields.
+	CustomFields map[string]any `json:"custom_fields"`
+	// Order-level discounts, applied after line discounts: percent first, then fixed.
+	DiscountPercent float64        `json:"discount_percent"`
+	DiscountFixed   float64        `json:"discount_fixed"`
+	Promo           *AppliedPromo  `json:"promo,omitempty"`
+	Breakdown       PriceBreakdown `json:"breakdown"`
+	// ArchivedAt hides finished orders from default lists; DeletedAt moves
+	// them to the admin-only trash. Both are cleared by a restore.
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Open reports whether the order is still being worked on. Orders waiting
+// for approval count as open.
+func (o Order) Open() bool {
+	return o.Status == OrderStatusCreated || o.Status == OrderStatusPendingApproval || o.Status == OrderStatusInProgress
+}
+
+// IsOverdue reports whether the order is open and past its due date at now.
+func (o Order) IsOverdue(now time.Time) bool {
+	return o.DueAt != nil && o.Open() && o.DueAt.Before(now)
+}
+
+// OrderViewer grants a user read-only access to a single order.
+type OrderViewer struct {
+	OrderID string    `json:"order_id"`
+	UserID  string    `json:"user_id"`
+	AddedBy string    `json:"added_by"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// OrderDependency means the order cannot start until the predecessor is done.
+type OrderDependency struct {
+	OrderID       string    `json:"order_id"`
+	PredecessorID string    `json:"predecessor_id"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// OrderStatusChange is one entry of the order status history.
+type OrderStatusChange struct {
+	From      OrderStatus `json:"from"`
+	To        OrderStatus `json:"to"`
+	ChangedAt time.Time   `json:"changed_at"`
 }
 
 
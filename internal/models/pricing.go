@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+type PromoKind string
+
+const (
+	PromoPercent PromoKind = "percent"
+	PromoFixed   PromoKind = "fixed"
+)
+
+type PromoCode struct {
+	Code  string    `json:"code"`
+	Kind  PromoKind `json:"kind"`
+	Value float64   `json:"value"`
+	// ValidFrom is inclusive, ValidTo exclusive; nil means unbounded.
+	ValidFrom *time.Time `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to"`
+	MaxUses   *int       `json:"max_uses"` // nil means unlimited
+	UsedCount int        `json:"used_count"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Unavailable explains why the code cannot be redeemed at now; "" means it can.
+func (p PromoCode) Unavailable(now time.Time) string {
+	switch {
+	case !p.Active:
+		return "promo code is not active"
+	case p.ValidFrom != nil && now.Before(*p.ValidFrom):
+		return "promo code is not valid yet"
+	case p.ValidTo != nil && !now.Before(*p.ValidTo):
+		return "promo code has expired"
+	case p.MaxUses != nil && p.UsedCount >= *p.MaxUses:
+		return "promo code usage limit reached"
+	}
+	return ""
+}
+
+// AppliedPromo is the promo code as it was when the order redeemed it, so later
+// changes to the code do not reprice the order.
+type AppliedPromo struct {
+	Code  string    `json:"code"`
+	Kind  PromoKind `json:"kind"`
+	Value float64   `json:"value"`
+}
+
+// PriceBreakdown is how the order total was computed. All amounts are rounded
+// to cents; Total = Subtotal - Discount + Tax.
+type PriceBreakdown struct {
+	Subtotal      float64 `json:"subtotal"` // sum of quantity * price
+	LineDiscount  float64 `json:"line_discount"`
+	OrderDiscount float64 `json:"order_discount"`
+	PromoDiscount float64 `json:"promo_discount"`
+	Discount      float64 `json:"discount"` // all discounts together
+	Tax           float64 `json:"tax"`
+	Total         float64 `json:"total"`
+}
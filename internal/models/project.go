@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+type ProjectStatus string
+
+const (
+	ProjectStatusPlanned   ProjectStatus = "planned"
+	ProjectStatusActive    ProjectStatus = "active"
+	ProjectStatusOnHold    ProjectStatus = "on_hold"
+	ProjectStatusCompleted ProjectStatus = "completed"
+	ProjectStatusCancelled ProjectStatus = "cancelled"
+)
+
+func (s ProjectStatus) Valid() bool {
+	switch s {
+	case ProjectStatusPlanned, ProjectStatusActive, ProjectStatusOnHold, ProjectStatusCompleted, ProjectStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// ProjectRole is the role of a user within one project, independent of the
+// global user roles.
+type ProjectRole string
+
+const (
+	// ProjectRoleManager may edit the project and manage its members.
+	ProjectRoleManager  ProjectRole = "manager"
+	ProjectRoleEngineer ProjectRole = "engineer"
+	ProjectRoleExecutor ProjectRole = "executor"
+	// ProjectRoleViewer only follows the progress, e.g. the customer.
+	ProjectRoleViewer ProjectRole = "viewer"
+)
+
+func (r ProjectRole) Valid() bool {
+	switch r {
+	case ProjectRoleManager, ProjectRoleEngineer, ProjectRoleExecutor, ProjectRoleViewer:
+		return true
+	}
+	return false
+}
+
+// Project is a construction object (site) that orders belong to.
+type Project struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+	Customer string `json:"customer"`
+	// StartDate and EndDate are calendar dates (YYYY-MM-DD), empty when unknown.
+	StartDate string        `json:"start_date,omitempty"`
+	EndDate   string        `json:"end_date,omitempty"`
+	Status    ProjectStatus `json:"status"`
+	CreatedBy string        `json:"created_by"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+type ProjectMember struct {
+	ProjectID string      `json:"project_id"`
+	UserID    string      `json:"user_id"`
+	Role      ProjectRole `json:"role"`
+	AddedBy   string      `json:"added_by"`
+	AddedAt   time.Time   `json:"added_at"`
+}
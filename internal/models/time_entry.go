@@ -0,0 +1,36 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// TimeEntry is labor logged by a user on an order, either entered by hand or
+// recorded with the start/stop timer. EndedAt is nil while the timer runs.
+type TimeEntry struct {
+	ID              string     `json:"id"`
+	OrderID         string     `json:"order_id"`
+	UserID          string     `json:"user_id"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	DurationSeconds int64      `json:"duration_seconds"`
+	Hours           float64    `json:"hours"`
+	Running         bool       `json:"running"`
+	Note            string     `json:"note"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// TimeTotal sums finished time entries of one user or one order.
+type TimeTotal struct {
+	UserID          string  `json:"user_id,omitempty"`
+	OrderID         string  `json:"order_id,omitempty"`
+	Entries         int     `json:"entries"`
+	DurationSeconds int64   `json:"duration_seconds"`
+	Hours           float64 `json:"hours"`
+}
+
+// Hours converts seconds to hours rounded to two decimals, the precision
+// labor is billed with.
+func Hours(seconds int64) float64 {
+	return math.Round(float64(seconds)/36) / 100
+}
@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"frame_control_system/internal/models"
+)
+
+type ApprovalRepository struct {
+	db DBTX
+}
+
+func NewApprovalRepository(db DBTX) *ApprovalRepository {
+	return &ApprovalRepository{db: db}
+}
+
+const approvalRuleColumns = `id, name, min_total, categories, level, approver_role, created_by, created_at, updated_at`
+
+func scanApprovalRule(row rowScanner) (models.ApprovalRule, error) {
+	var ar models.ApprovalRule
+	var minTotal sql.NullFloat64
+	var categories, createdAt, updatedAt string
+	if err := row.Scan(&ar.ID, &ar.Name, &minTotal, &categories, &ar.Level, &ar.ApproverRole, &ar.CreatedBy, &createdAt, &updatedAt); err != nil {
+		return models.ApprovalRule{}, err
+	}
+	if minTotal.Valid {
+		ar.MinTotal = &minTotal.Float64
+	}
+	_ = json.Unmarshal([]byte(categories), &ar.Categories)
+	if ar.Categories == nil {
+		ar.Categories = []string{}
+	}
+	ar.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	ar.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return ar, nil
+}
+
+func stringsJSON(v []string) string {
+	if v == nil {
+		v = []string{}
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func nullFloat(f *float64) any {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
+
+// CreateRule fills in ID and timestamps and stores the rule.
+func (r *ApprovalRepository) CreateRule(ctx context.Context, ar *models.ApprovalRule) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	ar.ID = uuid.NewString()
+	ar.CreatedAt, ar.UpdatedAt = now, now
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO approval_rules (id, name, min_total, categories, level, approver_role, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, ar.ID, ar.Name, nullFloat(ar.MinTotal), stringsJSON(ar.Categories), ar.Level, ar.ApproverRole, ar.CreatedBy,
+		now.Format(time.RFC3339), now.Format(time.RFC3339))
+	return err
+}
+
+// GetRule returns the rule; sql.ErrNoRows if there is none.
+func (r *ApprovalRepository) GetRule(ctx context.Context, id string) (*models.ApprovalRule, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+approvalRuleColumns+` FROM approval_rules WHERE id = ?`, id)
+	ar, err := scanApprovalRule(row)
+	if err != nil {
+		return nil, err
+	}
+	return &ar, nil
+}
+
+// ListRules returns all rules ordered by level and name.
+func (r *ApprovalRepository) ListRules(ctx context.Context) ([]models.ApprovalRule, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+approvalRuleColumns+` FROM approval_rules ORDER BY level, name, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.ApprovalRule{}
+	for rows.Next() {
+		ar, err := scanApprovalRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, ar)
+	}
+	return res, rows.Err()
+}
+
+func (r *ApprovalRepository) UpdateRule(ctx context.Context, ar *models.ApprovalRule) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE approval_rules SET name = ?, min_total = ?, categories = ?, level = ?, approver_role = ?, updated_at = ?
+		WHERE id = ?
+	`, ar.Name, nullFloat(ar.MinTotal), stringsJSON(ar.Categories), ar.Level, ar.ApproverRole, now.Format(time.RFC3339), ar.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	ar.UpdatedAt = now
+	return nil
+}
+
+// DeleteRule removes the rule; approvals already requested under it stay.
+func (r *ApprovalRepository) DeleteRule(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM approval_rules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+const orderApprovalColumns = `id, order_id, round, level, approver_role, rules, status, decided_by, decided_at, comment, created_at`
+
+func scanOrderApproval(row rowScanner) (models.OrderApproval, error) {
+	var a models.OrderApproval
+	var rules, status, createdAt string
+	var decidedBy, decidedAt sql.NullString
+	if err := row.Scan(&a.ID, &a.OrderID, &a.Round, &a.Level, &a.ApproverRole, &rules, &status, &decidedBy, &decidedAt, &a.Comment, &createdAt); err != nil {
+		return models.OrderApproval{}, err
+	}
+	_ = json.Unmarshal([]byte(rules), &a.Rules)
+	if a.Rules == nil {
+		a.Rules = []string{}
+	}
+	a.Status = models.ApprovalStatus(status)
+	a.DecidedBy = decidedBy.String
+	a.DecidedAt = parseNullTime(decidedAt)
+	a.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	return a, nil
+}
+
+func (r *ApprovalRepository) query(ctx context.Context, query string, args ...any) ([]models.OrderApproval, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.OrderApproval{}
+	for rows.Next() {
+		a, err := scanOrderApproval(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
+
+// StartRound closes whatever is still pending for the order and stores the
+// steps as its next approval round, filling in IDs, round and timestamps.
+func (r *ApprovalRepository) StartRound(ctx context.Context, orderID string, steps []models.OrderApproval) error {
+	if err := r.CancelPending(ctx, orderID); err != nil {
+		return err
+	}
+	var round int
+	if err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(round), 0) + 1 FROM order_approvals WHERE order_id = ?`, orderID).Scan(&round); err != nil {
+		return err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := range steps {
+		a := &steps[i]
+		a.ID, a.OrderID, a.Round, a.Status, a.CreatedAt = uuid.NewString(), orderID, round, models.ApprovalPending, now
+		if _, err := r.db.ExecContext(ctx, `
+			INSERT INTO order_approvals (id, order_id, round, level, approver_role, rules, status, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, a.ID, a.OrderID, a.Round, a.Level, a.ApproverRole, stringsJSON(a.Rules), string(a.Status), now.Format(time.RFC3339)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListByOrder returns the approval steps of all rounds, oldest round first.
+func (r *ApprovalRepository) ListByOrder(ctx context.Context, orderID string) ([]models.OrderApproval, error) {
+	return r.query(ctx, `
+		SELECT `+orderApprovalColumns+` FROM order_approvals WHERE order_id = ? ORDER BY round, level, approver_role
+	`, orderID)
+}
+
+// CurrentRound returns the steps of the order's latest approval round.
+func (r *ApprovalRepository) CurrentRound(ctx context.Context, orderID string) ([]models.OrderApproval, error) {
+	return r.query(ctx, `
+		SELECT `+orderApprovalColumns+`
+		FROM order_approvals
+		WHERE order_id = ? AND round = (SELECT MAX(round) FROM order_approvals WHERE order_id = ?)
+		ORDER BY level, approver_role
+	`, orderID, orderID)
+}
+
+// Decide records the decision on a pending step; sql.ErrNoRows if the step
+// was decided in the meantime.
+func (r *ApprovalRepository) Decide(ctx context.Context, a *models.OrderApproval, status models.ApprovalStatus, userID, comment string, at time.Time) error {
+	at = at.UTC().Truncate(time.Second)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE order_approvals SET status = ?, decided_by = ?, decided_at = ?, comment = ?
+		WHERE id = ? AND status = ?
+	`, string(status), userID, at.Format(time.RFC3339), comment, a.ID, string(models.ApprovalPending))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	a.Status, a.DecidedBy, a.DecidedAt, a.Comment = status, userID, &at, comment
+	return nil
+}
+
+// CancelPending closes the order's pending steps without a decision.
+func (r *ApprovalRepository) CancelPending(ctx context.Context, orderID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE order_approvals SET status = ? WHERE order_id = ? AND status = ?
+	`, string(models.ApprovalCancelled), orderID, string(models.ApprovalPending))
+	return err
+}
+
+// Queue returns the steps waiting for userID: pending steps at the lowest
+// open level of the latest round of orders awaiting approval, for one of the
+// given roles (any role if anyRole is set). Orders of the user and orders
+// where the user already approved a level are left out.
+func (r *ApprovalRepository) Queue(ctx context.Context, userID string, roles []string, anyRole bool, limit int) ([]models.OrderApproval, error) {
+	where := []string{
+		"a.status = 'pending'",
+		"o.status = 'pending_approval'",
+		"o.deleted_at IS NULL",
+		"o.user_id <> ?",
+		"a.round = (SELECT MAX(round) FROM order_approvals WHERE order_id = a.order_id)",
+		"a.level = (SELECT MIN(level) FROM order_approvals WHERE order_id = a.order_id AND round = a.round AND status = 'pending')",
+		"NOT EXISTS (SELECT 1 FROM order_approvals d WHERE d.order_id = a.order_id AND d.round = a.round AND d.decided_by = ?)",
+	}
+	args := []any{userID, userID}
+	if !anyRole {
+		if len(roles) == 0 {
+			return []models.OrderApproval{}, nil
+		}
+		where = append(where, "a.approver_role IN ("+placeholders(len(roles))+")")
+		for _, role := range roles {
+			args = append(args, role)
+		}
+	}
+	args = append(args, limit)
+	return r.query(ctx, `
+		SELECT a.id, a.order_id, a.round, a.level, a.approver_role, a.rules, a.status, a.decided_by, a.decided_at, a.comment, a.created_at
+		FROM order_approvals a
+		JOIN orders o ON o.id = a.order_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY a.created_at, a.order_id, a.approver_role
+		LIMIT ?
+	`, args...)
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"frame_control_system/internal/models"
+)
+
+type AttachmentRepository struct {
+	db DBTX
+}
+
+func NewAttachmentRepository(db DBTX) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+const attachmentColumns = `id, order_id, blob_key, filename, content_type, size, uploaded_by, created_at,
+	media_status, taken_at, gps_lat, gps_lon`
+
+func scanAttachment(row rowScanner) (models.Attachment, error) {
+	var a models.Attachment
+	var createdAt string
+	var takenAt sql.NullString
+	var lat, lon sql.NullFloat64
+	if err := row.Scan(&a.ID, &a.OrderID, &a.BlobKey, &a.Filename, &a.ContentType, &a.Size, &a.UploadedBy, &createdAt,
+		&a.MediaStatus, &takenAt, &lat, &lon); err != nil {
+		return models.Attachment{}, err
+	}
+	a.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	a.TakenAt = parseNullTime(takenAt)
+	if lat.Valid && lon.Valid {
+		a.Location = &models.GeoPoint{Lat: lat.Float64, Lon: lon.Float64}
+	}
+	return a, nil
+}
+
+// Create fills in ID and CreatedAt and stores the attachment metadata.
+func (r *AttachmentRepository) Create(ctx context.Context, a *models.Attachment) error {
+	a.ID = uuid.NewString()
+	a.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	if a.MediaStatus == "" {
+		a.MediaStatus = models.MediaNone
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO order_attachments (id, order_id, blob_key, filename, content_type, size, uploaded_by, created_at, media_status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, a.ID, a.OrderID, a.BlobKey, a.Filename, a.ContentType, a.Size, a.UploadedBy, a.CreatedAt.Format(time.RFC3339), a.MediaStatus)
+	return err
+}
+
+// GetByID returns an attachment of the given order; sql.ErrNoRows if there is none.
+func (r *AttachmentRepository) GetByID(ctx context.Context, orderID, id string) (*models.Attachment, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+attachmentColumns+` FROM order_attachments WHERE id = ? AND order_id = ?`, id, orderID)
+	a, err := scanAttachment(row)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *AttachmentRepository) ListByOrder(ctx context.Context, orderID string) ([]models.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM order_attachments
+		WHERE order_id = ?
+		ORDER BY created_at ASC, id ASC
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
+
+func (r *AttachmentRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM order_attachments WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// BlobInUse reports whether any attachment, thumbnail or defect photo still
+// references the blob; blobs are shared between files with identical content.
+func (r *AttachmentRepository) BlobInUse(ctx context.Context, key string) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT (SELECT COUNT(*) FROM order_attachments WHERE blob_key = ?)
+			+ (SELECT COUNT(*) FROM attachment_thumbnails WHERE blob_key = ?)
+			+ (SELECT COUNT(*) FROM defect_photos WHERE blob_key = ?)
+	`, key, key, key).Scan(&n)
+	return n > 0, err
+}
+
+// Thumbnails returns the thumbnails of the given attachments keyed by
+// attachment id, smallest first.
+func (r *AttachmentRepository) Thumbnails(ctx context.Context, attachmentIDs ...string) (map[string][]models.Thumbnail, error) {
+	res := map[string][]models.Thumbnail{}
+	if len(attachmentIDs) == 0 {
+		return res, nil
+	}
+	args := make([]interface{}, len(attachmentIDs))
+	for i, id := range attachmentIDs {
+		args[i] = id
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT attachment_id, size, blob_key, width, height
+		FROM attachment_thumbnails
+		WHERE attachment_id IN (`+placeholders(len(args))+`)
+		ORDER BY attachment_id, size
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var t models.Thumbnail
+		if err := rows.Scan(&id, &t.Size, &t.BlobKey, &t.Width, &t.Height); err != nil {
+			return nil, err
+		}
+		res[id] = append(res[id], t)
+	}
+	return res, rows.Err()
+}
+
+// ListPendingMedia returns the oldest attachments waiting for thumbnails.
+func (r *AttachmentRepository) ListPendingMedia(ctx context.Context, limit int) ([]models.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM order_attachments
+		WHERE media_status = ?
+		ORDER BY created_at, id
+		LIMIT ?
+	`, models.MediaPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
+
+func (r *AttachmentRepository) AddThumbnail(ctx context.Context, attachmentID string, t models.Thumbnail) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO attachment_thumbnails (attachment_id, size, blob_key, width, height)
+		VALUES (?, ?, ?, ?, ?)
+	`, attachmentID, t.Size, t.BlobKey, t.Width, t.Height)
+	return err
+}
+
+// SetMedia records the outcome of media processing.
+func (r *AttachmentRepository) SetMedia(ctx context.Context, a models.Attachment) error {
+	var lat, lon any
+	if a.Location != nil {
+		lat, lon = a.Location.Lat, a.Location.Lon
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE order_attachments SET media_status = ?, taken_at = ?, gps_lat = ?, gps_lon = ?
+		WHERE id = ?
+	`, a.MediaStatus, nullTime(a.TakenAt), lat, lon, a.ID)
+	return err
+}
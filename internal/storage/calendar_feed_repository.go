@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// CalendarFeedRepository keeps the per-user calendar feed tokens; a user has
+// at most one, replaced on rotation.
+type CalendarFeedRepository struct {
+	db DBTX
+}
+
+func NewCalendarFeedRepository(db DBTX) *CalendarFeedRepository {
+	return &CalendarFeedRepository{db: db}
+}
+
+// SetToken stores the token hash for the user, replacing the previous one.
+func (r *CalendarFeedRepository) SetToken(ctx context.Context, userID, tokenHash string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO calendar_feeds (user_id, token_hash, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at
+	`, userID, tokenHash, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// UserIDByTokenHash returns the owner of the feed; sql.ErrNoRows if the token is unknown.
+func (r *CalendarFeedRepository) UserIDByTokenHash(ctx context.Context, tokenHash string) (string, error) {
+	var userID string
+	err := r.db.QueryRowContext(ctx, `SELECT user_id FROM calendar_feeds WHERE token_hash = ?`, tokenHash).Scan(&userID)
+	return userID, err
+}
+
+// Delete disables the user's feed; sql.ErrNoRows if there was none.
+func (r *CalendarFeedRepository) Delete(ctx context.Context, userID string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM calendar_feeds WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
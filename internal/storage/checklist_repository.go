@@ -0,0 +1,279 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"frame_control_system/internal/models"
+)
+
+type ChecklistRepository struct {
+	db DBTX
+}
+
+func NewChecklistRepository(db DBTX) *ChecklistRepository {
+	return &ChecklistRepository{db: db}
+}
+
+const checklistTemplateColumns = `id, name, description, items, created_by, created_at, updated_at`
+
+func scanChecklistTemplate(row rowScanner) (models.ChecklistTemplate, error) {
+	var t models.ChecklistTemplate
+	var items, createdAt, updatedAt string
+	if err := row.Scan(&t.ID, &t.Name, &t.Description, &items, &t.CreatedBy, &createdAt, &updatedAt); err != nil {
+		return models.ChecklistTemplate{}, err
+	}
+	_ = json.Unmarshal([]byte(items), &t.Items)
+	if t.Items == nil {
+		t.Items = []models.ChecklistTemplateItem{}
+	}
+	t.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	t.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return t, nil
+}
+
+func templateItemsJSON(items []models.ChecklistTemplateItem) string {
+	if items == nil {
+		items = []models.ChecklistTemplateItem{}
+	}
+	b, _ := json.Marshal(items)
+	return string(b)
+}
+
+// CreateTemplate fills in ID and timestamps and stores the template.
+func (r *ChecklistRepository) CreateTemplate(ctx context.Context, t *models.ChecklistTemplate) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	t.ID = uuid.NewString()
+	t.CreatedAt, t.UpdatedAt = now, now
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO checklist_templates (id, name, description, items, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, t.ID, t.Name, t.Description, templateItemsJSON(t.Items), t.CreatedBy, now.Format(time.RFC3339), now.Format(time.RFC3339))
+	return err
+}
+
+// GetTemplate returns the template; sql.ErrNoRows if there is none.
+func (r *ChecklistRepository) GetTemplate(ctx context.Context, id string) (*models.ChecklistTemplate, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+checklistTemplateColumns+` FROM checklist_templates WHERE id = ?`, id)
+	t, err := scanChecklistTemplate(row)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListTemplates returns all templates ordered by name.
+func (r *ChecklistRepository) ListTemplates(ctx context.Context) ([]models.ChecklistTemplate, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+checklistTemplateColumns+` FROM checklist_templates ORDER BY name, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.ChecklistTemplate{}
+	for rows.Next() {
+		t, err := scanChecklistTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
+
+func (r *ChecklistRepository) UpdateTemplate(ctx context.Context, t *models.ChecklistTemplate) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE checklist_templates SET name = ?, description = ?, items = ?, updated_at = ? WHERE id = ?
+	`, t.Name, t.Description, templateItemsJSON(t.Items), now.Format(time.RFC3339), t.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	t.UpdatedAt = now
+	return nil
+}
+
+// DeleteTemplate removes the template; checklists made from it stay.
+func (r *ChecklistRepository) DeleteTemplate(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM checklist_templates WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Instantiate attaches a copy of the template to the order and returns it.
+func (r *ChecklistRepository) Instantiate(ctx context.Context, t *models.ChecklistTemplate, orderID, createdBy string) (*models.Checklist, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	c := &models.Checklist{
+		ID:         uuid.NewString(),
+		OrderID:    orderID,
+		TemplateID: t.ID,
+		Name:       t.Name,
+		Items:      []models.ChecklistItem{},
+		CreatedBy:  createdBy,
+		CreatedAt:  now,
+	}
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO order_checklists (id, order_id, template_id, name, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, c.ID, c.OrderID, c.TemplateID, c.Name, c.CreatedBy, now.Format(time.RFC3339)); err != nil {
+		return nil, err
+	}
+	for i, ti := range t.Items {
+		it := models.ChecklistItem{ID: uuid.NewString(), ChecklistID: c.ID, Position: i + 1, Text: ti.Text, Mandatory: ti.Mandatory}
+		if _, err := r.db.ExecContext(ctx, `
+			INSERT INTO order_checklist_items (id, checklist_id, position, text, mandatory) VALUES (?, ?, ?, ?, ?)
+		`, it.ID, it.ChecklistID, it.Position, it.Text, it.Mandatory); err != nil {
+			return nil, err
+		}
+		if it.Mandatory {
+			c.MandatoryOpen++
+		}
+		c.Items = append(c.Items, it)
+	}
+	return c, nil
+}
+
+const checklistColumns = `id, order_id, template_id, name, created_by, created_at`
+
+func scanChecklist(row rowScanner) (models.Checklist, error) {
+	var c models.Checklist
+	var templateID sql.NullString
+	var createdAt string
+	if err := row.Scan(&c.ID, &c.OrderID, &templateID, &c.Name, &c.CreatedBy, &createdAt); err != nil {
+		return models.Checklist{}, err
+	}
+	c.TemplateID = templateID.String
+	c.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	c.Items = []models.ChecklistItem{}
+	return c, nil
+}
+
+const checklistItemColumns = `id, checklist_id, position, text, mandatory, checked_by, checked_at`
+
+func scanChecklistItem(row rowScanner) (models.ChecklistItem, error) {
+	var it models.ChecklistItem
+	var checkedBy, checkedAt sql.NullString
+	if err := row.Scan(&it.ID, &it.ChecklistID, &it.Position, &it.Text, &it.Mandatory, &checkedBy, &checkedAt); err != nil {
+		return models.ChecklistItem{}, err
+	}
+	it.CheckedBy = checkedBy.String
+	it.CheckedAt = parseNullTime(checkedAt)
+	it.Checked = it.CheckedAt != nil
+	return it, nil
+}
+
+// ListByOrder returns the checklists of the order with their items, oldest first.
+func (r *ChecklistRepository) ListByOrder(ctx context.Context, orderID string) ([]models.Checklist, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+checklistColumns+` FROM order_checklists WHERE order_id = ? ORDER BY created_at, id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	res := []models.Checklist{}
+	index := map[string]int{}
+	for rows.Next() {
+		c, err := scanChecklist(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[c.ID] = len(res)
+		res = append(res, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	items, err := r.db.QueryContext(ctx, `
+		SELECT `+checklistItemColumns+`
+		FROM order_checklist_items
+		WHERE checklist_id IN (SELECT id FROM order_checklists WHERE order_id = ?)
+		ORDER BY position, id
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer items.Close()
+	for items.Next() {
+		it, err := scanChecklistItem(items)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := index[it.ChecklistID]; ok {
+			c := &res[i]
+			c.Items = append(c.Items, it)
+			if it.Mandatory && !it.Checked {
+				c.MandatoryOpen++
+			}
+		}
+	}
+	return res, items.Err()
+}
+
+// Get returns a checklist of the order with its items; sql.ErrNoRows if there is none.
+func (r *ChecklistRepository) Get(ctx context.Context, orderID, id string) (*models.Checklist, error) {
+	list, err := r.ListByOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].ID == id {
+			return &list[i], nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// Delete removes the checklist together with its items.
+func (r *ChecklistRepository) Delete(ctx context.Context, orderID, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM order_checklists WHERE id = ? AND order_id = ?`, id, orderID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetItemChecked checks the item off for userID at the given time, or clears
+// the check when at is nil. sql.ErrNoRows if the item is not in the checklist.
+func (r *ChecklistRepository) SetItemChecked(ctx context.Context, checklistID, itemID, userID string, at *time.Time) error {
+	checkedBy := nullString(userID)
+	if at == nil {
+		checkedBy = nil
+	}
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE order_checklist_items SET checked_by = ?, checked_at = ? WHERE id = ? AND checklist_id = ?
+	`, checkedBy, nullTime(at), itemID, checklistID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// OpenMandatoryItems counts the mandatory items of all the order's checklists
+// that are not checked off yet.
+func (r *ChecklistRepository) OpenMandatoryItems(ctx context.Context, orderID string) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM order_checklist_items i
+		JOIN order_checklists c ON c.id = i.checklist_id
+		WHERE c.order_id = ? AND i.mandatory = 1 AND i.checked_at IS NULL
+	`, orderID).Scan(&n)
+	return n, err
+}
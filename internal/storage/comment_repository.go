@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"frame_control_system/internal/models"
+)
+
+type CommentRepository struct {
+	db DBTX
+}
+
+func NewCommentRepository(db DBTX) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+const commentColumns = `id, order_id, author_id, body, mentions, created_at, updated_at, edited_at`
+
+func scanComment(row rowScanner) (models.Comment, error) {
+	var c models.Comment
+	var mentions, createdAt, updatedAt string
+	var editedAt sql.NullString
+	if err := row.Scan(&c.ID, &c.OrderID, &c.AuthorID, &c.Body, &mentions, &createdAt, &updatedAt, &editedAt); err != nil {
+		return models.Comment{}, err
+	}
+	_ = json.Unmarshal([]byte(mentions), &c.Mentions)
+	if c.Mentions == nil {
+		c.Mentions = []string{}
+	}
+	c.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	c.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	c.EditedAt = parseNullTime(editedAt)
+	return c, nil
+}
+
+// Create fills in ID and timestamps and stores the comment.
+func (r *CommentRepository) Create(ctx context.Context, c *models.Comment) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	c.ID = uuid.NewString()
+	c.CreatedAt, c.UpdatedAt = now, now
+	if c.Mentions == nil {
+		c.Mentions = []string{}
+	}
+	mentions, _ := json.Marshal(c.Mentions)
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO order_comments (id, order_id, author_id, body, mentions, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, c.ID, c.OrderID, c.AuthorID, c.Body, string(mentions), now.Format(time.RFC3339), now.Format(time.RFC3339))
+	return err
+}
+
+// GetByID returns a comment of the given order; sql.ErrNoRows if there is none.
+func (r *CommentRepository) GetByID(ctx context.Context, orderID, id string) (*models.Comment, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+commentColumns+` FROM order_comments WHERE id = ? AND order_id = ?`, id, orderID)
+	c, err := scanComment(row)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListByOrder returns comments oldest first. A non-nil after continues from a
+// cursor returned for the previous page.
+func (r *CommentRepository) ListByOrder(ctx context.Context, orderID string, limit int, after *Cursor) ([]models.Comment, error) {
+	where := "order_id = ?"
+	args := []interface{}{orderID}
+	if after != nil {
+		cond, cargs := keysetCondition(*after, false)
+		where += " AND " + cond
+		args = append(args, cargs...)
+	}
+	args = append(args, limit)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM order_comments
+		WHERE `+where+`
+		ORDER BY created_at ASC, id ASC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// Update replaces the body and mentions and marks the comment as edited.
+func (r *CommentRepository) Update(ctx context.Context, c *models.Comment) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	mentions, _ := json.Marshal(c.Mentions)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE order_comments SET body = ?, mentions = ?, updated_at = ?, edited_at = ?
+		WHERE id = ?
+	`, c.Body, string(mentions), now.Format(time.RFC3339), now.Format(time.RFC3339), c.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	c.UpdatedAt = now
+	c.EditedAt = &now
+	return nil
+}
+
+func (r *CommentRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM order_comments WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a list ordered by (created_at, id). It is handed to
+// clients as an opaque string.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+	// Sort is the order of the list the cursor was issued for, in lists that
+	// can be sorted; a cursor only continues that order.
+	Sort string
+}
+
+type cursorJSON struct {
+	CreatedAt string `json:"c"`
+	ID        string `json:"i"`
+	Sort      string `json:"s,omitempty"`
+}
+
+func EncodeCursor(c Cursor) string {
+	b, _ := json.Marshal(cursorJSON{CreatedAt: c.CreatedAt.UTC().Format(time.RFC3339), ID: c.ID, Sort: c.Sort})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var cj cursorJSON
+	if err := json.Unmarshal(b, &cj); err != nil || cj.ID == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339, cj.CreatedAt)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{CreatedAt: t, ID: cj.ID, Sort: cj.Sort}, nil
+}
+
+// keysetCondition returns the WHERE fragment selecting rows strictly after c
+// in (created_at, id) order.
+func keysetCondition(c Cursor, desc bool) (string, []interface{}) {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	ts := c.CreatedAt.UTC().Format(time.RFC3339)
+	return "(created_at " + op + " ? OR (created_at = ? AND id " + op + " ?))", []interface{}{ts, ts, c.ID}
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{CreatedAt: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC), ID: "abc", Sort: "created_asc"}
+	got, err := DecodeCursor(EncodeCursor(c))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID || got.Sort != c.Sort {
+		t.Fatalf("want %+v, got %+v", c, got)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"", "!!!", "bm90LWpzb24", "eyJjIjoieCIsImkiOiJhIn0"} {
+		if _, err := DecodeCursor(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"frame_control_system/internal/models"
+)
+
+type CustomFieldRepository struct {
+	db DBTX
+}
+
+func NewCustomFieldRepository(db DBTX) *CustomFieldRepository {
+	return &CustomFieldRepository{db: db}
+}
+
+const customFieldColumns = `key, label, type, options, created_at, updated_at`
+
+func scanCustomField(row rowScanner) (models.CustomFieldDefinition, error) {
+	var d models.CustomFieldDefinition
+	var typ, options, createdAt, updatedAt string
+	if err := row.Scan(&d.Key, &d.Label, &typ, &options, &createdAt, &updatedAt); err != nil {
+		return models.CustomFieldDefinition{}, err
+	}
+	d.Type = models.CustomFieldType(typ)
+	_ = json.Unmarshal([]byte(options), &d.Options)
+	d.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	d.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return d, nil
+}
+
+func optionsJSON(options []string) string {
+	if options == nil {
+		options = []string{}
+	}
+	b, _ := json.Marshal(options)
+	return string(b)
+}
+
+// Create fills in timestamps and stores the definition.
+func (r *CustomFieldRepository) Create(ctx context.Context, d *models.CustomFieldDefinition) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	d.CreatedAt, d.UpdatedAt = now, now
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO custom_field_definitions (key, label, type, options, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, d.Key, d.Label, string(d.Type), optionsJSON(d.Options), now.Format(time.RFC3339), now.Format(time.RFC3339))
+	return err
+}
+
+// Get returns the definition; sql.ErrNoRows if there is none.
+func (r *CustomFieldRepository) Get(ctx context.Context, key string) (*models.CustomFieldDefinition, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+customFieldColumns+` FROM custom_field_definitions WHERE key = ?`, key)
+	d, err := scanCustomField(row)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// List returns all definitions ordered by key.
+func (r *CustomFieldRepository) List(ctx context.Context) ([]models.CustomFieldDefinition, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+customFieldColumns+` FROM custom_field_definitions ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.CustomFieldDefinition{}
+	for rows.Next() {
+		d, err := scanCustomField(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+// Update saves the label and options; the type of a field cannot change.
+func (r *CustomFieldRepository) Update(ctx context.Context, d *models.CustomFieldDefinition) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	d.UpdatedAt = now
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE custom_field_definitions SET label = ?, options = ?, updated_at = ? WHERE key = ?
+	`, d.Label, optionsJSON(d.Options), now.Format(time.RFC3339), d.Key)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *CustomFieldRepository) Delete(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM custom_field_definitions WHERE key = ?`, key)
+	return err
+}
+
+// InUse reports whether any order, deleted ones included, has a value for the field.
+func (r *CustomFieldRepository) InUse(ctx context.Context, key string) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM orders WHERE json_type(custom_fields, '$."' || ? || '"') IS NOT NULL
+	`, key).Scan(&n)
+	return n > 0, err
+}
@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"frame_control_system/internal/models"
+)
+
+type DefectRepository struct {
+	db DBTX
+}
+
+func NewDefectRepository(db DBTX) *DefectRepository {
+	return &DefectRepository{db: db}
+}
+
+const defectColumns = `id, project_id, title, description, severity, location, responsible_id, due_at, status,
+	reported_by, created_at, updated_at`
+
+func scanDefect(row rowScanner) (models.Defect, error) {
+	var d models.Defect
+	var severity, status, createdAt, updatedAt string
+	var responsibleID, dueAt sql.NullString
+	if err := row.Scan(&d.ID, &d.ProjectID, &d.Title, &d.Description, &severity, &d.Location, &responsibleID, &dueAt, &status,
+		&d.ReportedBy, &createdAt, &updatedAt); err != nil {
+		return models.Defect{}, err
+	}
+	d.Severity = models.DefectSeverity(severity)
+	d.Status = models.DefectStatus(status)
+	d.ResponsibleID = responsibleID.String
+	d.DueAt = parseNullTime(dueAt)
+	d.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	d.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	d.Overdue = d.IsOverdue(time.Now())
+	return d, nil
+}
+
+// Create fills in ID and timestamps and stores the defect.
+func (r *DefectRepository) Create(ctx context.Context, d *models.Defect) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	d.ID = uuid.NewString()
+	d.CreatedAt, d.UpdatedAt = now, now
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO defects (id, project_id, title, description, severity, location, responsible_id, due_at, status,
+			reported_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, d.ID, d.ProjectID, d.Title, d.Description, string(d.Severity), d.Location, nullString(d.ResponsibleID), nullTime(d.DueAt),
+		string(d.Status), d.ReportedBy, now.Format(time.RFC3339), now.Format(time.RFC3339))
+	return err
+}
+
+// GetByID returns the defect without photos; sql.ErrNoRows if there is none.
+func (r *DefectRepository) GetByID(ctx context.Context, id string) (*models.Defect, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+defectColumns+` FROM defects WHERE id = ?`, id)
+	d, err := scanDefect(row)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+type ListDefectsParams struct {
+	// Without AdminView only defects of projects UserID is a member of are returned.
+	UserID        string
+	AdminView     bool
+	ProjectID     string
+	Statuses      []string
+	Severities    []string
+	ResponsibleID string
+	ReportedBy    string
+	// Overdue keeps open defects whose deadline has passed.
+	Overdue bool
+	Limit   int
+	Offset  int
+	After   *Cursor
+}
+
+func (p ListDefectsParams) filter() ([]string, []interface{}) {
+	where := []string{"1=1"}
+	args := []interface{}{}
+	if !p.AdminView {
+		where = append(where, "project_id IN (SELECT project_id FROM project_members WHERE user_id = ?)")
+		args = append(args, p.UserID)
+	}
+	if p.ProjectID != "" {
+		where = append(where, "project_id = ?")
+		args = append(args, p.ProjectID)
+	}
+	if len(p.Statuses) > 0 {
+		where = append(where, "status IN ("+placeholders(len(p.Statuses))+")")
+		for _, st := range p.Statuses {
+			args = append(args, st)
+		}
+	}
+	if len(p.Severities) > 0 {
+		where = append(where, "severity IN ("+placeholders(len(p.Severities))+")")
+		for _, s := range p.Severities {
+			args = append(args, s)
+		}
+	}
+	if p.ResponsibleID != "" {
+		where = append(where, "responsible_id = ?")
+		args = append(args, p.ResponsibleID)
+	}
+	if p.ReportedBy != "" {
+		where = append(where, "reported_by = ?")
+		args = append(args, p.ReportedBy)
+	}
+	if p.Overdue {
+		where = append(where, "due_at < ? AND status IN ('new', 'in_work', 'on_review')")
+		args = append(args, time.Now().UTC().Format(time.RFC3339))
+	}
+	return where, args
+}
+
+// List returns defects newest first, without photos.
+func (r *DefectRepository) List(ctx context.Context, p ListDefectsParams) ([]models.Defect, error) {
+	where, args := p.filter()
+	offset := p.Offset
+	if p.After != nil {
+		cond, cargs := keysetCondition(*p.After, true)
+		where = append(where, cond)
+		args = append(args, cargs...)
+		offset = 0
+	}
+	args = append(args, p.Limit, offset)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+defectColumns+`
+		FROM defects
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.Defect{}
+	for rows.Next() {
+		d, err := scanDefect(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+// Count returns the number of defects matching the filters, ignoring pagination.
+func (r *DefectRepository) Count(ctx context.Context, p ListDefectsParams) (int, error) {
+	where, args := p.filter()
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM defects WHERE `+strings.Join(where, " AND "), args...).Scan(&n)
+	return n, err
+}
+
+// Update saves the editable fields and the status of the defect.
+func (r *DefectRepository) Update(ctx context.Context, d *models.Defect) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE defects SET title = ?, description = ?, severity = ?, location = ?, responsible_id = ?, due_at = ?,
+			status = ?, updated_at = ?
+		WHERE id = ?
+	`, d.Title, d.Description, string(d.Severity), d.Location, nullString(d.ResponsibleID), nullTime(d.DueAt),
+		string(d.Status), now.Format(time.RFC3339), d.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	d.UpdatedAt = now
+	d.Overdue = d.IsOverdue(time.Now())
+	return nil
+}
+
+// Delete removes the defect together with its photo records.
+func (r *DefectRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM defects WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// HasDefects reports whether any defect belongs to the project.
+func (r *DefectRepository) HasDefects(ctx context.Context, projectID string) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM defects WHERE project_id = ?`, projectID).Scan(&n)
+	return n > 0, err
+}
+
+// StatusCounts returns the number of the project's defects per status.
+func (r *DefectRepository) StatusCounts(ctx context.Context, projectID string) (map[models.DefectStatus]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM defects WHERE project_id = ? GROUP BY status`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[models.DefectStatus]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		res[models.DefectStatus(status)] = n
+	}
+	return res, rows.Err()
+}
+
+const defectPhotoColumns = `id, defect_id, blob_key, filename, content_type, size, uploaded_by, created_at`
+
+func scanDefectPhoto(row rowScanner) (models.DefectPhoto, error) {
+	var ph models.DefectPhoto
+	var createdAt string
+	if err := row.Scan(&ph.ID, &ph.DefectID, &ph.BlobKey, &ph.Filename, &ph.ContentType, &ph.Size, &ph.UploadedBy, &createdAt); err != nil {
+		return models.DefectPhoto{}, err
+	}
+	ph.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	return ph, nil
+}
+
+// AddPhoto fills in ID and CreatedAt and stores the photo metadata.
+func (r *DefectRepository) AddPhoto(ctx context.Context, ph *models.DefectPhoto) error {
+	ph.ID = uuid.NewString()
+	ph.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO defect_photos (id, defect_id, blob_key, filename, content_type, size, uploaded_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, ph.ID, ph.DefectID, ph.BlobKey, ph.Filename, ph.ContentType, ph.Size, ph.UploadedBy, ph.CreatedAt.Format(time.RFC3339))
+	return err
+}
+
+// GetPhoto returns a photo of the given defect; sql.ErrNoRows if there is none.
+func (r *DefectRepository) GetPhoto(ctx context.Context, defectID, id string) (*models.DefectPhoto, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+defectPhotoColumns+` FROM defect_photos WHERE id = ? AND defect_id = ?`, id, defectID)
+	ph, err := scanDefectPhoto(row)
+	if err != nil {
+		return nil, err
+	}
+	return &ph, nil
+}
+
+// Photos returns the photos of the given defects keyed by defect id, oldest first.
+func (r *DefectRepository) Photos(ctx context.Context, defectIDs ...string) (map[string][]models.DefectPhoto, error) {
+	res := map[string][]models.DefectPhoto{}
+	if len(defectIDs) == 0 {
+		return res, nil
+	}
+	args := make([]interface{}, len(defectIDs))
+	for i, id := range defectIDs {
+		args[i] = id
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+defectPhotoColumns+`
+		FROM defect_photos
+		WHERE defect_id IN (`+placeholders(len(defectIDs))+`)
+		ORDER BY created_at ASC, id ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		ph, err := scanDefectPhoto(rows)
+		if err != nil {
+			return nil, err
+		}
+		res[ph.DefectID] = append(res[ph.DefectID], ph)
+	}
+	return res, rows.Err()
+}
+
+func (r *DefectRepository) DeletePhoto(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM defect_photos WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
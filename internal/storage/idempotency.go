@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type IdempotencyRecord struct {
+	UserID       string
+	Key          string
+	Method       string
+	Path         string
+	Fingerprint  string
+	StatusCode   int // 0 while the first request is still being processed
+	ResponseBody []byte
+	// ResponseHeaders are the headers the handler set; nil for records stored
+	// before they were kept.
+	ResponseHeaders map[string][]string
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+}
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims the key for a new request. If the key is already taken and
+// not expired, the stored record is returned and nothing is inserted.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, rec IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, error) {
+	now := time.Now().UTC()
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE expires_at <= ?
+	`, now.Format(time.RFC3339)); err != nil {
+		return nil, err
+	}
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, method, path, fingerprint, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, key) DO NOTHING
+	`, rec.UserID, rec.Key, rec.Method, rec.Path, rec.Fingerprint, now.Format(time.RFC3339), now.Add(ttl).Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return nil, nil
+	}
+	return r.get(ctx, rec.UserID, rec.Key)
+}
+
+func (r *IdempotencyRepository) get(ctx context.Context, userID, key string) (*IdempotencyRecord, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT user_id, key, method, path, fingerprint, status_code, response_body, response_headers, created_at, expires_at
+		FROM idempotency_keys WHERE user_id = ? AND key = ?
+	`, userID, key)
+	var (
+		rec                  IdempotencyRecord
+		status               sql.NullInt64
+		body, headers        sql.NullString
+		createdAt, expiresAt string
+	)
+	if err := row.Scan(&rec.UserID, &rec.Key, &rec.Method, &rec.Path, &rec.Fingerprint, &status, &body, &headers, &createdAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	rec.StatusCode = int(status.Int64)
+	rec.ResponseBody = []byte(body.String)
+	if headers.Valid {
+		_ = json.Unmarshal([]byte(headers.String), &rec.ResponseHeaders)
+	}
+	rec.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	rec.ExpiresAt, _ = time.Parse(time.RFC3339, expiresAt)
+	return &rec, nil
+}
+
+// Complete stores the response so that retries with the same key replay it.
+func (r *IdempotencyRepository) Complete(ctx context.Context, userID, key string, status int, headers map[string][]string, body []byte) error {
+	h, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET status_code = ?, response_headers = ?, response_body = ? WHERE user_id = ? AND key = ?
+	`, status, string(h), string(body), userID, key)
+	return err
+}
+
+// Release drops a reservation so the request can be retried with the same key.
+func (r *IdempotencyRepository) Release(ctx context.Context, userID, key string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE user_id = ? AND key = ?
+	`, userID, key)
+	return err
+}
@@ -4,14 +4,27 @@ import (
 	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
 	"sort"
+	"time"
 )
 
 //go:embed migrations/*.sql
 var migrationsFS embed.FS
 
+// errNoFTS5 is returned when the sqlite3 driver was compiled without the
+// sqlite_fts5 build tag; migration 005 (full-text search) needs it.
+var errNoFTS5 = errors.New("sqlite3 driver built without FTS5: build with -tags sqlite_fts5 (see Makefile)")
+
 func RunMigrations(db *sql.DB) error {
+	var fts5 bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil {
+		return fmt.Errorf("check sqlite options: %w", err)
+	}
+	if !fts5 {
+		return errNoFTS5
+	}
 	entries, err := migrationsFS.ReadDir("migrations")
 	if err != nil {
 		return fmt.Errorf("read migrations dir: %w", err)
@@ -28,7 +41,25 @@ func RunMigrations(db *sql.DB) error {
 		return err
 	}
 	defer func() { _ = tx.Rollback() }()
+	// Applied migrations are recorded so that non-idempotent statements
+	// (ALTER TABLE, backfills) run exactly once.
+	if _, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			name TEXT PRIMARY KEY,
+			applied_at TEXT NOT NULL
+		)
+	`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
 	for _, name := range names {
+		var one int
+		err := tx.QueryRow(`SELECT 1 FROM schema_migrations WHERE name = ?`, name).Scan(&one)
+		if err == nil {
+			continue
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("check %s: %w", name, err)
+		}
 		b, err := migrationsFS.ReadFile("migrations/" + name)
 		if err != nil {
 			return fmt.Errorf("read %s: %w", name, err)
@@ -36,8 +67,9 @@ func RunMigrations(db *sql.DB) error {
 		if _, err := tx.Exec(string(b)); err != nil {
 			return fmt.Errorf("exec %s: %w", name, err)
 		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (name, applied_at) VALUES (?, ?)`, name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			return fmt.Errorf("record %s: %w", name, err)
+		}
 	}
 	return tx.Commit()
 }
-
-
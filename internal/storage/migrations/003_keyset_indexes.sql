@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_orders_created_at_id ON orders(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_user_created_at_id ON orders(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users(created_at, id);
@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN notes TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,60 @@
+-- Full-text search over orders (item names, notes) and users (name, email).
+-- Requires SQLite built with FTS5 (go build -tags sqlite_fts5).
+
+CREATE VIRTUAL TABLE IF NOT EXISTS orders_fts USING fts5(
+    order_id UNINDEXED,
+    items, -- space-separated item names
+    notes,
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3'
+);
+
+CREATE TRIGGER IF NOT EXISTS orders_fts_ai AFTER INSERT ON orders BEGIN
+    INSERT INTO orders_fts (order_id, items, notes)
+    VALUES (
+        new.id,
+        (SELECT group_concat(json_extract(value, '$.name'), ' ') FROM json_each(new.items)),
+        new.notes
+    );
+END;
+
+CREATE TRIGGER IF NOT EXISTS orders_fts_au AFTER UPDATE OF items, notes ON orders BEGIN
+    DELETE FROM orders_fts WHERE order_id = old.id;
+    INSERT INTO orders_fts (order_id, items, notes)
+    VALUES (
+        new.id,
+        (SELECT group_concat(json_extract(value, '$.name'), ' ') FROM json_each(new.items)),
+        new.notes
+    );
+END;
+
+CREATE TRIGGER IF NOT EXISTS orders_fts_ad AFTER DELETE ON orders BEGIN
+    DELETE FROM orders_fts WHERE order_id = old.id;
+END;
+
+INSERT INTO orders_fts (order_id, items, notes)
+SELECT o.id, (SELECT group_concat(json_extract(value, '$.name'), ' ') FROM json_each(o.items)), o.notes
+FROM orders o;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS users_fts USING fts5(
+    user_id UNINDEXED,
+    name,
+    email,
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3'
+);
+
+CREATE TRIGGER IF NOT EXISTS users_fts_ai AFTER INSERT ON users BEGIN
+    INSERT INTO users_fts (user_id, name, email) VALUES (new.id, new.name, new.email);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_au AFTER UPDATE OF name, email ON users BEGIN
+    DELETE FROM users_fts WHERE user_id = old.id;
+    INSERT INTO users_fts (user_id, name, email) VALUES (new.id, new.name, new.email);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_ad AFTER DELETE ON users BEGIN
+    DELETE FROM users_fts WHERE user_id = old.id;
+END;
+
+INSERT INTO users_fts (user_id, name, email) SELECT id, name, email FROM users;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    changed_by TEXT, -- NULL for backfilled rows
+    changed_at TEXT NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history(order_id, changed_at);
+CREATE INDEX IF NOT EXISTS idx_order_status_history_to_status ON order_status_history(to_status, changed_at);
+
+-- Best-effort history for orders that changed status before it was recorded:
+-- the last status change is assumed to have happened at updated_at.
+INSERT INTO order_status_history (id, order_id, from_status, to_status, changed_by, changed_at)
+SELECT lower(hex(randomblob(16))), id,
+    CASE status WHEN 'in_progress' THEN 'created' ELSE 'in_progress' END,
+    status, NULL, updated_at
+FROM orders
+WHERE status IN ('in_progress', 'done', 'cancelled');
@@ -0,0 +1,4 @@
+ALTER TABLE orders ADD COLUMN due_at TEXT; -- RFC 3339, NULL when there is no deadline
+ALTER TABLE orders ADD COLUMN priority TEXT NOT NULL DEFAULT 'normal'; -- low,normal,high,urgent
+ALTER TABLE orders ADD COLUMN overdue_notified_at TEXT; -- set once order.overdue was emitted
+CREATE INDEX IF NOT EXISTS idx_orders_due_at ON orders(due_at);
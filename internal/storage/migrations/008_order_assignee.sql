@@ -0,0 +1,2 @@
+ALTER TABLE orders ADD COLUMN assignee_id TEXT REFERENCES users(id); -- executor, NULL when unassigned
+CREATE INDEX IF NOT EXISTS idx_orders_assignee ON orders(assignee_id, created_at, id);
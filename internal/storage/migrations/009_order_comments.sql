@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS order_comments (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    author_id TEXT NOT NULL REFERENCES users(id),
+    body TEXT NOT NULL,
+    mentions TEXT NOT NULL DEFAULT '[]', -- JSON array of mentioned user ids
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    edited_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_comments_order ON order_comments(order_id, created_at, id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS order_attachments (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    blob_key TEXT NOT NULL, -- sha256 of the content in the blob store
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    uploaded_by TEXT NOT NULL REFERENCES users(id),
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_attachments_order ON order_attachments(order_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_order_attachments_blob ON order_attachments(blob_key);
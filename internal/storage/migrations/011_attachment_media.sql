@@ -0,0 +1,20 @@
+-- media_status: none (not an image), pending, ready, failed, unsupported
+ALTER TABLE order_attachments ADD COLUMN media_status TEXT NOT NULL DEFAULT 'none';
+ALTER TABLE order_attachments ADD COLUMN taken_at TEXT; -- EXIF DateTimeOriginal, RFC 3339
+ALTER TABLE order_attachments ADD COLUMN gps_lat REAL;
+ALTER TABLE order_attachments ADD COLUMN gps_lon REAL;
+
+CREATE TABLE IF NOT EXISTS attachment_thumbnails (
+    attachment_id TEXT NOT NULL REFERENCES order_attachments(id) ON DELETE CASCADE,
+    size INTEGER NOT NULL, -- requested longer side in pixels
+    blob_key TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    PRIMARY KEY (attachment_id, size)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachment_thumbnails_blob ON attachment_thumbnails(blob_key);
+CREATE INDEX IF NOT EXISTS idx_order_attachments_media ON order_attachments(media_status, created_at);
+
+UPDATE order_attachments SET media_status = 'pending'
+WHERE content_type IN ('image/jpeg', 'image/png', 'image/gif');
@@ -0,0 +1,22 @@
+ALTER TABLE orders ADD COLUMN discount_percent REAL NOT NULL DEFAULT 0; -- order-level discount, applied after line discounts
+ALTER TABLE orders ADD COLUMN discount_fixed REAL NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN promo TEXT; -- JSON snapshot of the redeemed promo code
+ALTER TABLE orders ADD COLUMN breakdown TEXT NOT NULL DEFAULT '{}'; -- JSON: subtotal, discounts, tax, total
+
+UPDATE orders SET breakdown = json_object(
+    'subtotal', total_amount, 'line_discount', 0, 'order_discount', 0, 'promo_discount', 0,
+    'discount', 0, 'tax', 0, 'total', total_amount
+);
+
+CREATE TABLE IF NOT EXISTS promo_codes (
+    code TEXT PRIMARY KEY, -- upper case
+    kind TEXT NOT NULL, -- percent or fixed
+    value REAL NOT NULL,
+    valid_from TEXT, -- inclusive, NULL means no lower bound
+    valid_to TEXT, -- exclusive, NULL means no upper bound
+    max_uses INTEGER, -- NULL means unlimited
+    used_count INTEGER NOT NULL DEFAULT 0,
+    active INTEGER NOT NULL DEFAULT 1,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
@@ -0,0 +1,3 @@
+ALTER TABLE orders ADD COLUMN archived_at TEXT; -- set by the archiver; hidden from default lists
+ALTER TABLE orders ADD COLUMN deleted_at TEXT; -- soft delete; hidden everywhere except the admin trash
+CREATE INDEX IF NOT EXISTS idx_orders_archivable ON orders(status, updated_at) WHERE archived_at IS NULL AND deleted_at IS NULL;
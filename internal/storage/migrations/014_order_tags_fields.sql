@@ -0,0 +1,11 @@
+ALTER TABLE orders ADD COLUMN tags TEXT NOT NULL DEFAULT '[]'; -- JSON array of lower-case tags
+ALTER TABLE orders ADD COLUMN custom_fields TEXT NOT NULL DEFAULT '{}'; -- JSON object keyed by custom_field_definitions.key
+
+CREATE TABLE IF NOT EXISTS custom_field_definitions (
+    key TEXT PRIMARY KEY,
+    label TEXT NOT NULL,
+    type TEXT NOT NULL, -- text, number, date, enum
+    options TEXT NOT NULL DEFAULT '[]', -- JSON array, allowed values of an enum
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS projects (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL DEFAULT '',
+    customer TEXT NOT NULL DEFAULT '',
+    start_date TEXT, -- YYYY-MM-DD
+    end_date TEXT, -- YYYY-MM-DD
+    status TEXT NOT NULL, -- planned,active,on_hold,completed,cancelled
+    created_by TEXT NOT NULL REFERENCES users(id),
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_projects_created_at_id ON projects(created_at, id);
+
+CREATE TABLE IF NOT EXISTS project_members (
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL, -- manager,engineer,executor
+    added_by TEXT NOT NULL,
+    added_at TEXT NOT NULL,
+    PRIMARY KEY (project_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_project_members_user ON project_members(user_id);
+
+ALTER TABLE orders ADD COLUMN project_id TEXT REFERENCES projects(id); -- NULL when the order is not tied to a project
+CREATE INDEX IF NOT EXISTS idx_orders_project ON orders(project_id, created_at, id);
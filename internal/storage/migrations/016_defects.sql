@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS defects (
+    id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL REFERENCES projects(id),
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    severity TEXT NOT NULL, -- low,medium,high,critical
+    location TEXT NOT NULL DEFAULT '',
+    responsible_id TEXT REFERENCES users(id),
+    due_at TEXT,
+    status TEXT NOT NULL, -- new,in_work,on_review,closed,rejected
+    reported_by TEXT NOT NULL REFERENCES users(id),
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_defects_created_at_id ON defects(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_defects_project ON defects(project_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_defects_responsible ON defects(responsible_id);
+
+CREATE TABLE IF NOT EXISTS defect_photos (
+    id TEXT PRIMARY KEY,
+    defect_id TEXT NOT NULL REFERENCES defects(id) ON DELETE CASCADE,
+    blob_key TEXT NOT NULL, -- shared with attachments when the content is identical
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    uploaded_by TEXT NOT NULL REFERENCES users(id),
+    created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_defect_photos_defect ON defect_photos(defect_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_defect_photos_blob ON defect_photos(blob_key);
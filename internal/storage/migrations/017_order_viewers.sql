@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS order_viewers (
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    added_by TEXT NOT NULL,
+    added_at TEXT NOT NULL,
+    PRIMARY KEY (order_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_order_viewers_user ON order_viewers(user_id);
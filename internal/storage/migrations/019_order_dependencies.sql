@@ -0,0 +1,10 @@
+ALTER TABLE orders ADD COLUMN duration_days INTEGER NOT NULL DEFAULT 0; -- planned duration for scheduling, 0 when unknown
+CREATE TABLE IF NOT EXISTS order_dependencies (
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    predecessor_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    created_by TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (order_id, predecessor_id),
+    CHECK (order_id <> predecessor_id)
+);
+CREATE INDEX IF NOT EXISTS idx_order_dependencies_predecessor ON order_dependencies(predecessor_id);
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS checklist_templates (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    items TEXT NOT NULL DEFAULT '[]', -- JSON array of {text, mandatory}
+    created_by TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS order_checklists (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    template_id TEXT, -- kept when the template is deleted
+    name TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_order_checklists_order ON order_checklists(order_id, created_at);
+
+CREATE TABLE IF NOT EXISTS order_checklist_items (
+    id TEXT PRIMARY KEY,
+    checklist_id TEXT NOT NULL REFERENCES order_checklists(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    mandatory INTEGER NOT NULL DEFAULT 0,
+    checked_by TEXT,
+    checked_at TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_order_checklist_items_checklist ON order_checklist_items(checklist_id, position);
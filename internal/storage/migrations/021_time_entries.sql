@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS time_entries (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    started_at TEXT NOT NULL,
+    ended_at TEXT, -- NULL while the timer is running
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_time_entries_order ON time_entries(order_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_time_entries_user ON time_entries(user_id, started_at);
+-- A user runs at most one timer at a time.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries(user_id) WHERE ended_at IS NULL;
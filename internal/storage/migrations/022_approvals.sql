@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS approval_rules (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    min_total REAL,
+    categories TEXT NOT NULL DEFAULT '[]', -- JSON array of item categories
+    level INTEGER NOT NULL,
+    approver_role TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS order_approvals (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    round INTEGER NOT NULL,
+    level INTEGER NOT NULL,
+    approver_role TEXT NOT NULL,
+    rules TEXT NOT NULL DEFAULT '[]', -- JSON array of rule names
+    status TEXT NOT NULL, -- pending,approved,rejected,cancelled
+    decided_by TEXT,
+    decided_at TEXT,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_order_approvals_order ON order_approvals(order_id, round, level);
+CREATE INDEX IF NOT EXISTS idx_order_approvals_pending ON order_approvals(status, approver_role);
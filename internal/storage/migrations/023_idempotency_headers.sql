@@ -0,0 +1,2 @@
+-- JSON object of the headers the handler set on the stored response.
+ALTER TABLE idempotency_keys ADD COLUMN response_headers TEXT;
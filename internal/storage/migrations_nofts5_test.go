@@ -0,0 +1,20 @@
+//go:build !sqlite_fts5
+
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunMigrationsWithoutFTS5(t *testing.T) {
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	if err := RunMigrations(db); !errors.Is(err, errNoFTS5) {
+		t.Fatalf("want errNoFTS5, got %v", err)
+	}
+}
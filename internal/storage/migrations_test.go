@@ -0,0 +1,27 @@
+//go:build sqlite_fts5
+
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRunMigrations(t *testing.T) {
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// Applied migrations are recorded, so a second run is a no-op.
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("migrate again: %v", err)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM orders_fts`).Scan(&n); err != nil {
+		t.Fatalf("full-text table: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"frame_control_system/internal/models"
+)
+
+type OrderDependencyRepository struct {
+	db DBTX
+}
+
+func NewOrderDependencyRepository(db DBTX) *OrderDependencyRepository {
+	return &OrderDependencyRepository{db: db}
+}
+
+const orderDependencyColumns = `order_id, predecessor_id, created_by, created_at`
+
+func scanOrderDependency(row rowScanner) (models.OrderDependency, error) {
+	var d models.OrderDependency
+	var createdAt string
+	if err := row.Scan(&d.OrderID, &d.PredecessorID, &d.CreatedBy, &createdAt); err != nil {
+		return models.OrderDependency{}, err
+	}
+	d.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	return d, nil
+}
+
+func (r *OrderDependencyRepository) query(ctx context.Context, query string, args ...any) ([]models.OrderDependency, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.OrderDependency{}
+	for rows.Next() {
+		d, err := scanOrderDependency(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+// Add stores the dependency; it returns false if it already existed. Callers
+// check for cycles first.
+func (r *OrderDependencyRepository) Add(ctx context.Context, d *models.OrderDependency) (bool, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO order_dependencies (order_id, predecessor_id, created_by, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (order_id, predecessor_id) DO NOTHING
+	`, d.OrderID, d.PredecessorID, d.CreatedBy, now.Format(time.RFC3339))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	if n == 1 {
+		d.CreatedAt = now
+	}
+	return n == 1, nil
+}
+
+// Remove deletes the dependency; sql.ErrNoRows if there was none.
+func (r *OrderDependencyRepository) Remove(ctx context.Context, orderID, predecessorID string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM order_dependencies WHERE order_id = ? AND predecessor_id = ?`, orderID, predecessorID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Predecessors returns what the order waits for, oldest first.
+func (r *OrderDependencyRepository) Predecessors(ctx context.Context, orderID string) ([]models.OrderDependency, error) {
+	return r.query(ctx, `SELECT `+orderDependencyColumns+` FROM order_dependencies WHERE order_id = ? ORDER BY created_at, predecessor_id`, orderID)
+}
+
+// Successors returns the orders waiting for the given one, oldest first.
+func (r *OrderDependencyRepository) Successors(ctx context.Context, orderID string) ([]models.OrderDependency, error) {
+	return r.query(ctx, `SELECT `+orderDependencyColumns+` FROM order_dependencies WHERE predecessor_id = ? ORDER BY created_at, order_id`, orderID)
+}
+
+// Upstream returns every dependency the order transitively relies on: its own
+// predecessors, their predecessors and so on.
+func (r *OrderDependencyRepository) Upstream(ctx context.Context, orderID string) ([]models.OrderDependency, error) {
+	return r.query(ctx, `
+		WITH RECURSIVE up(id) AS (
+			SELECT ?
+			UNION
+			SELECT d.predecessor_id FROM order_dependencies d JOIN up ON d.order_id = up.id
+		)
+		SELECT `+orderDependencyColumns+`
+		FROM order_dependencies
+		WHERE order_id IN (SELECT id FROM up)
+	`, orderID)
+}
+
+// ListByProject returns the dependencies between orders of the project.
+func (r *OrderDependencyRepository) ListByProject(ctx context.Context, projectID string) ([]models.OrderDependency, error) {
+	return r.query(ctx, `
+		SELECT `+orderDependencyColumns+`
+		FROM order_dependencies
+		WHERE order_id IN (SELECT id FROM orders WHERE project_id = ?)
+			AND predecessor_id IN (SELECT id FROM orders WHERE project_id = ?)
+		ORDER BY created_at, order_id, predecessor_id
+	`, projectID, projectID)
+}
+
+// PendingPredecessors returns the ids of predecessors that are not done. A
+// cancelled predecessor still blocks: the work it stood for was not carried
+// out, so the dependency has to be removed (or the predecessor deleted)
+// explicitly. Deleted orders no longer hold anything up.
+func (r *OrderDependencyRepository) PendingPredecessors(ctx context.Context, orderID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT o.id
+		FROM order_dependencies d
+		JOIN orders o ON o.id = d.predecessor_id
+		WHERE d.order_id = ? AND o.status <> 'done' AND o.deleted_at IS NULL
+		ORDER BY d.created_at, o.id
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,60 @@
+//go:build sqlite_fts5
+
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"frame_control_system/internal/models"
+)
+
+func TestPendingPredecessors(t *testing.T) {
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	ctx := context.Background()
+	if err := NewUserRepository(db).Create(ctx, models.User{ID: "u1", Email: "u1@x.io", PasswordHash: "x", Name: "u1", Roles: []string{"user"}}); err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	orders := NewOrderRepository(db)
+	deps := NewOrderDependencyRepository(db)
+	statuses := map[string]models.OrderStatus{
+		"created":     models.OrderStatusCreated,
+		"in_progress": models.OrderStatusInProgress,
+		"done":        models.OrderStatusDone,
+		"cancelled":   models.OrderStatusCancelled,
+		"deleted":     models.OrderStatusCancelled,
+		"successor":   models.OrderStatusCreated,
+	}
+	for id, st := range statuses {
+		if err := orders.Create(ctx, models.Order{ID: id, UserID: "u1", Status: st, Items: []models.OrderItem{}}); err != nil {
+			t.Fatalf("order %s: %v", id, err)
+		}
+	}
+	if err := orders.SoftDelete(ctx, "deleted", time.Now()); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	for _, id := range []string{"created", "in_progress", "done", "cancelled", "deleted"} {
+		if _, err := deps.Add(ctx, &models.OrderDependency{OrderID: "successor", PredecessorID: id, CreatedBy: "u1"}); err != nil {
+			t.Fatalf("dependency %s: %v", id, err)
+		}
+	}
+	got, err := deps.PendingPredecessors(ctx, "successor")
+	if err != nil {
+		t.Fatalf("pending: %v", err)
+	}
+	// Same created_at, so ties are broken by id.
+	want := []string{"cancelled", "created", "in_progress"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
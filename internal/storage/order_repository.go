@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,117 +16,632 @@ import (
 	"frame_control_system/internal/models"
 )
 
+var ErrOrderNotEditable = errors.New("order is not editable")
+
 type OrderRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewOrderRepository(db *sql.DB) *OrderRepository {
+func NewOrderRepository(db DBTX) *OrderRepository {
 	return &OrderRepository{db: db}
 }
 
+const orderColumns = `id, user_id, assignee_id, project_id, items, status, total_amount, notes, due_at, priority, duration_days,
+	tags, custom_fields, discount_percent, discount_fixed, promo, breakdown, archived_at, deleted_at, created_at, updated_at,
+	(SELECT COALESCE(SUM(duration_seconds), 0) FROM time_entries WHERE order_id = orders.id AND ended_at IS NOT NULL)`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOrder(row rowScanner) (models.Order, error) {
+	var itemsStr, status, priority, tags, customFields, breakdown, createdAt, updatedAt string
+	var assigneeID, projectID, dueAt, promo, archivedAt, deletedAt sql.NullString
+	var loggedSeconds int64
+	var o models.Order
+	if err := row.Scan(&o.ID, &o.UserID, &assigneeID, &projectID, &itemsStr, &status, &o.TotalAmount, &o.Notes, &dueAt, &priority, &o.DurationDays,
+		&tags, &customFields, &o.DiscountPercent, &o.DiscountFixed, &promo, &breakdown, &archivedAt, &deletedAt, &createdAt, &updatedAt,
+		&loggedSeconds); err != nil {
+		return models.Order{}, err
+	}
+	_ = json.Unmarshal([]byte(itemsStr), &o.Items)
+	_ = json.Unmarshal([]byte(breakdown), &o.Breakdown)
+	_ = json.Unmarshal([]byte(tags), &o.Tags)
+	_ = json.Unmarshal([]byte(customFields), &o.CustomFields)
+	if o.Tags == nil {
+		o.Tags = []string{}
+	}
+	if o.CustomFields == nil {
+		o.CustomFields = map[string]any{}
+	}
+	if promo.Valid {
+		o.Promo = &models.AppliedPromo{}
+		_ = json.Unmarshal([]byte(promo.String), o.Promo)
+	}
+	o.Status = models.OrderStatus(status)
+	o.AssigneeID = assigneeID.String
+	o.ProjectID = projectID.String
+	o.DueAt = parseNullTime(dueAt)
+	o.Priority = models.OrderPriority(priority)
+	o.ArchivedAt = parseNullTime(archivedAt)
+	o.DeletedAt = parseNullTime(deletedAt)
+	o.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	o.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	o.Overdue = o.IsOverdue(time.Now())
+	o.LoggedHours = models.Hours(loggedSeconds)
+	return o, nil
+}
+
+func parseNullTime(s sql.NullString) *time.Time {
+	if !s.Valid {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s.String)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// nullTime formats an optional time for storage, NULL when t is nil.
+func nullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
 func (r *OrderRepository) Create(ctx context.Context, o models.Order) error {
 	itemsJSON, _ := json.Marshal(o.Items)
 	now := time.Now().UTC().Format(time.RFC3339)
+	if o.Priority == "" {
+		o.Priority = models.OrderPriorityNormal
+	}
+	breakdownJSON, _ := json.Marshal(o.Breakdown)
+	tagsJSON, fieldsJSON := attributesJSON(o.Tags, o.CustomFields)
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO orders (id, user_id, items, status, total_amount, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, o.ID, o.UserID, string(itemsJSON), string(o.Status), o.TotalAmount, now, now)
+		INSERT INTO orders (id, user_id, project_id, items, status, total_amount, notes, due_at, priority, duration_days,
+			tags, custom_fields, discount_percent, discount_fixed, promo, breakdown, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, o.ID, o.UserID, nullString(o.ProjectID), string(itemsJSON), string(o.Status), o.TotalAmount, o.Notes, nullTime(o.DueAt), string(o.Priority),
+		o.DurationDays, tagsJSON, fieldsJSON, o.DiscountPercent, o.DiscountFixed, nullPromo(o.Promo), string(breakdownJSON), now, now)
 	return err
 }
 
+// attributesJSON encodes tags and custom fields, storing nil as empty values.
+func attributesJSON(tags []string, fields map[string]any) (string, string) {
+	if tags == nil {
+		tags = []string{}
+	}
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	t, _ := json.Marshal(tags)
+	f, _ := json.Marshal(fields)
+	return string(t), string(f)
+}
+
+func nullPromo(p *models.AppliedPromo) any {
+	if p == nil {
+		return nil
+	}
+	b, _ := json.Marshal(p)
+	return string(b)
+}
+
 func (r *OrderRepository) GetByID(ctx context.Context, id string) (*models.Order, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, user_id, items, status, total_amount, created_at, updated_at
+		SELECT `+orderColumns+`
 		FROM orders WHERE id = ?
 	`, id)
-	var itemsStr, status, createdAt, updatedAt string
-	var o models.Order
-	if err := row.Scan(&o.ID, &o.UserID, &itemsStr, &status, &o.TotalAmount, &createdAt, &updatedAt); err != nil {
+	o, err := scanOrder(row)
+	if err != nil {
 		return nil, err
 	}
-	_ = json.Unmarshal([]byte(itemsStr), &o.Items)
-	o.Status = models.OrderStatus(status)
-	o.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-	o.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
 	return &o, nil
 }
 
 type ListOrdersParams struct {
-	UserID string
-	Status string
-	Sort   string
-	Limit  int
-	Offset int
+	UserID   string
+	Statuses []string
+	// OwnerID narrows an admin view down to a single order owner.
+	OwnerID string
+	// AssigneeID keeps orders assigned to the given user; Unassigned keeps
+	// orders without an assignee.
+	AssigneeID string
+	Unassigned bool
+	// ProjectID keeps orders of the given project.
+	ProjectID string
+	// Date bounds: *From are inclusive, *To are exclusive.
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	MinTotal    *float64
+	MaxTotal    *float64
+	// ItemName matches orders having an item whose name contains the value.
+	ItemName   string
+	Priorities []string
+	// Overdue keeps open orders whose due date has passed.
+	Overdue bool
+	// DueBefore keeps open orders due before the given time (due soon).
+	DueBefore *time.Time
+	// Archived orders are left out unless IncludeArchived is set; soft-deleted
+	// orders are left out unless Deleted asks for the trash only.
+	IncludeArchived bool
+	Deleted         bool
+	// Tags keeps orders carrying all of the given tags.
+	Tags []string
+	// CustomFields keeps orders whose custom field equals the value; numbers
+	// match their decimal form ("12" matches 12).
+	CustomFields map[string]string
+	Sort         string
+	Limit        int
+	Offset       int
+	// After switches to keyset pagination; only valid with created_* sorts.
+	After     *Cursor
 	AdminView bool
 }
 
-func (r *OrderRepository) List(ctx context.Context, p ListOrdersParams) ([]models.Order, error) {
+var orderSorts = map[string]string{
+	"created_asc":  "created_at ASC, id ASC",
+	"created_desc": "created_at DESC, id DESC",
+	"updated_asc":  "updated_at ASC, id ASC",
+	"updated_desc": "updated_at DESC, id DESC",
+	"total_asc":    "total_amount ASC, id ASC",
+	"total_desc":   "total_amount DESC, id DESC",
+	"due_asc":      "due_at IS NULL, due_at ASC, id ASC",
+	"due_desc":     "due_at IS NULL, due_at DESC, id DESC",
+	"priority_desc": `CASE priority WHEN 'urgent' THEN 0 WHEN 'high' THEN 1 WHEN 'normal' THEN 2 ELSE 3 END,
+		due_at IS NULL, due_at ASC, id ASC`,
+}
+
+// ValidOrderSort reports whether s is a supported sort value ("" means default).
+func ValidOrderSort(s string) bool {
+	_, ok := orderSorts[s]
+	return ok || s == ""
+}
+
+// KeysetSort reports whether the sort order supports cursor pagination.
+func (p ListOrdersParams) KeysetSort() bool {
+	return p.Sort == "" || p.Sort == "created_asc" || p.Sort == "created_desc"
+}
+
+func (p ListOrdersParams) filter() ([]string, []interface{}) {
 	where := []string{"1=1"}
 	args := []interface{}{}
+	if p.Deleted {
+		where = append(where, "deleted_at IS NOT NULL")
+	} else {
+		where = append(where, "deleted_at IS NULL")
+		if !p.IncludeArchived {
+			where = append(where, "archived_at IS NULL")
+		}
+	}
 	if !p.AdminView {
+		where = append(where, "(user_id = ? OR assignee_id = ? OR project_id IN (SELECT project_id FROM project_members WHERE user_id = ?)"+
+			" OR id IN (SELECT order_id FROM order_viewers WHERE user_id = ?))")
+		args = append(args, p.UserID, p.UserID, p.UserID, p.UserID)
+	}
+	if p.ProjectID != "" {
+		where = append(where, "project_id = ?")
+		args = append(args, p.ProjectID)
+	}
+	if p.OwnerID != "" {
 		where = append(where, "user_id = ?")
-		args = append(args, p.UserID)
+		args = append(args, p.OwnerID)
+	}
+	if p.AssigneeID != "" {
+		where = append(where, "assignee_id = ?")
+		args = append(args, p.AssigneeID)
+	}
+	if p.Unassigned {
+		where = append(where, "assignee_id IS NULL")
+	}
+	if len(p.Statuses) > 0 {
+		where = append(where, "status IN ("+placeholders(len(p.Statuses))+")")
+		for _, st := range p.Statuses {
+			args = append(args, st)
+		}
+	}
+	timeBound := func(col, op string, t *time.Time) {
+		if t != nil {
+			where = append(where, col+" "+op+" ?")
+			args = append(args, t.UTC().Format(time.RFC3339))
+		}
+	}
+	timeBound("created_at", ">=", p.CreatedFrom)
+	timeBound("created_at", "<", p.CreatedTo)
+	timeBound("updated_at", ">=", p.UpdatedFrom)
+	timeBound("updated_at", "<", p.UpdatedTo)
+	if p.MinTotal != nil {
+		where = append(where, "total_amount >= ?")
+		args = append(args, *p.MinTotal)
+	}
+	if p.MaxTotal != nil {
+		where = append(where, "total_amount <= ?")
+		args = append(args, *p.MaxTotal)
+	}
+	if p.ItemName != "" {
+		where = append(where, `EXISTS (
+			SELECT 1 FROM json_each(orders.items)
+			WHERE json_extract(json_each.value, '$.name') LIKE ? ESCAPE '\'
+		)`)
+		args = append(args, "%"+escapeLike(p.ItemName)+"%")
+	}
+	for _, tag := range p.Tags {
+		where = append(where, "EXISTS (SELECT 1 FROM json_each(orders.tags) WHERE json_each.value = ?)")
+		args = append(args, tag)
+	}
+	keys := make([]string, 0, len(p.CustomFields))
+	for k := range p.CustomFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		// Keys are validated by the caller, so they are safe inside the JSON path.
+		path := `'$."` + k + `"'`
+		v := p.CustomFields[k]
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			where = append(where, "(json_extract(custom_fields, "+path+") = ? OR json_extract(custom_fields, "+path+") = ?)")
+			args = append(args, v, f)
+		} else {
+			where = append(where, "json_extract(custom_fields, "+path+") = ?")
+			args = append(args, v)
+		}
+	}
+	if len(p.Priorities) > 0 {
+		where = append(where, "priority IN ("+placeholders(len(p.Priorities))+")")
+		for _, pr := range p.Priorities {
+			args = append(args, pr)
+		}
 	}
-	if p.Status != "" {
-		where = append(where, "status = ?")
-		args = append(args, p.Status)
+	now := time.Now().UTC().Format(time.RFC3339)
+	if p.Overdue {
+		where = append(where, "due_at < ? AND status IN ('created', 'pending_approval', 'in_progress')")
+		args = append(args, now)
+	}
+	if p.DueBefore != nil {
+		where = append(where, "due_at >= ? AND due_at < ? AND status IN ('created', 'pending_approval', 'in_progress')")
+		args = append(args, now, p.DueBefore.UTC().Format(time.RFC3339))
+	}
+	return where, args
+}
+
+func (r *OrderRepository) List(ctx context.Context, p ListOrdersParams) ([]models.Order, error) {
+	var res []models.Order
+	err := r.Each(ctx, p, func(o models.Order) error {
+		res = append(res, o)
+		return nil
+	})
+	return res, err
+}
+
+// Each streams matching orders to fn straight from the database cursor, so
+// callers can process large results without holding them in memory. A zero
+// Limit means no limit. Iteration stops at the first error returned by fn.
+func (r *OrderRepository) Each(ctx context.Context, p ListOrdersParams, fn func(models.Order) error) error {
+	where, args := p.filter()
+	order := orderSorts["created_desc"]
+	if o, ok := orderSorts[p.Sort]; ok {
+		order = o
+	}
+	offset := p.Offset
+	if p.After != nil && p.KeysetSort() {
+		cond, cargs := keysetCondition(*p.After, p.Sort != "created_asc")
+		where = append(where, cond)
+		args = append(args, cargs...)
+		offset = 0
 	}
-	order := "created_at DESC"
-	switch p.Sort {
-	case "created_asc":
-		order = "created_at ASC"
-	case "created_desc":
-		order = "created_at DESC"
+	limit := p.Limit
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
 	}
 	query := `
-		SELECT id, user_id, items, status, total_amount, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM orders
 		WHERE ` + strings.Join(where, " AND ") + `
 		ORDER BY ` + order + `
 		LIMIT ? OFFSET ?
 	`
-	args = append(args, p.Limit, p.Offset)
+	args = append(args, limit, offset)
 	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Count returns the number of orders matching the filters, ignoring pagination.
+func (r *OrderRepository) Count(ctx context.Context, p ListOrdersParams) (int, error) {
+	where, args := p.filter()
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders WHERE `+strings.Join(where, " AND "), args...).Scan(&n)
+	return n, err
+}
+
+// UpdateStatus moves the order from one status to another. It returns
+// ErrOrderNotEditable if the order is no longer in the from status.
+func (r *OrderRepository) UpdateStatus(ctx context.Context, id string, from, to models.OrderStatus) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE orders SET status = ?, updated_at = ? WHERE id = ? AND status = ?
+	`, string(to), now, id, string(from))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOrderNotEditable
+	}
+	return nil
+}
+
+// UpdateEditable saves the editable fields (items, notes, discounts and the
+// resulting price) of an order that is still created or awaiting approval.
+func (r *OrderRepository) UpdateEditable(ctx context.Context, o models.Order) error {
+	itemsJSON, _ := json.Marshal(o.Items)
+	breakdownJSON, _ := json.Marshal(o.Breakdown)
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE orders SET items = ?, total_amount = ?, notes = ?,
+			discount_percent = ?, discount_fixed = ?, breakdown = ?, updated_at = ?
+		WHERE id = ? AND status IN (?, ?)
+	`, string(itemsJSON), o.TotalAmount, o.Notes, o.DiscountPercent, o.DiscountFixed, string(breakdownJSON),
+		now, o.ID, string(models.OrderStatusCreated), string(models.OrderStatusPendingApproval))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOrderNotEditable
+	}
+	return nil
+}
+
+// UpdateSchedule changes the due date, priority and planned duration of an
+// open order. Moving the due date re-arms the overdue notification.
+func (r *OrderRepository) UpdateSchedule(ctx context.Context, id string, dueAt *time.Time, priority models.OrderPriority, durationDays int) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE orders SET
+			overdue_notified_at = CASE WHEN due_at IS ? THEN overdue_notified_at ELSE NULL END,
+			due_at = ?, priority = ?, duration_days = ?, updated_at = ?
+		WHERE id = ? AND status IN ('created', 'pending_approval', 'in_progress')
+	`, nullTime(dueAt), nullTime(dueAt), string(priority), durationDays, now, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOrderNotEditable
+	}
+	return nil
+}
+
+// UpdateAttributes replaces the tags and custom fields of an open order.
+func (r *OrderRepository) UpdateAttributes(ctx context.Context, id string, tags []string, fields map[string]any) error {
+	tagsJSON, fieldsJSON := attributesJSON(tags, fields)
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE orders SET tags = ?, custom_fields = ?, updated_at = ?
+		WHERE id = ? AND status IN ('created', 'pending_approval', 'in_progress') AND deleted_at IS NULL
+	`, tagsJSON, fieldsJSON, now, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOrderNotEditable
+	}
+	return nil
+}
+
+// ListOverdueUnnotified returns open, past-due orders that have not had an
+// order.overdue event yet.
+func (r *OrderRepository) ListOverdueUnnotified(ctx context.Context, now time.Time, limit int) ([]models.Order, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+orderColumns+`
+		FROM orders
+		WHERE due_at < ? AND status IN ('created', 'pending_approval', 'in_progress') AND overdue_notified_at IS NULL
+		ORDER BY due_at
+		LIMIT ?
+	`, now.UTC().Format(time.RFC3339), limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var res []models.Order
 	for rows.Next() {
-		var o models.Order
-		var itemsStr, status, createdAt, updatedAt string
-		if err := rows.Scan(&o.ID, &o.UserID, &itemsStr, &status, &o.TotalAmount, &createdAt, &updatedAt); err != nil {
+		o, err := scanOrder(rows)
+		if err != nil {
 			return nil, err
 		}
-		_ = json.Unmarshal([]byte(itemsStr), &o.Items)
-		o.Status = models.OrderStatus(status)
-		o.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-		o.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
 		res = append(res, o)
 	}
 	return res, rows.Err()
 }
 
-func (r *OrderRepository) UpdateStatus(ctx context.Context, id string, to models.OrderStatus) error {
+// MarkOverdueNotified flags the order as notified; it returns false if another
+// worker got there first.
+func (r *OrderRepository) MarkOverdueNotified(ctx context.Context, id string, at time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE orders SET overdue_notified_at = ? WHERE id = ? AND overdue_notified_at IS NULL
+	`, at.UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// Assign sets or clears (assigneeID == "") the executor of an open order.
+func (r *OrderRepository) Assign(ctx context.Context, id, assigneeID string) error {
+	var assignee any
+	if assigneeID != "" {
+		assignee = assigneeID
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE orders SET assignee_id = ?, updated_at = ?
+		WHERE id = ? AND status IN ('created', 'pending_approval', 'in_progress')
+	`, assignee, now, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOrderNotEditable
+	}
+	return nil
+}
+
+// SetProject moves an open order to another project; projectID "" detaches it.
+func (r *OrderRepository) SetProject(ctx context.Context, id, projectID string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE orders SET project_id = ?, updated_at = ?
+		WHERE id = ? AND status IN ('created', 'pending_approval', 'in_progress') AND deleted_at IS NULL
+	`, nullString(projectID), now, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOrderNotEditable
+	}
+	return nil
+}
+
+// SoftDelete moves a done or cancelled order to the trash.
+func (r *OrderRepository) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	ts := at.UTC().Format(time.RFC3339)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE orders SET deleted_at = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL AND status IN ('done', 'cancelled')
+	`, ts, ts, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOrderNotEditable
+	}
+	return nil
+}
+
+// Restore takes an order out of the trash and the archive. updated_at is
+// bumped so the archiver does not pick the order up again right away. It
+// returns false if the order was neither deleted nor archived.
+func (r *OrderRepository) Restore(ctx context.Context, id string, at time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE orders SET deleted_at = NULL, archived_at = NULL, updated_at = ?
+		WHERE id = ? AND (deleted_at IS NOT NULL OR archived_at IS NOT NULL)
+	`, at.UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// ListArchivable returns done or cancelled orders last updated before the
+// given time that are neither archived nor deleted.
+func (r *OrderRepository) ListArchivable(ctx context.Context, before time.Time, limit int) ([]models.Order, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+orderColumns+`
+		FROM orders
+		WHERE status IN ('done', 'cancelled') AND archived_at IS NULL AND deleted_at IS NULL AND updated_at < ?
+		ORDER BY updated_at
+		LIMIT ?
+	`, before.UTC().Format(time.RFC3339), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Order
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, o)
+	}
+	return res, rows.Err()
+}
+
+// MarkArchived archives the order; it returns false if it was archived or
+// deleted in the meantime. updated_at is left alone.
+func (r *OrderRepository) MarkArchived(ctx context.Context, id string, at time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE orders SET archived_at = ? WHERE id = ? AND archived_at IS NULL AND deleted_at IS NULL
+	`, at.UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// StatusHistory returns the status changes of the order, oldest first.
+func (r *OrderRepository) StatusHistory(ctx context.Context, orderID string) ([]models.OrderStatusChange, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT from_status, to_status, changed_at FROM order_status_history
+		WHERE order_id = ?
+		ORDER BY changed_at, id
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.OrderStatusChange{}
+	for rows.Next() {
+		var from, to, at string
+		if err := rows.Scan(&from, &to, &at); err != nil {
+			return nil, err
+		}
+		t, _ := time.Parse(time.RFC3339, at)
+		res = append(res, models.OrderStatusChange{From: models.OrderStatus(from), To: models.OrderStatus(to), ChangedAt: t})
+	}
+	return res, rows.Err()
+}
+
+// AddStatusHistory records a status change; reports use it to measure lead times.
+func (r *OrderRepository) AddStatusHistory(ctx context.Context, orderID string, from, to models.OrderStatus, changedBy string) error {
 	now := time.Now().UTC().Format(time.RFC3339)
 	_, err := r.db.ExecContext(ctx, `
-		UPDATE orders SET status = ?, updated_at = ? WHERE id = ?
-	`, string(to), now, id)
+		INSERT INTO order_status_history (id, order_id, from_status, to_status, changed_by, changed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, uuid.NewString(), orderID, string(from), string(to), changedBy, now)
 	return err
 }
 
-func (r *OrderRepository) Cancel(ctx context.Context, id string) error {
-	return r.UpdateStatus(ctx, id, models.OrderStatusCancelled)
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
 }
 
+// escapeLike escapes LIKE wildcards so the value is matched literally.
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return r.Replace(s)
+}
+
+// CalculateTotal sums the items. Quantities must be positive and prices
+// finite and non-negative; a total that overflows is rejected too.
 func CalculateTotal(items []models.OrderItem) (float64, error) {
 	var total float64
 	for _, it := range items {
-		if it.Quantity <= 0 || it.Price < 0 {
+		if it.Quantity <= 0 || it.Price < 0 || math.IsNaN(it.Price) || math.IsInf(it.Price, 0) {
 			return 0, errors.New("invalid item")
 		}
 		total += float64(it.Quantity) * it.Price
 	}
+	if math.IsInf(total, 0) {
+		return 0, errors.New("total too large")
+	}
 	return total, nil
 }
 
@@ -133,11 +651,15 @@ func NewOrder(userID string, items []models.OrderItem) (models.Order, error) {
 		return models.Order{}, err
 	}
 	return models.Order{
-		ID:          uuid.NewString(),
-		UserID:      userID,
-		Items:       items,
-		Status:      models.OrderStatusCreated,
-		TotalAmount: total,
+		ID:           uuid.NewString(),
+		UserID:       userID,
+		Items:        items,
+		Status:       models.OrderStatusCreated,
+		TotalAmount:  total,
+		Priority:     models.OrderPriorityNormal,
+		Tags:         []string{},
+		CustomFields: map[string]any{},
+		Breakdown:    models.PriceBreakdown{Subtotal: total, Total: total},
 	}, nil
 }
 
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"math"
 	"testing"
 
 	"frame_control_system/internal/models"
@@ -30,4 +31,14 @@ func TestCalculateTotal_Invalid(t *testing.T) {
 	}
 }
 
-
+func TestCalculateTotal_NonFinite(t *testing.T) {
+	for _, it := range []models.OrderItem{
+		{Name: "a", Quantity: 1, Price: math.NaN()},
+		{Name: "a", Quantity: 1, Price: math.Inf(1)},
+		{Name: "a", Quantity: 2, Price: math.MaxFloat64},
+	} {
+		if _, err := CalculateTotal([]models.OrderItem{it}); err == nil {
+			t.Fatalf("expected error for price %v x %d", it.Price, it.Quantity)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"frame_control_system/internal/models"
+)
+
+type OrderViewerRepository struct {
+	db DBTX
+}
+
+func NewOrderViewerRepository(db DBTX) *OrderViewerRepository {
+	return &OrderViewerRepository{db: db}
+}
+
+// Add grants the user read-only access to the order; it returns false if the
+// user already had it.
+func (r *OrderViewerRepository) Add(ctx context.Context, v *models.OrderViewer) (bool, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO order_viewers (order_id, user_id, added_by, added_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (order_id, user_id) DO NOTHING
+	`, v.OrderID, v.UserID, v.AddedBy, now.Format(time.RFC3339))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	if n == 1 {
+		v.AddedAt = now
+	}
+	return n == 1, nil
+}
+
+// Get returns the grant; sql.ErrNoRows if the user has none.
+func (r *OrderViewerRepository) Get(ctx context.Context, orderID, userID string) (*models.OrderViewer, error) {
+	var v models.OrderViewer
+	var addedAt string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT order_id, user_id, added_by, added_at FROM order_viewers WHERE order_id = ? AND user_id = ?
+	`, orderID, userID).Scan(&v.OrderID, &v.UserID, &v.AddedBy, &addedAt)
+	if err != nil {
+		return nil, err
+	}
+	v.AddedAt, _ = time.Parse(time.RFC3339, addedAt)
+	return &v, nil
+}
+
+// List returns the viewers of an order in the order they were added.
+func (r *OrderViewerRepository) List(ctx context.Context, orderID string) ([]models.OrderViewer, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT order_id, user_id, added_by, added_at FROM order_viewers
+		WHERE order_id = ?
+		ORDER BY added_at, user_id
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.OrderViewer{}
+	for rows.Next() {
+		var v models.OrderViewer
+		var addedAt string
+		if err := rows.Scan(&v.OrderID, &v.UserID, &v.AddedBy, &addedAt); err != nil {
+			return nil, err
+		}
+		v.AddedAt, _ = time.Parse(time.RFC3339, addedAt)
+		res = append(res, v)
+	}
+	return res, rows.Err()
+}
+
+func (r *OrderViewerRepository) Remove(ctx context.Context, orderID, userID string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM order_viewers WHERE order_id = ? AND user_id = ?`, orderID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -2,7 +2,6 @@ package storage
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"time"
 
@@ -16,7 +15,7 @@ type OutboxEvent struct {
 	CreatedAt time.Time
 }
 
-func AddOutboxEvent(ctx context.Context, db *sql.DB, eventType string, payload any) error {
+func AddOutboxEvent(ctx context.Context, db DBTX, eventType string, payload any) error {
 	id := uuid.NewString()
 	now := time.Now().UTC().Format(time.RFC3339)
 	body, _ := json.Marshal(payload)
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"frame_control_system/internal/models"
+)
+
+// TaxRates holds VAT rates in percent. Prices are net, tax is added on top.
+type TaxRates struct {
+	Default    float64
+	ByCategory map[string]float64
+}
+
+// Rate returns the VAT rate for an item category.
+func (t TaxRates) Rate(category string) float64 {
+	if r, ok := t.ByCategory[category]; ok {
+		return r
+	}
+	return t.Default
+}
+
+func validPercent(p float64) bool {
+	return p >= 0 && p <= 100
+}
+
+// PriceOrder computes the order breakdown. Line discounts come first, then the
+// order discount (percent, then fixed) and the promo code on what is left;
+// discounts never take the amount below zero. Order-level discounts are spread
+// over the lines in proportion to their amounts, and VAT is charged per rate
+// on the discounted amounts.
+func PriceOrder(items []models.OrderItem, discountPercent, discountFixed float64, promo *models.AppliedPromo, rates TaxRates) (models.PriceBreakdown, error) {
+	if _, err := CalculateTotal(items); err != nil {
+		return models.PriceBreakdown{}, err
+	}
+	if !validPercent(discountPercent) || discountFixed < 0 {
+		return models.PriceBreakdown{}, errors.New("invalid order discount")
+	}
+	var b models.PriceBreakdown
+	nets := make([]float64, len(items))
+	var net float64
+	for i, it := range items {
+		if !validPercent(it.DiscountPercent) {
+			return models.PriceBreakdown{}, errors.New("invalid item discount")
+		}
+		gross := roundCents(float64(it.Quantity) * it.Price)
+		nets[i] = LineNet(it)
+		b.Subtotal += gross
+		net += nets[i]
+	}
+	b.Subtotal = roundCents(b.Subtotal)
+	net = roundCents(net)
+	b.LineDiscount = roundCents(b.Subtotal - net)
+
+	b.OrderDiscount = math.Min(roundCents(net*discountPercent/100+discountFixed), net)
+	rest := roundCents(net - b.OrderDiscount)
+	if promo != nil {
+		switch promo.Kind {
+		case models.PromoPercent:
+			b.PromoDiscount = roundCents(rest * promo.Value / 100)
+		case models.PromoFixed:
+			b.PromoDiscount = math.Min(promo.Value, rest)
+		}
+	}
+	docDiscount := b.OrderDiscount + b.PromoDiscount
+	b.Discount = roundCents(b.LineDiscount + docDiscount)
+
+	taxable := map[float64]float64{}
+	for i, it := range items {
+		share := nets[i]
+		if net > 0 {
+			share -= docDiscount * nets[i] / net
+		}
+		taxable[rates.Rate(it.Category)] += share
+	}
+	// Sum per rate in a fixed order so the result does not depend on map order.
+	rs := make([]float64, 0, len(taxable))
+	for r := range taxable {
+		rs = append(rs, r)
+	}
+	sort.Float64s(rs)
+	for _, r := range rs {
+		b.Tax += roundCents(taxable[r] * r / 100)
+	}
+	b.Tax = roundCents(b.Tax)
+	b.Total = roundCents(b.Subtotal - b.Discount + b.Tax)
+	return b, nil
+}
+
+// LineNet is the line amount after its own discount, before order-level
+// discounts and VAT.
+func LineNet(it models.OrderItem) float64 {
+	gross := roundCents(float64(it.Quantity) * it.Price)
+	return gross - roundCents(gross*it.DiscountPercent/100)
+}
+
+// Reprice recomputes the breakdown and total of o from its items and discounts.
+func Reprice(o *models.Order, rates TaxRates) error {
+	b, err := PriceOrder(o.Items, o.DiscountPercent, o.DiscountFixed, o.Promo, rates)
+	if err != nil {
+		return err
+	}
+	o.Breakdown = b
+	o.TotalAmount = b.Total
+	return nil
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
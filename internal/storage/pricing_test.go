@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"testing"
+
+	"frame_control_system/internal/models"
+)
+
+func TestPriceOrder_NoDiscountsNoTax(t *testing.T) {
+	items := []models.OrderItem{
+		{Name: "a", Quantity: 2, Price: 10},
+		{Name: "b", Quantity: 1, Price: 5.5},
+	}
+	b, err := PriceOrder(items, 0, 0, nil, TaxRates{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := models.PriceBreakdown{Subtotal: 25.5, Total: 25.5}
+	if b != want {
+		t.Fatalf("want %+v, got %+v", want, b)
+	}
+}
+
+func TestPriceOrder_DiscountsAndTax(t *testing.T) {
+	items := []models.OrderItem{
+		{Name: "frame", Quantity: 2, Price: 100, DiscountPercent: 10, Category: "goods"},
+		{Name: "book", Quantity: 1, Price: 20, Category: "books"},
+	}
+	rates := TaxRates{Default: 20, ByCategory: map[string]float64{"books": 10}}
+	promo := &models.AppliedPromo{Code: "SALE", Kind: models.PromoFixed, Value: 10}
+	b, err := PriceOrder(items, 5, 1, promo, rates)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Lines: 200-20=180 and 20; order discount 5% of 200 + 1 = 11, promo 10.
+	// The 21 of order discounts splits 18.9/2.1, so VAT is 20% of 161.1 plus
+	// 10% of 17.9.
+	want := models.PriceBreakdown{
+		Subtotal:      220,
+		LineDiscount:  20,
+		OrderDiscount: 11,
+		PromoDiscount: 10,
+		Discount:      41,
+		Tax:           34.01,
+		Total:         213.01,
+	}
+	if b != want {
+		t.Fatalf("want %+v, got %+v", want, b)
+	}
+}
+
+func TestPriceOrder_DiscountCappedAtAmount(t *testing.T) {
+	items := []models.OrderItem{{Name: "a", Quantity: 1, Price: 30}}
+	promo := &models.AppliedPromo{Code: "BIG", Kind: models.PromoFixed, Value: 100}
+	b, err := PriceOrder(items, 0, 20, promo, TaxRates{Default: 20})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.OrderDiscount != 20 || b.PromoDiscount != 10 || b.Tax != 0 || b.Total != 0 {
+		t.Fatalf("unexpected breakdown %+v", b)
+	}
+}
+
+func TestPriceOrder_Invalid(t *testing.T) {
+	items := []models.OrderItem{{Name: "a", Quantity: 1, Price: 10, DiscountPercent: 120}}
+	if _, err := PriceOrder(items, 0, 0, nil, TaxRates{}); err == nil {
+		t.Fatalf("expected error for item discount over 100%%")
+	}
+	items[0].DiscountPercent = 0
+	if _, err := PriceOrder(items, -1, 0, nil, TaxRates{}); err == nil {
+		t.Fatalf("expected error for negative order discount")
+	}
+}
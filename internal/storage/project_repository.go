@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"frame_control_system/internal/models"
+)
+
+type ProjectRepository struct {
+	db DBTX
+}
+
+func NewProjectRepository(db DBTX) *ProjectRepository {
+	return &ProjectRepository{db: db}
+}
+
+const projectColumns = `id, name, address, customer, start_date, end_date, status, created_by, created_at, updated_at`
+
+func scanProject(row rowScanner) (models.Project, error) {
+	var p models.Project
+	var status, createdAt, updatedAt string
+	var startDate, endDate sql.NullString
+	if err := row.Scan(&p.ID, &p.Name, &p.Address, &p.Customer, &startDate, &endDate, &status, &p.CreatedBy, &createdAt, &updatedAt); err != nil {
+		return models.Project{}, err
+	}
+	p.StartDate = startDate.String
+	p.EndDate = endDate.String
+	p.Status = models.ProjectStatus(status)
+	p.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	p.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return p, nil
+}
+
+// nullString stores an empty string as NULL.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// Create fills in ID and timestamps and stores the project.
+func (r *ProjectRepository) Create(ctx context.Context, p *models.Project) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	p.ID = uuid.NewString()
+	p.CreatedAt, p.UpdatedAt = now, now
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO projects (id, name, address, customer, start_date, end_date, status, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.ID, p.Name, p.Address, p.Customer, nullString(p.StartDate), nullString(p.EndDate), string(p.Status), p.CreatedBy,
+		now.Format(time.RFC3339), now.Format(time.RFC3339))
+	return err
+}
+
+// GetByID returns the project; sql.ErrNoRows if there is none.
+func (r *ProjectRepository) GetByID(ctx context.Context, id string) (*models.Project, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+projectColumns+` FROM projects WHERE id = ?`, id)
+	p, err := scanProject(row)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+type ListProjectsParams struct {
+	// Without AdminView only projects UserID is a member of are returned.
+	UserID    string
+	AdminView bool
+	Statuses  []string
+	Limit     int
+	Offset    int
+	After     *Cursor
+}
+
+func (p ListProjectsParams) filter() ([]string, []interface{}) {
+	where := []string{"1=1"}
+	args := []interface{}{}
+	if !p.AdminView {
+		where = append(where, "id IN (SELECT project_id FROM project_members WHERE user_id = ?)")
+		args = append(args, p.UserID)
+	}
+	if len(p.Statuses) > 0 {
+		where = append(where, "status IN ("+placeholders(len(p.Statuses))+")")
+		for _, st := range p.Statuses {
+			args = append(args, st)
+		}
+	}
+	return where, args
+}
+
+// List returns projects newest first.
+func (r *ProjectRepository) List(ctx context.Context, p ListProjectsParams) ([]models.Project, error) {
+	where, args := p.filter()
+	offset := p.Offset
+	if p.After != nil {
+		cond, cargs := keysetCondition(*p.After, true)
+		where = append(where, cond)
+		args = append(args, cargs...)
+		offset = 0
+	}
+	args = append(args, p.Limit, offset)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+projectColumns+`
+		FROM projects
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.Project{}
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
+// Count returns the number of projects matching the filters, ignoring pagination.
+func (r *ProjectRepository) Count(ctx context.Context, p ListProjectsParams) (int, error) {
+	where, args := p.filter()
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM projects WHERE `+strings.Join(where, " AND "), args...).Scan(&n)
+	return n, err
+}
+
+// Update saves the editable fields of the project.
+func (r *ProjectRepository) Update(ctx context.Context, p *models.Project) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE projects SET name = ?, address = ?, customer = ?, start_date = ?, end_date = ?, status = ?, updated_at = ?
+		WHERE id = ?
+	`, p.Name, p.Address, p.Customer, nullString(p.StartDate), nullString(p.EndDate), string(p.Status),
+		now.Format(time.RFC3339), p.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	p.UpdatedAt = now
+	return nil
+}
+
+// Delete removes the project and its memberships.
+func (r *ProjectRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM projects WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// HasOrders reports whether any order, deleted ones included, belongs to the project.
+func (r *ProjectRepository) HasOrders(ctx context.Context, id string) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders WHERE project_id = ?`, id).Scan(&n)
+	return n > 0, err
+}
+
+// OrderStatusCounts returns the number of the project's orders per status;
+// deleted orders are not counted.
+func (r *ProjectRepository) OrderStatusCounts(ctx context.Context, id string) (map[models.OrderStatus]int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT status, COUNT(*) FROM orders WHERE project_id = ? AND deleted_at IS NULL GROUP BY status
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[models.OrderStatus]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		res[models.OrderStatus(status)] = n
+	}
+	return res, rows.Err()
+}
+
+// SetMember adds the user to the project or changes their role.
+func (r *ProjectRepository) SetMember(ctx context.Context, m *models.ProjectMember) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	m.AddedAt = now
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO project_members (project_id, user_id, role, added_by, added_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (project_id, user_id) DO UPDATE SET role = excluded.role
+	`, m.ProjectID, m.UserID, string(m.Role), m.AddedBy, now.Format(time.RFC3339))
+	return err
+}
+
+// GetMember returns the membership; sql.ErrNoRows if the user is not a member.
+func (r *ProjectRepository) GetMember(ctx context.Context, projectID, userID string) (*models.ProjectMember, error) {
+	var m models.ProjectMember
+	var role, addedAt string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT project_id, user_id, role, added_by, added_at FROM project_members WHERE project_id = ? AND user_id = ?
+	`, projectID, userID).Scan(&m.ProjectID, &m.UserID, &role, &m.AddedBy, &addedAt)
+	if err != nil {
+		return nil, err
+	}
+	m.Role = models.ProjectRole(role)
+	m.AddedAt, _ = time.Parse(time.RFC3339, addedAt)
+	return &m, nil
+}
+
+// ListMembers returns the members of a project in the order they were added.
+func (r *ProjectRepository) ListMembers(ctx context.Context, projectID string) ([]models.ProjectMember, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT project_id, user_id, role, added_by, added_at FROM project_members
+		WHERE project_id = ?
+		ORDER BY added_at, user_id
+	`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.ProjectMember{}
+	for rows.Next() {
+		var m models.ProjectMember
+		var role, addedAt string
+		if err := rows.Scan(&m.ProjectID, &m.UserID, &role, &m.AddedBy, &addedAt); err != nil {
+			return nil, err
+		}
+		m.Role = models.ProjectRole(role)
+		m.AddedAt, _ = time.Parse(time.RFC3339, addedAt)
+		res = append(res, m)
+	}
+	return res, rows.Err()
+}
+
+func (r *ProjectRepository) RemoveMember(ctx context.Context, projectID, userID string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM project_members WHERE project_id = ? AND user_id = ?`, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"frame_control_system/internal/models"
+)
+
+type PromoCodeRepository struct {
+	db DBTX
+}
+
+func NewPromoCodeRepository(db DBTX) *PromoCodeRepository {
+	return &PromoCodeRepository{db: db}
+}
+
+const promoColumns = `code, kind, value, valid_from, valid_to, max_uses, used_count, active, created_at, updated_at`
+
+func scanPromoCode(row rowScanner) (models.PromoCode, error) {
+	var p models.PromoCode
+	var kind, createdAt, updatedAt string
+	var validFrom, validTo sql.NullString
+	var maxUses sql.NullInt64
+	if err := row.Scan(&p.Code, &kind, &p.Value, &validFrom, &validTo, &maxUses, &p.UsedCount, &p.Active, &createdAt, &updatedAt); err != nil {
+		return models.PromoCode{}, err
+	}
+	p.Kind = models.PromoKind(kind)
+	p.ValidFrom = parseNullTime(validFrom)
+	p.ValidTo = parseNullTime(validTo)
+	if maxUses.Valid {
+		n := int(maxUses.Int64)
+		p.MaxUses = &n
+	}
+	p.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	p.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return p, nil
+}
+
+func nullInt(n *int) any {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
+
+// Create fills in timestamps and stores the code; p.Code must already be normalized.
+func (r *PromoCodeRepository) Create(ctx context.Context, p *models.PromoCode) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	p.CreatedAt, p.UpdatedAt = now, now
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO promo_codes (code, kind, value, valid_from, valid_to, max_uses, used_count, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?, ?)
+	`, p.Code, string(p.Kind), p.Value, nullTime(p.ValidFrom), nullTime(p.ValidTo), nullInt(p.MaxUses), p.Active,
+		now.Format(time.RFC3339), now.Format(time.RFC3339))
+	return err
+}
+
+// Get returns the code; sql.ErrNoRows if there is none.
+func (r *PromoCodeRepository) Get(ctx context.Context, code string) (*models.PromoCode, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+promoColumns+` FROM promo_codes WHERE code = ?`, code)
+	p, err := scanPromoCode(row)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *PromoCodeRepository) List(ctx context.Context) ([]models.PromoCode, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+promoColumns+` FROM promo_codes ORDER BY created_at DESC, code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.PromoCode{}
+	for rows.Next() {
+		p, err := scanPromoCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
+// Update saves the editable settings of a code; the usage counter is kept.
+func (r *PromoCodeRepository) Update(ctx context.Context, p *models.PromoCode) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	p.UpdatedAt = now
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE promo_codes SET kind = ?, value = ?, valid_from = ?, valid_to = ?, max_uses = ?, active = ?, updated_at = ?
+		WHERE code = ?
+	`, string(p.Kind), p.Value, nullTime(p.ValidFrom), nullTime(p.ValidTo), nullInt(p.MaxUses), p.Active,
+		now.Format(time.RFC3339), p.Code)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Redeem counts one use of the code if it is usable at now. It returns false
+// when the code is inactive, outside its validity window or used up, which
+// also guards against concurrent redemptions of the last use.
+func (r *PromoCodeRepository) Redeem(ctx context.Context, code string, now time.Time) (bool, error) {
+	ts := now.UTC().Format(time.RFC3339)
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE promo_codes SET used_count = used_count + 1, updated_at = ?
+		WHERE code = ? AND active = 1
+			AND (valid_from IS NULL OR valid_from <= ?)
+			AND (valid_to IS NULL OR valid_to > ?)
+			AND (max_uses IS NULL OR used_count < max_uses)
+	`, ts, code, ts, ts)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"frame_control_system/internal/models"
+)
+
+// ReportFilter limits reports to orders created in [From, To).
+type ReportFilter struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// where bounds col and leaves out soft-deleted orders; col may carry a table
+// alias ("o.created_at"), which is reused for deleted_at.
+func (f ReportFilter) where(col string) (string, []interface{}) {
+	return f.bounds(col, strings.TrimSuffix(col, "created_at")+"deleted_at IS NULL")
+}
+
+// bounds adds the [From, To) range on col to the given conditions.
+func (f ReportFilter) bounds(col string, conds ...string) (string, []interface{}) {
+	where := append([]string{}, conds...)
+	args := []interface{}{}
+	if f.From != nil {
+		where = append(where, col+" >= ?")
+		args = append(args, f.From.UTC().Format(time.RFC3339))
+	}
+	if f.To != nil {
+		where = append(where, col+" < ?")
+		args = append(args, f.To.UTC().Format(time.RFC3339))
+	}
+	return strings.Join(where, " AND "), args
+}
+
+type StatusReportRow struct {
+	Status string  `json:"status"`
+	Count  int     `json:"count"`
+	Total  float64 `json:"total_amount"`
+}
+
+type PeriodReportRow struct {
+	Period string  `json:"period"`
+	Count  int     `json:"count"`
+	Total  float64 `json:"total_amount"`
+}
+
+type UserReportRow struct {
+	UserID string  `json:"user_id"`
+	Name   string  `json:"name"`
+	Email  string  `json:"email"`
+	Count  int     `json:"count"`
+	Total  float64 `json:"total_amount"`
+}
+
+type HoursReportRow struct {
+	UserID  string  `json:"user_id"`
+	Name    string  `json:"name"`
+	Email   string  `json:"email"`
+	Orders  int     `json:"orders"`
+	Entries int     `json:"entries"`
+	Hours   float64 `json:"hours"`
+}
+
+type LeadTimeReport struct {
+	Count    int     `json:"count"`
+	AvgHours float64 `json:"avg_hours"`
+	MinHours float64 `json:"min_hours"`
+	MaxHours float64 `json:"max_hours"`
+}
+
+// periodExpr groups created_at by calendar day, ISO week (by its Monday) or month.
+var periodExpr = map[string]string{
+	"day":   "date(created_at)",
+	"week":  "date(created_at, 'weekday 0', '-6 days')",
+	"month": "strftime('%Y-%m', created_at)",
+}
+
+func ValidReportGranularity(g string) bool {
+	_, ok := periodExpr[g]
+	return ok
+}
+
+type ReportRepository struct {
+	db *sql.DB
+}
+
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+func (r *ReportRepository) ByStatus(ctx context.Context, f ReportFilter) ([]StatusReportRow, error) {
+	where, args := f.where("created_at")
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT status, COUNT(*), COALESCE(SUM(total_amount), 0)
+		FROM orders
+		WHERE `+where+`
+		GROUP BY status
+		ORDER BY status
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []StatusReportRow{}
+	for rows.Next() {
+		var row StatusReportRow
+		if err := rows.Scan(&row.Status, &row.Count, &row.Total); err != nil {
+			return nil, err
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+func (r *ReportRepository) ByPeriod(ctx context.Context, f ReportFilter, granularity string) ([]PeriodReportRow, error) {
+	expr, ok := periodExpr[granularity]
+	if !ok {
+		expr = periodExpr["day"]
+	}
+	where, args := f.where("created_at")
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+expr+` AS period, COUNT(*), COALESCE(SUM(total_amount), 0)
+		FROM orders
+		WHERE `+where+`
+		GROUP BY period
+		ORDER BY period
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []PeriodReportRow{}
+	for rows.Next() {
+		var row PeriodReportRow
+		if err := rows.Scan(&row.Period, &row.Count, &row.Total); err != nil {
+			return nil, err
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+func (r *ReportRepository) ByUser(ctx context.Context, f ReportFilter) ([]UserReportRow, error) {
+	where, args := f.where("o.created_at")
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT o.user_id, COALESCE(u.name, ''), COALESCE(u.email, ''), COUNT(*), COALESCE(SUM(o.total_amount), 0)
+		FROM orders o
+		LEFT JOIN users u ON u.id = o.user_id
+		WHERE `+where+`
+		GROUP BY o.user_id
+		ORDER BY COUNT(*) DESC, o.user_id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []UserReportRow{}
+	for rows.Next() {
+		var row UserReportRow
+		if err := rows.Scan(&row.UserID, &row.Name, &row.Email, &row.Count, &row.Total); err != nil {
+			return nil, err
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+// LeadTime measures the time from order creation to its (last) move to done,
+// using the status history.
+func (r *ReportRepository) LeadTime(ctx context.Context, f ReportFilter) (LeadTimeReport, error) {
+	where, args := f.where("o.created_at")
+	var res LeadTimeReport
+	var avg, min, max sql.NullFloat64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), AVG(hours), MIN(hours), MAX(hours)
+		FROM (
+			SELECT (julianday(MAX(h.changed_at)) - julianday(o.created_at)) * 24 AS hours
+			FROM orders o
+			JOIN order_status_history h ON h.order_id = o.id AND h.to_status = 'done'
+			WHERE o.status = 'done' AND `+where+`
+			GROUP BY o.id
+		)
+	`, args...).Scan(&res.Count, &avg, &min, &max)
+	res.AvgHours, res.MinHours, res.MaxHours = avg.Float64, min.Float64, max.Float64
+	return res, err
+}
+
+// HoursByUser sums the finished time entries per user. Unlike the other
+// reports, the period applies to when the work started, not to when the
+// order was created.
+func (r *ReportRepository) HoursByUser(ctx context.Context, f ReportFilter) ([]HoursReportRow, error) {
+	where, args := f.bounds("t.started_at", "t.ended_at IS NOT NULL", "o.deleted_at IS NULL")
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.user_id, COALESCE(u.name, ''), COALESCE(u.email, ''), COUNT(DISTINCT t.order_id), COUNT(*), SUM(t.duration_seconds)
+		FROM time_entries t
+		JOIN orders o ON o.id = t.order_id
+		LEFT JOIN users u ON u.id = t.user_id
+		WHERE `+where+`
+		GROUP BY t.user_id
+		ORDER BY SUM(t.duration_seconds) DESC, t.user_id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []HoursReportRow{}
+	for rows.Next() {
+		var row HoursReportRow
+		var seconds int64
+		if err := rows.Scan(&row.UserID, &row.Name, &row.Email, &row.Orders, &row.Entries, &seconds); err != nil {
+			return nil, err
+		}
+		row.Hours = models.Hours(seconds)
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
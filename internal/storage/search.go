@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Highlight markers wrapped around matched terms by SearchOrders/SearchUsers.
+// They never occur in user text, so callers can escape the text first and then
+// turn the markers into markup.
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+const maxSearchTerms = 10
+
+type SearchParams struct {
+	Query     string
+	UserID    string
+	AdminView bool
+	Limit     int
+}
+
+type OrderHit struct {
+	ID          string
+	UserID      string
+	Status      string
+	TotalAmount float64
+	CreatedAt   time.Time
+	Items       string // item names with highlighted matches
+	Notes       string // snippet of notes with highlighted matches
+	Score       float64
+}
+
+type UserHit struct {
+	ID    string
+	Email string
+	Name  string
+	Score float64
+}
+
+type SearchRepository struct {
+	db *sql.DB
+}
+
+func NewSearchRepository(db *sql.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// BuildMatchQuery turns free text into a safe FTS5 MATCH expression: every
+// word becomes a quoted prefix term and all terms must match. It returns ""
+// when the text has no searchable words.
+func BuildMatchQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
+	}
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, `"`+w+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// SearchOrders ranks orders by bm25, item names weighing more than notes.
+// Without AdminView only the user's own orders, those assigned to or shared
+// with them and orders of their projects match; deleted orders never do.
+func (r *SearchRepository) SearchOrders(ctx context.Context, p SearchParams) ([]OrderHit, error) {
+	where := []string{"orders_fts MATCH ?", "o.deleted_at IS NULL"}
+	args := []interface{}{BuildMatchQuery(p.Query)}
+	if !p.AdminView {
+		where = append(where, "(o.user_id = ? OR o.assignee_id = ? OR o.project_id IN (SELECT project_id FROM project_members WHERE user_id = ?)"+
+			" OR o.id IN (SELECT order_id FROM order_viewers WHERE user_id = ?))")
+		args = append(args, p.UserID, p.UserID, p.UserID, p.UserID)
+	}
+	args = append(args, p.Limit)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT o.id, o.user_id, o.status, o.total_amount, o.created_at,
+			highlight(orders_fts, 1, ?, ?),
+			snippet(orders_fts, 2, ?, ?, '…', 16),
+			bm25(orders_fts, 0.0, 2.0, 1.0) AS score
+		FROM orders_fts
+		JOIN orders o ON o.id = orders_fts.order_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY score
+		LIMIT ?
+	`, append([]interface{}{HighlightStart, HighlightEnd, HighlightStart, HighlightEnd}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []OrderHit
+	for rows.Next() {
+		var h OrderHit
+		var createdAt string
+		var items, notes sql.NullString
+		if err := rows.Scan(&h.ID, &h.UserID, &h.Status, &h.TotalAmount, &createdAt, &items, &notes, &h.Score); err != nil {
+			return nil, err
+		}
+		h.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		h.Items = items.String
+		h.Notes = notes.String
+		// bm25 is lower-is-better; expose higher-is-better scores.
+		h.Score = -h.Score
+		res = append(res, h)
+	}
+	return res, rows.Err()
+}
+
+// SearchUsers ranks users by name and email. Callers restrict it to admins.
+func (r *SearchRepository) SearchUsers(ctx context.Context, p SearchParams) ([]UserHit, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id, highlight(users_fts, 2, ?, ?), highlight(users_fts, 1, ?, ?),
+			bm25(users_fts, 0.0, 2.0, 1.0) AS score
+		FROM users_fts
+		WHERE users_fts MATCH ?
+		ORDER BY score
+		LIMIT ?
+	`, HighlightStart, HighlightEnd, HighlightStart, HighlightEnd, BuildMatchQuery(p.Query), p.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []UserHit
+	for rows.Next() {
+		var h UserHit
+		if err := rows.Scan(&h.ID, &h.Email, &h.Name, &h.Score); err != nil {
+			return nil, err
+		}
+		h.Score = -h.Score
+		res = append(res, h)
+	}
+	return res, rows.Err()
+}
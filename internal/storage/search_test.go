@@ -0,0 +1,19 @@
+package storage
+
+import "testing"
+
+func TestBuildMatchQuery(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"rebar block C", `"rebar"* "block"* "C"*`},
+		{`арматура "A500" OR NOT*`, `"арматура"* "A500"* "OR"* "NOT"*`},
+		{"  -- ()  ", ""},
+	}
+	for _, tt := range tests {
+		if got := BuildMatchQuery(tt.in); got != tt.want {
+			t.Fatalf("BuildMatchQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
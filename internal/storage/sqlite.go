@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 )
@@ -9,7 +10,9 @@ import (
 import _ "github.com/mattn/go-sqlite3"
 
 func OpenSQLite(path string) (*sql.DB, error) {
-	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL&_fk=1", path)
+	// Transactions take the write lock up front, so an order read inside one
+	// cannot change before the same transaction writes it back.
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL&_fk=1&_txlock=immediate", path)
 	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, err
@@ -20,4 +23,10 @@ func OpenSQLite(path string) (*sql.DB, error) {
 	return db, nil
 }
 
-
+// DBTX is implemented by both *sql.DB and *sql.Tx, letting repositories run
+// inside a transaction when the caller needs one.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"frame_control_system/internal/models"
+)
+
+type TimeEntryRepository struct {
+	db DBTX
+}
+
+func NewTimeEntryRepository(db DBTX) *TimeEntryRepository {
+	return &TimeEntryRepository{db: db}
+}
+
+const timeEntryColumns = `id, order_id, user_id, started_at, ended_at, duration_seconds, note, created_at`
+
+func scanTimeEntry(row rowScanner) (models.TimeEntry, error) {
+	var e models.TimeEntry
+	var startedAt, createdAt string
+	var endedAt sql.NullString
+	if err := row.Scan(&e.ID, &e.OrderID, &e.UserID, &startedAt, &endedAt, &e.DurationSeconds, &e.Note, &createdAt); err != nil {
+		return models.TimeEntry{}, err
+	}
+	e.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+	e.EndedAt = parseNullTime(endedAt)
+	e.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	e.Running = e.EndedAt == nil
+	e.Hours = models.Hours(e.DurationSeconds)
+	return e, nil
+}
+
+func (r *TimeEntryRepository) query(ctx context.Context, query string, args ...any) ([]models.TimeEntry, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.TimeEntry{}
+	for rows.Next() {
+		e, err := scanTimeEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
+// Create fills in ID, creation time and the derived fields and stores the
+// entry. An entry without EndedAt is a running timer. Callers check for
+// overlaps first.
+func (r *TimeEntryRepository) Create(ctx context.Context, e *models.TimeEntry) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	e.ID = uuid.NewString()
+	e.CreatedAt = now
+	e.StartedAt = e.StartedAt.UTC().Truncate(time.Second)
+	e.DurationSeconds = 0
+	if e.EndedAt != nil {
+		end := e.EndedAt.UTC().Truncate(time.Second)
+		e.EndedAt = &end
+		e.DurationSeconds = int64(end.Sub(e.StartedAt) / time.Second)
+	}
+	e.Running = e.EndedAt == nil
+	e.Hours = models.Hours(e.DurationSeconds)
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO time_entries (id, order_id, user_id, started_at, ended_at, duration_seconds, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.ID, e.OrderID, e.UserID, e.StartedAt.Format(time.RFC3339), nullTime(e.EndedAt), e.DurationSeconds, e.Note, now.Format(time.RFC3339))
+	return err
+}
+
+// Get returns an entry of the order; sql.ErrNoRows if there is none.
+func (r *TimeEntryRepository) Get(ctx context.Context, orderID, id string) (*models.TimeEntry, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+timeEntryColumns+` FROM time_entries WHERE id = ? AND order_id = ?`, id, orderID)
+	e, err := scanTimeEntry(row)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ListByOrder returns the entries of the order, oldest first.
+func (r *TimeEntryRepository) ListByOrder(ctx context.Context, orderID string) ([]models.TimeEntry, error) {
+	return r.query(ctx, `SELECT `+timeEntryColumns+` FROM time_entries WHERE order_id = ? ORDER BY started_at, id`, orderID)
+}
+
+// ListByUser returns the user's entries started in [from, to), oldest first;
+// nil bounds are open.
+func (r *TimeEntryRepository) ListByUser(ctx context.Context, userID string, from, to *time.Time) ([]models.TimeEntry, error) {
+	return r.query(ctx, `
+		SELECT `+timeEntryColumns+`
+		FROM time_entries
+		WHERE user_id = ? AND (? IS NULL OR started_at >= ?) AND (? IS NULL OR started_at < ?)
+		ORDER BY started_at, id
+	`, userID, nullTime(from), nullTime(from), nullTime(to), nullTime(to))
+}
+
+// Running returns the user's running timer; sql.ErrNoRows if there is none.
+func (r *TimeEntryRepository) Running(ctx context.Context, userID string) (*models.TimeEntry, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+timeEntryColumns+` FROM time_entries WHERE user_id = ? AND ended_at IS NULL`, userID)
+	e, err := scanTimeEntry(row)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Overlapping returns an entry of the user that intersects [start, end); a
+// nil end is open-ended, and a running timer reaches into the future. It
+// returns sql.ErrNoRows if the span is free.
+func (r *TimeEntryRepository) Overlapping(ctx context.Context, userID string, start time.Time, end *time.Time) (*models.TimeEntry, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+timeEntryColumns+`
+		FROM time_entries
+		WHERE user_id = ? AND (ended_at IS NULL OR ended_at > ?) AND (? IS NULL OR started_at < ?)
+		ORDER BY started_at, id
+		LIMIT 1
+	`, userID, start.UTC().Format(time.RFC3339), nullTime(end), nullTime(end))
+	e, err := scanTimeEntry(row)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Stop ends the running timer at the given time and fills in the duration;
+// sql.ErrNoRows if the entry is not running any more.
+func (r *TimeEntryRepository) Stop(ctx context.Context, e *models.TimeEntry, at time.Time) error {
+	end := at.UTC().Truncate(time.Second)
+	duration := int64(end.Sub(e.StartedAt) / time.Second)
+	if duration < 0 {
+		duration = 0
+	}
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE time_entries SET ended_at = ?, duration_seconds = ? WHERE id = ? AND ended_at IS NULL
+	`, end.Format(time.RFC3339), duration, e.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	e.EndedAt, e.DurationSeconds, e.Running = &end, duration, false
+	e.Hours = models.Hours(duration)
+	return nil
+}
+
+// Delete removes an entry of the order; sql.ErrNoRows if there is none.
+func (r *TimeEntryRepository) Delete(ctx context.Context, orderID, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM time_entries WHERE id = ? AND order_id = ?`, id, orderID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *TimeEntryRepository) totals(ctx context.Context, query string, args ...any) ([]models.TimeTotal, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []models.TimeTotal{}
+	for rows.Next() {
+		var t models.TimeTotal
+		if err := rows.Scan(&t.UserID, &t.OrderID, &t.Entries, &t.DurationSeconds); err != nil {
+			return nil, err
+		}
+		t.Hours = models.Hours(t.DurationSeconds)
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
+
+// TotalsByOrder sums the finished entries of the order per user.
+func (r *TimeEntryRepository) TotalsByOrder(ctx context.Context, orderID string) ([]models.TimeTotal, error) {
+	return r.totals(ctx, `
+		SELECT user_id, '', COUNT(*), COALESCE(SUM(duration_seconds), 0)
+		FROM time_entries
+		WHERE order_id = ? AND ended_at IS NOT NULL
+		GROUP BY user_id
+		ORDER BY SUM(duration_seconds) DESC, user_id
+	`, orderID)
+}
+
+// TotalsByUser sums the user's finished entries started in [from, to) per
+// order; nil bounds are open.
+func (r *TimeEntryRepository) TotalsByUser(ctx context.Context, userID string, from, to *time.Time) ([]models.TimeTotal, error) {
+	return r.totals(ctx, `
+		SELECT '', order_id, COUNT(*), COALESCE(SUM(duration_seconds), 0)
+		FROM time_entries
+		WHERE user_id = ? AND ended_at IS NOT NULL AND (? IS NULL OR started_at >= ?) AND (? IS NULL OR started_at < ?)
+		GROUP BY order_id
+		ORDER BY SUM(duration_seconds) DESC, order_id
+	`, userID, nullTime(from), nullTime(from), nullTime(to), nullTime(to))
+}
@@ -80,9 +80,16 @@ type ListUsersParams struct {
 	Limit int
 	Offset int
 	Sort string
+	// After switches to keyset pagination; only valid with created_* sorts.
+	After *Cursor
 }
 
-func (r *UserRepository) List(ctx context.Context, p ListUsersParams) ([]models.User, error) {
+// KeysetSort reports whether the sort order supports cursor pagination.
+func (p ListUsersParams) KeysetSort() bool {
+	return p.Sort == "" || p.Sort == "created_asc" || p.Sort == "created_desc"
+}
+
+func (p ListUsersParams) filter() ([]string, []interface{}) {
 	where := []string{"1=1"}
 	args := []interface{}{}
 	if p.Email != "" {
@@ -97,7 +104,12 @@ func (r *UserRepository) List(ctx context.Context, p ListUsersParams) ([]models.
 		where = append(where, "roles LIKE ?")
 		args = append(args, "%"+p.Role+"%")
 	}
-	order := "created_at DESC"
+	return where, args
+}
+
+func (r *UserRepository) List(ctx context.Context, p ListUsersParams) ([]models.User, error) {
+	where, args := p.filter()
+	order := "created_at DESC, id DESC"
 	switch p.Sort {
 	case "email_asc":
 		order = "email ASC"
@@ -108,9 +120,16 @@ func (r *UserRepository) List(ctx context.Context, p ListUsersParams) ([]models.
 	case "name_desc":
 		order = "name DESC"
 	case "created_asc":
-		order = "created_at ASC"
+		order = "created_at ASC, id ASC"
 	case "created_desc":
-		order = "created_at DESC"
+		order = "created_at DESC, id DESC"
+	}
+	offset := p.Offset
+	if p.After != nil && p.KeysetSort() {
+		cond, cargs := keysetCondition(*p.After, p.Sort != "created_asc")
+		where = append(where, cond)
+		args = append(args, cargs...)
+		offset = 0
 	}
 	query := `
 		SELECT id, email, password_hash, name, roles, created_at, updated_at
@@ -119,7 +138,7 @@ func (r *UserRepository) List(ctx context.Context, p ListUsersParams) ([]models.
 		ORDER BY ` + order + `
 		LIMIT ? OFFSET ?
 	`
-	args = append(args, p.Limit, p.Offset)
+	args = append(args, p.Limit, offset)
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
@@ -140,6 +159,14 @@ func (r *UserRepository) List(ctx context.Context, p ListUsersParams) ([]models.
 	return res, rows.Err()
 }
 
+// Count returns the number of users matching the filters, ignoring pagination.
+func (r *UserRepository) Count(ctx context.Context, p ListUsersParams) (int, error) {
+	where, args := p.filter()
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE `+strings.Join(where, " AND "), args...).Scan(&n)
+	return n, err
+}
+
 func (r *UserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
 	row := r.db.QueryRowContext(ctx, `SELECT 1 FROM users WHERE email = ? LIMIT 1`)
 	var one int